@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// statusCapturingResponseWriter guarda o status HTTP e a quantidade de bytes
+// escritos pelo handler
+type statusCapturingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// newStatusCapturingResponseWriter assume 200 até que o handler escreva outro status
+func newStatusCapturingResponseWriter(w http.ResponseWriter) *statusCapturingResponseWriter {
+	return &statusCapturingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *statusCapturingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusCapturingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap expõe o ResponseWriter original para o http.ResponseController
+func (w *statusCapturingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// accessLog registra uma linha por requisição com o método, o caminho, o
+// status, o tamanho da resposta e a duração total
+type accessLog struct {
+	logger *slog.Logger
+}
+
+// newAccessLog cria o middleware escrevendo no logger informado
+func newAccessLog(l *slog.Logger) *accessLog {
+	return &accessLog{logger: l}
+}
+
+// Wrap registra as requisições atendidas pelo handler informado
+func (a *accessLog) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := newStatusCapturingResponseWriter(w)
+		next(recorder, r)
+		a.logger.InfoContext(r.Context(), "requisição HTTP",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"client_ip", clientIP(r, trustedProxies),
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration_ms", float64(time.Since(start))/float64(time.Millisecond),
+		)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogWrap(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		expectedStatus float64
+		expectedBytes  float64
+	}{
+		{"Status implícito", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"ok"}`))
+		}, 200, 15},
+		{"Status explícito", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}, 404, 9},
+		{"Sem corpo", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}, 204, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := newAccessLog(newJSONLogger(&buf, slog.LevelInfo)).Wrap(tt.handler)
+
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+			if rr.Code != int(tt.expectedStatus) {
+				t.Errorf("status repassado = %d, want %v", rr.Code, tt.expectedStatus)
+			}
+
+			lines := logLines(t, &buf)
+			if len(lines) != 1 {
+				t.Fatalf("esperada 1 linha de log, got %d: %v", len(lines), lines)
+			}
+			line := lines[0]
+			if line["method"] != "GET" || line["path"] != "/weatherbycep/01310100" {
+				t.Errorf("method/path = %v %v, want GET /weatherbycep/01310100", line["method"], line["path"])
+			}
+			if line["status"] != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", line["status"], tt.expectedStatus)
+			}
+			if line["bytes"] != tt.expectedBytes {
+				t.Errorf("bytes = %v, want %v", line["bytes"], tt.expectedBytes)
+			}
+			if duration, ok := line["duration_ms"].(float64); !ok || duration <= 0 {
+				t.Errorf("duration_ms = %v, want valor positivo", line["duration_ms"])
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang-weatherbycep/weather"
+)
+
+// GeocodeResult representa uma localização encontrada a partir de um endereço
+type GeocodeResult struct {
+	City        string  `json:"city"`
+	State       string  `json:"state"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	DisplayName string  `json:"display_name,omitempty"`
+}
+
+// AddressGeocoder converte um endereço livre em localizações candidatas,
+// ordenadas da mais para a menos relevante
+type AddressGeocoder interface {
+	Geocode(ctx context.Context, query string) ([]GeocodeResult, *CustomError)
+}
+
+// addressGeocoder é o geocodificador usado pelo endpoint /weatherbyaddress
+var addressGeocoder AddressGeocoder = &nominatimGeocoder{baseURL: config.GeocoderBaseURL}
+
+// nominatimGeocoder implementa AddressGeocoder usando a API de busca do Nominatim
+type nominatimGeocoder struct {
+	baseURL string
+}
+
+// Geocode busca o endereço no Nominatim restringindo os resultados ao Brasil,
+// com o circuit breaker e o prazo do provedor
+func (g *nominatimGeocoder) Geocode(ctx context.Context, query string) (_ []GeocodeResult, geoErr *CustomError) {
+	// Com o circuito aberto o Nominatim não é consultado até o fim do cooldown
+	if !geocoderBreaker.Allow() {
+		logger.WarnContext(ctx, "circuito aberto, consulta ignorada", "provider", providerGeocoder)
+		return nil, &CustomError{Code: 503, Message: "geocoder temporarily unavailable", Err: weather.ErrUpstreamUnavailable}
+	}
+	defer func(ctx context.Context) { geocoderBreaker.Done(ctx, geoErr) }(ctx)
+
+	ctx, cancel := withUpstreamTimeout(ctx, config.timeoutFor(providerGeocoder, config.GeocoderTimeout))
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "jsonv2")
+	params.Set("addressdetails", "1")
+	params.Set("countrycodes", "br")
+	params.Set("limit", "5")
+
+	var places []struct {
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+		DisplayName string `json:"display_name"`
+		Address     struct {
+			City         string `json:"city"`
+			Town         string `json:"town"`
+			Village      string `json:"village"`
+			Municipality string `json:"municipality"`
+			StateCode    string `json:"ISO3166-2-lvl4"`
+		} `json:"address"`
+	}
+	// O Nominatim exige um User-Agent identificando a aplicação, enviado pelo
+	// cliente de upstream junto com a política de novas tentativas
+	if err := newUpstreamClient(ctx).GetJSON(ctx, g.baseURL+"?"+params.Encode(), &places); err != nil {
+		return nil, upstreamError(ctx, providerGeocoder, err)
+	}
+
+	results := make([]GeocodeResult, 0, len(places))
+	for _, p := range places {
+		city := firstNonEmpty(p.Address.City, p.Address.Town, p.Address.Village, p.Address.Municipality)
+		if city == "" {
+			continue
+		}
+		lat, _ := strconv.ParseFloat(p.Lat, 64)
+		lon, _ := strconv.ParseFloat(p.Lon, 64)
+		results = append(results, GeocodeResult{
+			City:        city,
+			State:       strings.TrimPrefix(p.Address.StateCode, "BR-"),
+			Lat:         lat,
+			Lon:         lon,
+			DisplayName: p.DisplayName,
+		})
+	}
+
+	return results, nil
+}
+
+// firstNonEmpty retorna o primeiro valor não vazio
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// AddressWeatherResponse representa a resposta do endpoint /weatherbyaddress
+type AddressWeatherResponse struct {
+	WeatherData
+	Location GeocodeResult `json:"location"`
+	Note     string        `json:"note,omitempty"`
+}
+
+// NewWeatherByAddressHandler cria o handler de GET /weatherbyaddress?q={endereço},
+// que geocodifica o endereço e consulta a temperatura da cidade encontrada
+func NewWeatherByAddressHandler(geocoder AddressGeocoder, weatherResolver WeatherResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			setAllow(w, http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		if query == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "q parameter is required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		// Geocodifica o endereço
+		results, geoErr := geocoder.Geocode(ctx, query)
+		if geoErr != nil {
+			w.WriteHeader(geoErr.Code)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: geoErr.Message})
+			return
+		}
+		if len(results) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "can not find address"})
+			return
+		}
+
+		// Em caso de ambiguidade usa o resultado mais relevante e sinaliza na resposta
+		location := results[0]
+		var note string
+		if len(results) > 1 {
+			note = fmt.Sprintf("ambiguous address, using top result of %d", len(results))
+		}
+
+		// Busca dados climáticos
+		weatherData, weatherErr := weatherResolver.ResolveWeather(ctx, location.City, location.State)
+		if weatherErr != nil {
+			w.WriteHeader(weatherErr.Code)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: weatherErr.Message})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		encodeJSON(w, AddressWeatherResponse{
+			WeatherData: *weatherData,
+			Location:    location,
+			Note:        note,
+		})
+	}
+}
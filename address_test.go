@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubGeocoder devolve resultados fixos, sem acessar a rede
+type stubGeocoder struct {
+	results []GeocodeResult
+	err     *CustomError
+}
+
+func (g *stubGeocoder) Geocode(ctx context.Context, query string) ([]GeocodeResult, *CustomError) {
+	return g.results, g.err
+}
+
+// useGeocoder substitui o geocodificador durante o teste
+func useGeocoder(t *testing.T, g AddressGeocoder) {
+	t.Helper()
+	original := addressGeocoder
+	addressGeocoder = g
+	t.Cleanup(func() { addressGeocoder = original })
+}
+
+func TestWeatherByAddressHandler(t *testing.T) {
+	saoPaulo := GeocodeResult{City: "São Paulo", State: "SP", Lat: -23.561, Lon: -46.656}
+	saoPauloNorte := GeocodeResult{City: "São Paulo", State: "SP", Lat: -23.48, Lon: -46.62}
+
+	tests := []struct {
+		name           string
+		query          string
+		method         string
+		geocoder       *stubGeocoder
+		expectedStatus int
+		expectedMsg    string
+		expectedNote   string
+	}{
+		{
+			name:           "Endereço único",
+			query:          "?q=Av+Paulista,+Sao+Paulo",
+			method:         "GET",
+			geocoder:       &stubGeocoder{results: []GeocodeResult{saoPaulo}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Endereço ambíguo usa o primeiro resultado",
+			query:          "?q=Paulista,+Sao+Paulo",
+			method:         "GET",
+			geocoder:       &stubGeocoder{results: []GeocodeResult{saoPaulo, saoPauloNorte}},
+			expectedStatus: http.StatusOK,
+			expectedNote:   "ambiguous address, using top result of 2",
+		},
+		{
+			name:           "Query vazia",
+			query:          "?q=+",
+			method:         "GET",
+			geocoder:       &stubGeocoder{},
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "q parameter is required",
+		},
+		{
+			name:           "Endereço não encontrado",
+			query:          "?q=Rua+Inexistente",
+			method:         "GET",
+			geocoder:       &stubGeocoder{},
+			expectedStatus: http.StatusNotFound,
+			expectedMsg:    "can not find address",
+		},
+		{
+			name:           "Método não permitido",
+			query:          "?q=Av+Paulista",
+			method:         "POST",
+			geocoder:       &stubGeocoder{},
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedMsg:    "method not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherResolver := &fakeWeatherResolver{tempC: 22}
+			req := httptest.NewRequest(tt.method, "/weatherbyaddress"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			NewWeatherByAddressHandler(tt.geocoder, weatherResolver)(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler retornou status code errado: got %v want %v (%s)",
+					rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				var errorResp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+					t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+				}
+				if errorResp.Message != tt.expectedMsg {
+					t.Errorf("Mensagem de erro incorreta: got %v want %v", errorResp.Message, tt.expectedMsg)
+				}
+				return
+			}
+
+			var resp AddressWeatherResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if resp.Location != saoPaulo {
+				t.Errorf("localização incorreta: got %+v want %+v", resp.Location, saoPaulo)
+			}
+			if resp.Note != tt.expectedNote {
+				t.Errorf("nota incorreta: got %q want %q", resp.Note, tt.expectedNote)
+			}
+			if resp.TempC != 22 {
+				t.Errorf("temp_C incorreta: got %v want 22", resp.TempC)
+			}
+			if weatherResolver.city != saoPaulo.City || weatherResolver.state != saoPaulo.State {
+				t.Errorf("temperatura consultada para %s/%s, want %s/%s", weatherResolver.city, weatherResolver.state, saoPaulo.City, saoPaulo.State)
+			}
+		})
+	}
+}
+
+// deadlineGeocoder registra se a consulta recebeu um prazo
+type deadlineGeocoder struct {
+	hasDeadline bool
+}
+
+func (g *deadlineGeocoder) Geocode(ctx context.Context, query string) ([]GeocodeResult, *CustomError) {
+	_, g.hasDeadline = ctx.Deadline()
+	return nil, nil
+}
+
+func TestWeatherByAddressHandlerBoundsLookups(t *testing.T) {
+	geocoder := &deadlineGeocoder{}
+	rr := httptest.NewRecorder()
+	NewWeatherByAddressHandler(geocoder, &fakeWeatherResolver{})(rr, httptest.NewRequest("GET", "/weatherbyaddress?q=Av+Paulista", nil))
+
+	if !geocoder.hasDeadline {
+		t.Error("a geocodificação deveria ser limitada por handlerTimeout")
+	}
+}
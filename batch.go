@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// batchMaxCEPs é a quantidade máxima de CEPs aceita em uma requisição em lote
+	batchMaxCEPs = 20
+	// batchConcurrency limita as consultas simultâneas de um lote
+	batchConcurrency = 5
+	// defaultBatchItemTimeout é o prazo padrão da consulta de cada CEP do lote
+	defaultBatchItemTimeout = 5 * time.Second
+)
+
+// batchItemTimeout é o prazo da consulta de cada CEP do lote, lido de
+// BATCH_ITEM_TIMEOUT; o lote inteiro continua limitado por handlerTimeout
+var batchItemTimeout = envDuration("BATCH_ITEM_TIMEOUT", defaultBatchItemTimeout)
+
+// BatchRequest é o corpo aceito por POST /weatherbycep/batch
+type BatchRequest struct {
+	CEPs []string `json:"ceps"`
+}
+
+// BatchError descreve a falha de um CEP do lote
+type BatchError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// BatchResult é o resultado de um CEP do lote: a temperatura ou o erro
+type BatchResult struct {
+	CEP     string       `json:"cep"`
+	Weather *WeatherData `json:"weather,omitempty"`
+	Error   *BatchError  `json:"error,omitempty"`
+}
+
+// BatchStreamResult é uma linha de resultado do lote em NDJSON: o resultado
+// de um CEP e a sua posição na lista recebida
+type BatchStreamResult struct {
+	Index int `json:"index"`
+	BatchResult
+}
+
+// BatchProgress informa quantos CEPs do lote já foram processados
+type BatchProgress struct {
+	Processed int `json:"processed"`
+	Total     int `json:"total"`
+}
+
+// BatchProgressEvent é a linha de progresso do lote em NDJSON
+type BatchProgressEvent struct {
+	Progress BatchProgress `json:"progress"`
+}
+
+// NewBatchWeatherHandler cria o handler de POST /weatherbycep/batch, que
+// consulta vários CEPs em paralelo e devolve os resultados na ordem recebida.
+// Com Accept: application/x-ndjson, cada resultado é enviado assim que
+// concluído, seguido de uma linha de progresso.
+func NewBatchWeatherHandler(cepResolver CEPResolver, weatherResolver WeatherResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			setAllow(w, http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+			return
+		}
+
+		var batch BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			if isBodyTooLarge(err) {
+				writePayloadTooLarge(w)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid request body"})
+			return
+		}
+		if len(batch.CEPs) == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "ceps parameter is required"})
+			return
+		}
+		if len(batch.CEPs) > batchMaxCEPs {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "too many ceps"})
+			return
+		}
+
+		// O prazo do lote parte do contexto da requisição: a desconexão do
+		// cliente também cancela as consultas em andamento
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		if wantsNDJSON(r) {
+			streamBatch(ctx, w, cepResolver, weatherResolver, batch.CEPs)
+			return
+		}
+
+		results := resolveBatch(ctx, cepResolver, weatherResolver, batch.CEPs, nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		encodeJSON(w, results)
+	}
+}
+
+// streamBatch escreve o lote em NDJSON: cada resultado, na ordem em que é
+// concluído e com a sua posição em index, seguido de uma linha com o total
+// de CEPs processados até ali. A última linha de progresso sempre informa o
+// lote inteiro, inclusive os CEPs cancelados.
+func streamBatch(ctx context.Context, w http.ResponseWriter, cepResolver CEPResolver, weatherResolver WeatherResolver, ceps []string) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	processed := 0
+	resolveBatch(ctx, cepResolver, weatherResolver, ceps, func(i int, result BatchResult) {
+		processed++
+		encodeJSON(w, BatchStreamResult{Index: i, BatchResult: result})
+		encodeJSON(w, BatchProgressEvent{Progress: BatchProgress{Processed: processed, Total: len(ceps)}})
+		rc.Flush()
+	})
+}
+
+// resolveBatch consulta os CEPs em paralelo, limitando as chamadas
+// simultâneas e o tempo de cada uma. Se o contexto do lote terminar antes,
+// devolve os resultados já concluídos e marca os demais como cancelados.
+// onResult, quando informado, recebe cada resultado assim que é guardado,
+// uma chamada por vez.
+func resolveBatch(ctx context.Context, cepResolver CEPResolver, weatherResolver WeatherResolver, ceps []string, onResult func(int, BatchResult)) []BatchResult {
+	var (
+		mu       sync.Mutex
+		results  = make([]BatchResult, len(ceps))
+		finished = make([]bool, len(ceps))
+		closed   bool
+	)
+	// store guarda o resultado de um CEP, a menos que a resposta já tenha sido montada
+	store := func(i int, result BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			results[i], finished[i] = result, true
+			if onResult != nil {
+				onResult(i, result)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batchConcurrency)
+	for i, cep := range ceps {
+		wg.Add(1)
+		go func(i int, cep string) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			itemCtx, cancel := context.WithTimeout(ctx, batchItemTimeout)
+			defer cancel()
+			result := resolveBatchCEP(itemCtx, cepResolver, weatherResolver, cep)
+			// Falhas causadas pelo fim do lote contam como cancelamento
+			if result.Error != nil && ctx.Err() != nil {
+				return
+			}
+			store(i, result)
+		}(i, cep)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	for i, cep := range ceps {
+		if !finished[i] {
+			results[i] = BatchResult{CEP: cep, Error: &BatchError{Status: contextError(ctx).Code, Message: "cancelled"}}
+			if onResult != nil {
+				onResult(i, results[i])
+			}
+		}
+	}
+	return results
+}
+
+// resolveBatchCEP busca a temperatura de um CEP do lote
+func resolveBatchCEP(ctx context.Context, cepResolver CEPResolver, weatherResolver WeatherResolver, cep string) BatchResult {
+	result := BatchResult{CEP: cep}
+
+	if detail := cepValidationDetail(cep); detail != "" {
+		result.Error = &BatchError{Status: http.StatusUnprocessableEntity, Message: "invalid zipcode", Detail: detail}
+		return result
+	}
+
+	cepData, cepErr := cepResolver.ResolveCEP(ctx, cep)
+	if cepErr != nil {
+		result.Error = &BatchError{Status: cepErr.Code, Message: cepErr.Message, Detail: cepErr.Detail}
+		return result
+	}
+
+	// Como em /weatherbycep, CEPs sem cidade ou UF não consultam a temperatura
+	if !hasCompleteAddress(cepData) {
+		logger.WarnContext(ctx, "endereço incompleto", "cep", formatCEP(cep), "city", cepData.Localidade, "state", cepData.UF)
+		result.Error = &BatchError{Status: http.StatusBadGateway, Message: "incomplete address data"}
+		return result
+	}
+
+	weatherData, weatherErr := weatherResolver.ResolveWeather(ctx, cepData.Localidade, cepData.UF)
+	if weatherErr != nil {
+		result.Error = &BatchError{Status: weatherErr.Code, Message: weatherErr.Message}
+		return result
+	}
+
+	result.Weather = weatherData
+	return result
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchWeatherHandler(t *testing.T) {
+	cepResolver := newFakeCEPResolver()
+	cepResolver.data["20040002"] = &CEPData{CEP: "20040-002", Localidade: "Rio de Janeiro", UF: "RJ"}
+	// CEP especial sem cidade nem UF
+	cepResolver.data["70002900"] = &CEPData{CEP: "70002-900"}
+	weatherResolver := &fakeWeatherResolver{tempC: 23}
+	handler := NewBatchWeatherHandler(cepResolver, weatherResolver)
+
+	body := `{"ceps": ["01310100", "99999999", "20040-002", "123", "70002900"]}`
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("POST", "/weatherbycep/batch", strings.NewReader(body)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var results []BatchResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+
+	expected := []struct {
+		cep    string
+		status int
+	}{
+		{"01310100", 0},
+		{"99999999", http.StatusNotFound},
+		{"20040-002", 0},
+		{"123", http.StatusUnprocessableEntity},
+		{"70002900", http.StatusBadGateway},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("esperados %d resultados, got %d", len(expected), len(results))
+	}
+
+	for i, want := range expected {
+		got := results[i]
+		if got.CEP != want.cep {
+			t.Errorf("resultado %d: cep = %q, want %q (ordem não preservada)", i, got.CEP, want.cep)
+		}
+		if want.status == 0 {
+			if got.Error != nil || got.Weather == nil || got.Weather.TempC != 23 {
+				t.Errorf("resultado %d: esperada temperatura, got weather=%v error=%v", i, got.Weather, got.Error)
+			}
+			continue
+		}
+		if got.Weather != nil || got.Error == nil || got.Error.Status != want.status {
+			t.Errorf("resultado %d: esperado erro %d, got weather=%v error=%v", i, want.status, got.Weather, got.Error)
+		}
+	}
+
+	if msg := results[4].Error; msg == nil || msg.Message != "incomplete address data" {
+		t.Errorf("endereço incompleto: error = %v, want incomplete address data", msg)
+	}
+
+	// O CEP inválido não chega ao resolver, e o endereço incompleto não
+	// chega ao provedor de temperatura
+	if cepResolver.calls != 4 {
+		t.Errorf("resolver chamado %d vezes, want 4", cepResolver.calls)
+	}
+	if weatherResolver.calls != 2 {
+		t.Errorf("provedor de temperatura chamado %d vezes, want 2", weatherResolver.calls)
+	}
+}
+
+func TestBatchWeatherHandlerValidation(t *testing.T) {
+	tooMany := make([]string, batchMaxCEPs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf(`"%08d"`, 1310100+i)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{"Método não permitido", "GET", "", http.StatusMethodNotAllowed, "method not allowed"},
+		{"JSON inválido", "POST", `{"ceps": [`, http.StatusBadRequest, "invalid request body"},
+		{"Lista vazia", "POST", `{"ceps": []}`, http.StatusBadRequest, "ceps parameter is required"},
+		{"Acima do limite", "POST", `{"ceps": [` + strings.Join(tooMany, ",") + `]}`, http.StatusBadRequest, "too many ceps"},
+	}
+
+	handler := NewBatchWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest(tt.method, "/weatherbycep/batch", strings.NewReader(tt.body)))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+			}
+			if errorResp.Message != tt.expectedMsg {
+				t.Errorf("Mensagem de erro incorreta: got %v want %v", errorResp.Message, tt.expectedMsg)
+			}
+		})
+	}
+}
+
+// blockingCEPResolver resolve os CEPs do resolver de base, exceto o CEP
+// bloqueado, cuja consulta só termina quando o contexto é encerrado
+type blockingCEPResolver struct {
+	next    CEPResolver
+	blocked string
+}
+
+func (b blockingCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	if formatCEP(cep) == b.blocked {
+		<-ctx.Done()
+		return nil, contextError(ctx)
+	}
+	return b.next.ResolveCEP(ctx, cep)
+}
+
+// signalingWeatherResolver avisa em called a cada consulta concluída
+type signalingWeatherResolver struct {
+	fakeWeatherResolver
+	called chan struct{}
+}
+
+func (s *signalingWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	defer func() { s.called <- struct{}{} }()
+	return s.fakeWeatherResolver.ResolveWeather(ctx, city, state)
+}
+
+func TestBatchWeatherHandlerClientCancel(t *testing.T) {
+	weather := &signalingWeatherResolver{fakeWeatherResolver: fakeWeatherResolver{tempC: 23}, called: make(chan struct{}, 1)}
+	handler := NewBatchWeatherHandler(blockingCEPResolver{next: newFakeCEPResolver(), blocked: "20040002"}, weather)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest("POST", "/weatherbycep/batch", strings.NewReader(`{"ceps": ["20040-002", "01310100"]}`)).WithContext(ctx)
+
+	// O cliente desiste logo depois de o CEP rápido terminar; a pausa dá
+	// tempo para o resultado ser guardado antes do cancelamento
+	go func() {
+		<-weather.called
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler(rr, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("o lote não terminou após o cancelamento da requisição")
+	}
+
+	var results []BatchResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("esperados 2 resultados, got %d", len(results))
+	}
+
+	if results[0].CEP != "20040-002" || results[0].Error == nil || results[0].Error.Message != "cancelled" || results[0].Weather != nil {
+		t.Errorf("resultado pendente = %+v, want erro cancelled", results[0])
+	}
+	if results[1].Error != nil || results[1].Weather == nil || results[1].Weather.TempC != 23 {
+		t.Errorf("resultado concluído = %+v, want temperatura preservada", results[1])
+	}
+}
+
+func TestBatchWeatherHandlerItemTimeout(t *testing.T) {
+	previous := batchItemTimeout
+	batchItemTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { batchItemTimeout = previous })
+
+	handler := NewBatchWeatherHandler(blockingCEPResolver{next: newFakeCEPResolver(), blocked: "20040002"}, &fakeWeatherResolver{tempC: 23})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("POST", "/weatherbycep/batch", strings.NewReader(`{"ceps": ["20040002", "01310100"]}`)))
+
+	var results []BatchResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	// O prazo de um item não cancela o lote: o CEP lento falha sozinho
+	if results[0].Error == nil || results[0].Error.Status != http.StatusGatewayTimeout || results[0].Error.Message != "upstream timeout" {
+		t.Errorf("resultado lento = %+v, want 504 upstream timeout", results[0].Error)
+	}
+	if results[1].Error != nil || results[1].Weather == nil {
+		t.Errorf("resultado rápido = %+v, want temperatura", results[1])
+	}
+}
+
+func TestBatchWeatherHandlerNDJSON(t *testing.T) {
+	cepResolver := newFakeCEPResolver()
+	cepResolver.data["20040002"] = &CEPData{CEP: "20040-002", Localidade: "Rio de Janeiro", UF: "RJ"}
+	handler := NewBatchWeatherHandler(cepResolver, &fakeWeatherResolver{tempC: 23})
+
+	body := `{"ceps": ["01310100", "99999999", "20040002", "123"]}`
+	req := httptest.NewRequest("POST", "/weatherbycep/batch", strings.NewReader(body))
+	req.Header.Set("Accept", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != ndjsonContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ndjsonContentType)
+	}
+	if !rr.Flushed {
+		t.Error("resultados não foram enviados antes do fim do lote")
+	}
+
+	// Cada resultado vem seguido do progresso, que cresce de um em um até o total
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("esperadas 8 linhas, got %d: %s", len(lines), rr.Body.String())
+	}
+	seen := make(map[int]string)
+	for i := 0; i < len(lines); i += 2 {
+		var result BatchStreamResult
+		if err := json.Unmarshal([]byte(lines[i]), &result); err != nil || result.CEP == "" {
+			t.Fatalf("linha %d não é um resultado: %s", i, lines[i])
+		}
+		seen[result.Index] = result.CEP
+
+		var event BatchProgressEvent
+		if err := json.Unmarshal([]byte(lines[i+1]), &event); err != nil {
+			t.Fatalf("linha %d não é um progresso: %s", i+1, lines[i+1])
+		}
+		if want := (BatchProgress{Processed: i/2 + 1, Total: 4}); event.Progress != want {
+			t.Errorf("progresso na linha %d = %+v, want %+v", i+1, event.Progress, want)
+		}
+	}
+
+	for i, cep := range []string{"01310100", "99999999", "20040002", "123"} {
+		if seen[i] != cep {
+			t.Errorf("index %d: cep = %q, want %q", i, seen[i], cep)
+		}
+	}
+}
+
+func TestWantsNDJSON(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"application/x-ndjson", true},
+		{"application/x-ndjson, application/json;q=0.5", true},
+		{"application/json, application/x-ndjson", false},
+		{"*/*", false},
+		{"application/x-ndjson;q=0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/weatherbycep/batch", nil)
+			req.Header.Set("Accept", tt.accept)
+			if got := wantsNDJSON(req); got != tt.expected {
+				t.Errorf("wantsNDJSON(%q) = %v, want %v", tt.accept, got, tt.expected)
+			}
+		})
+	}
+}
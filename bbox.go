@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBBoxStep é o espaçamento padrão da grade, em graus
+	defaultBBoxStep = 0.5
+	// maxBBoxPoints limita a quantidade de pontos amostrados por requisição
+	maxBBoxPoints = 25
+	// bboxConcurrency limita as consultas simultâneas ao wttr.in
+	bboxConcurrency = 5
+)
+
+// BBox representa uma área retangular em coordenadas geográficas
+type BBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+// GridPoint representa um ponto amostrado da área
+type GridPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// BBoxSample representa a temperatura de um ponto da grade ou o erro da consulta
+type BBoxSample struct {
+	GridPoint
+	Weather *WeatherData `json:"weather,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// gridCounts calcula quantos pontos a grade terá em cada eixo. As contagens
+// ficam em float64 para que um passo minúsculo não estoure a conversão para
+// int antes da verificação de maxBBoxPoints.
+func gridCounts(box BBox, step float64) (float64, float64) {
+	// A tolerância evita perder o último ponto por erro de ponto flutuante
+	const epsilon = 1e-9
+	nLat := math.Floor((box.MaxLat-box.MinLat)/step+epsilon) + 1
+	nLon := math.Floor((box.MaxLon-box.MinLon)/step+epsilon) + 1
+	return nLat, nLon
+}
+
+// gridSize calcula quantos pontos a grade terá em cada eixo; só deve ser
+// usada com grades já limitadas por maxBBoxPoints
+func gridSize(box BBox, step float64) (int, int) {
+	nLat, nLon := gridCounts(box, step)
+	return int(nLat), int(nLon)
+}
+
+// samplePoints gera a grade de pontos da área com o espaçamento informado
+func samplePoints(box BBox, step float64) []GridPoint {
+	nLat, nLon := gridSize(box, step)
+	points := make([]GridPoint, 0, nLat*nLon)
+	for i := 0; i < nLat; i++ {
+		for j := 0; j < nLon; j++ {
+			points = append(points, GridPoint{
+				Lat: box.MinLat + float64(i)*step,
+				Lon: box.MinLon + float64(j)*step,
+			})
+		}
+	}
+	return points
+}
+
+// parseBBox lê e valida a área e o espaçamento da query string
+func parseBBox(r *http.Request) (BBox, float64, string) {
+	query := r.URL.Query()
+
+	values := make(map[string]float64)
+	for _, name := range []string{"minlat", "minlon", "maxlat", "maxlon"} {
+		value, err := strconv.ParseFloat(query.Get(name), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return BBox{}, 0, "invalid " + name + " parameter"
+		}
+		values[name] = value
+	}
+
+	box := BBox{
+		MinLat: values["minlat"],
+		MinLon: values["minlon"],
+		MaxLat: values["maxlat"],
+		MaxLon: values["maxlon"],
+	}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLon < -180 || box.MaxLon > 180 {
+		return BBox{}, 0, "coordinates out of range"
+	}
+	if box.MinLat > box.MaxLat || box.MinLon > box.MaxLon {
+		return BBox{}, 0, "invalid bounding box"
+	}
+
+	step := defaultBBoxStep
+	if value := query.Get("step"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(parsed) || parsed <= 0 {
+			return BBox{}, 0, "invalid step parameter"
+		}
+		step = parsed
+	}
+
+	if nLat, nLon := gridCounts(box, step); nLat*nLon > maxBBoxPoints {
+		return BBox{}, 0, "too many sample points"
+	}
+
+	return box, step, ""
+}
+
+// CoordWeatherCache guarda por pouco tempo a temperatura de coordenadas já
+// consultadas, para que áreas sobrepostas ou repetidas não refaçam as
+// consultas ao wttr.in. As coordenadas são arredondadas como na consulta.
+type CoordWeatherCache struct {
+	*ttlCache[*WeatherData]
+}
+
+// NewCoordWeatherCache cria um cache de coordenadas com o TTL informado
+func NewCoordWeatherCache(ttl time.Duration) *CoordWeatherCache {
+	return &CoordWeatherCache{newTTLCache[*WeatherData](ttl, defaultCacheMaxEntries)}
+}
+
+// newCoordWeatherCacheFromEnv cria o cache com o mesmo TTL e tamanho do cache
+// de temperatura por cidade (WEATHER_CACHE_TTL e WEATHER_CACHE_MAX_ENTRIES)
+func newCoordWeatherCacheFromEnv() *CoordWeatherCache {
+	return &CoordWeatherCache{newTTLCache[*WeatherData](
+		envDuration("WEATHER_CACHE_TTL", defaultWeatherCacheTTL),
+		envIntWhere("WEATHER_CACHE_MAX_ENTRIES", defaultCacheMaxEntries, positive[int]),
+	)}
+}
+
+// coordCacheKey monta a chave lat,lon|idioma com a precisão usada na consulta
+func coordCacheKey(lat, lon float64, lang string) string {
+	return fmt.Sprintf("%.4f,%.4f|%s", lat, lon, lang)
+}
+
+// weatherByCoords consulta a temperatura da coordenada, usando o cache
+// quando informado e armazenando apenas as consultas bem-sucedidas
+func (c *CoordWeatherCache) weatherByCoords(ctx context.Context, lat, lon float64) (*WeatherData, *CustomError) {
+	if c == nil {
+		return getWeatherByCoords(ctx, lat, lon)
+	}
+	key := namespacedKey(ctx, coordCacheKey(lat, lon, weatherLangFromContext(ctx)))
+	if data, _, ok := c.get(key); ok {
+		return data, nil
+	}
+	data, err := getWeatherByCoords(ctx, lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, data)
+	return data, nil
+}
+
+// NewBBoxWeatherHandler cria o handler de /weather/bbox, consultando as
+// coordenadas pelo cache informado (nil consulta sempre o wttr.in)
+func NewBBoxWeatherHandler(cache *CoordWeatherCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		weatherByBBox(w, r, cache)
+	}
+}
+
+// weatherByBBox lida com as requisições GET para /weather/bbox
+func weatherByBBox(w http.ResponseWriter, r *http.Request, cache *CoordWeatherCache) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		setAllow(w, http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+		return
+	}
+
+	box, step, msg := parseBBox(r)
+	if msg != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: msg})
+		return
+	}
+
+	ctx := r.Context()
+	points := samplePoints(box, step)
+	samples := make([]BBoxSample, len(points))
+
+	// Consulta os pontos em paralelo, limitando as chamadas simultâneas. Com
+	// a requisição encerrada, os pontos ainda na fila não esperam por vaga.
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, bboxConcurrency)
+	for i, point := range points {
+		wg.Add(1)
+		go func(i int, point GridPoint) {
+			defer wg.Done()
+			samples[i].GridPoint = point
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				samples[i].Error = contextError(ctx).Message
+				return
+			}
+
+			weatherData, weatherErr := cache.weatherByCoords(ctx, point.Lat, point.Lon)
+			if weatherErr != nil {
+				samples[i].Error = weatherErr.Message
+				return
+			}
+			samples[i].Weather = weatherData
+		}(i, point)
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	encodeJSON(w, samples)
+}
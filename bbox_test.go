@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSamplePoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      BBox
+		step     float64
+		expected int
+	}{
+		{"Grade 3x3", BBox{MinLat: -23.6, MinLon: -46.7, MaxLat: -23.4, MaxLon: -46.5}, 0.1, 9},
+		{"Grade 2x3", BBox{MinLat: -23.5, MinLon: -46.5, MaxLat: -23.0, MaxLon: -45.5}, 0.5, 6},
+		{"Ponto único", BBox{MinLat: -23.5, MinLon: -46.6, MaxLat: -23.5, MaxLon: -46.6}, 0.5, 1},
+		{"Passo maior que a área", BBox{MinLat: -23.5, MinLon: -46.6, MaxLat: -23.4, MaxLon: -46.5}, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := samplePoints(tt.box, tt.step)
+			if len(points) != tt.expected {
+				t.Fatalf("samplePoints() retornou %d pontos, want %d", len(points), tt.expected)
+			}
+			for _, p := range points {
+				if p.Lat < tt.box.MinLat || p.Lat > tt.box.MaxLat+1e-9 ||
+					p.Lon < tt.box.MinLon || p.Lon > tt.box.MaxLon+1e-9 {
+					t.Errorf("ponto fora da área: %+v", p)
+				}
+			}
+		})
+	}
+}
+
+func TestWeatherByBBoxHandler(t *testing.T) {
+	var calls int32
+	original := httpClient.Transport
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		body := `{"current_condition":[{"temp_C":"20","humidity":"50"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	req := httptest.NewRequest("GET", "/weather/bbox?minlat=-23.6&minlon=-46.7&maxlat=-23.4&maxlon=-46.5&step=0.1", nil)
+	rr := httptest.NewRecorder()
+	NewBBoxWeatherHandler(nil)(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v (%s)", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var samples []BBoxSample
+	if err := json.Unmarshal(rr.Body.Bytes(), &samples); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	if len(samples) != 9 || atomic.LoadInt32(&calls) != 9 {
+		t.Fatalf("esperados 9 pontos e 9 consultas, got %d pontos e %d consultas", len(samples), calls)
+	}
+	for _, s := range samples {
+		if s.Weather == nil || s.Weather.TempC != 20 {
+			t.Errorf("ponto sem temperatura: %+v", s)
+		}
+	}
+}
+
+func TestWeatherByBBoxHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		expectedMsg string
+	}{
+		{"Parâmetro ausente", "?minlat=-23.6&minlon=-46.7&maxlat=-23.4", "invalid maxlon parameter"},
+		{"Coordenada inválida", "?minlat=abc&minlon=-46.7&maxlat=-23.4&maxlon=-46.5", "invalid minlat parameter"},
+		{"Área invertida", "?minlat=-23.4&minlon=-46.7&maxlat=-23.6&maxlon=-46.5", "invalid bounding box"},
+		{"Fora do intervalo", "?minlat=-95&minlon=-46.7&maxlat=-23.6&maxlon=-46.5", "coordinates out of range"},
+		{"Passo inválido", "?minlat=-23.6&minlon=-46.7&maxlat=-23.4&maxlon=-46.5&step=0", "invalid step parameter"},
+		{"Pontos demais", "?minlat=-30&minlon=-50&maxlat=-20&maxlon=-40&step=0.1", "too many sample points"},
+		// A contagem de pontos estouraria o int se fosse convertida antes da verificação
+		{"Passo minúsculo", "?minlat=-23.6&minlon=-46.7&maxlat=-23.4&maxlon=-46.5&step=1e-300", "too many sample points"},
+		{"Passo subnormal", "?minlat=-23.6&minlon=-46.7&maxlat=-23.4&maxlon=-46.5&step=5e-324", "too many sample points"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			NewBBoxWeatherHandler(nil)(rr, httptest.NewRequest("GET", "/weather/bbox"+tt.query, nil))
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+			}
+			if errorResp.Message != tt.expectedMsg {
+				t.Errorf("Mensagem de erro incorreta: got %v want %v", errorResp.Message, tt.expectedMsg)
+			}
+		})
+	}
+}
+
+func TestWeatherByBBoxHandlerUsesCoordCache(t *testing.T) {
+	var calls int32
+	useTransport(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"current_condition":[{"temp_C":"20","humidity":"50"}]}`)),
+			Request:    req,
+		}, nil
+	}))
+
+	cache := NewCoordWeatherCache(time.Minute)
+	t.Cleanup(cache.Stop)
+	handler := NewBBoxWeatherHandler(cache)
+
+	// A segunda área repete os quatro pontos da primeira e acrescenta dois
+	for _, query := range []string{
+		"?minlat=-23.6&minlon=-46.7&maxlat=-23.5&maxlon=-46.6&step=0.1",
+		"?minlat=-23.6&minlon=-46.7&maxlat=-23.5&maxlon=-46.5&step=0.1",
+	} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/weather/bbox"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 (%s)", rr.Code, rr.Body.String())
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 6 {
+		t.Errorf("wttr.in consultado %d vezes, want 6", got)
+	}
+}
+
+func TestWeatherByBBoxHandlerStopsWaitingOnCancel(t *testing.T) {
+	// O primeiro lote de consultas fica preso até a requisição ser cancelada
+	useTransport(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/weather/bbox?minlat=-23.6&minlon=-46.7&maxlat=-23.4&maxlon=-46.5&step=0.1", nil).WithContext(ctx)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rr := httptest.NewRecorder()
+		NewBBoxWeatherHandler(nil)(rr, req)
+		done <- rr
+	}()
+
+	select {
+	case rr := <-done:
+		var samples []BBoxSample
+		if err := json.Unmarshal(rr.Body.Bytes(), &samples); err != nil {
+			t.Fatalf("Resposta não é um JSON válido: %v", err)
+		}
+		for _, s := range samples {
+			if s.Error == "" {
+				t.Errorf("ponto sem erro após o cancelamento: %+v", s)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("o handler não terminou após o prazo da requisição")
+	}
+}
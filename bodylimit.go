@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// defaultMaxRequestBodyBytes é o tamanho máximo padrão do corpo das requisições POST
+const defaultMaxRequestBodyBytes = 64 << 10
+
+// bodyLimit protege os endpoints de dados contra corpos grandes demais, que
+// esgotariam a memória ao serem decodificados, e contra corpos enviados em
+// requisições GET, que os handlers simplesmente ignorariam
+type bodyLimit struct {
+	maxBytes int64
+}
+
+// newBodyLimitFromEnv lê MAX_REQUEST_BODY_BYTES
+func newBodyLimitFromEnv() *bodyLimit {
+	return &bodyLimit{maxBytes: envInt64("MAX_REQUEST_BODY_BYTES", defaultMaxRequestBodyBytes, positive[int64])}
+}
+
+// Wrap aplica o limite de corpo ao handler informado. Corpos sem tamanho
+// declarado (chunked) são cortados durante a leitura pelo http.MaxBytesReader,
+// e o handler responde 413 ao receber o erro, via isBodyTooLarge
+func (l *bodyLimit) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if hasBody(r) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(ErrorResponse{Message: "request body not allowed"})
+				return
+			}
+		default:
+			if r.ContentLength > l.maxBytes {
+				writePayloadTooLarge(w)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, l.maxBytes)
+		}
+		next(w, r)
+	}
+}
+
+// hasBody indica se a requisição trouxe um corpo, declarado ou chunked
+func hasBody(r *http.Request) bool {
+	if r.ContentLength > 0 {
+		return true
+	}
+	return r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody
+}
+
+// isBodyTooLarge indica se o erro de leitura do corpo veio do limite de bodyLimit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// writePayloadTooLarge responde 413 quando o corpo excede o limite
+func writePayloadTooLarge(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	json.NewEncoder(w).Encode(ErrorResponse{Message: "payload too large"})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	// Imita o handler do lote: lê o corpo inteiro e trata o erro do limite
+	next := func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if isBodyTooLarge(err) {
+				writePayloadTooLarge(w)
+				return
+			}
+			t.Fatalf("erro inesperado ao ler o corpo: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := (&bodyLimit{maxBytes: 16}).Wrap(next)
+
+	tests := []struct {
+		name            string
+		method          string
+		body            string
+		chunked         bool
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{"GET sem corpo", "GET", "", false, http.StatusOK, ""},
+		{"GET com corpo", "GET", `{"ceps": []}`, false, http.StatusBadRequest, "request body not allowed"},
+		{"GET com corpo chunked", "GET", `{"ceps": []}`, true, http.StatusBadRequest, "request body not allowed"},
+		{"POST dentro do limite", "POST", `{"ceps": []}`, false, http.StatusOK, ""},
+		{"POST acima do limite", "POST", strings.Repeat("x", 17), false, http.StatusRequestEntityTooLarge, "payload too large"},
+		{"POST chunked acima do limite", "POST", strings.Repeat("x", 17), true, http.StatusRequestEntityTooLarge, "payload too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "/weatherbycep/batch", body)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedMessage == "" {
+				return
+			}
+			var errResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("resposta de erro não é um JSON válido: %v", err)
+			}
+			if errResp.Message != tt.expectedMessage {
+				t.Errorf("mensagem = %q, want %q", errResp.Message, tt.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestBatchHandlerRejectsOversizedBody(t *testing.T) {
+	handler := (&bodyLimit{maxBytes: 64}).Wrap(NewBatchWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23}))
+	body := `{"ceps": ["` + strings.Repeat("01310100", 20) + `"]}`
+
+	// Sem Content-Length o limite só é percebido durante a decodificação
+	req := httptest.NewRequest("POST", "/weatherbycep/batch", strings.NewReader(body))
+	req.ContentLength = -1
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413 (corpo: %s)", rr.Code, rr.Body.String())
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || errResp.Message != "payload too large" {
+		t.Errorf("resposta = %s, want payload too large", rr.Body.String())
+	}
+}
+
+func TestNewBodyLimitFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected int64
+	}{
+		{"Padrão", "", defaultMaxRequestBodyBytes},
+		{"Valor customizado", "1024", 1024},
+		{"Zero usa o padrão", "0", defaultMaxRequestBodyBytes},
+		{"Inválido usa o padrão", "muito", defaultMaxRequestBodyBytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_REQUEST_BODY_BYTES", tt.value)
+			if got := newBodyLimitFromEnv().maxBytes; got != tt.expected {
+				t.Errorf("maxBytes = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
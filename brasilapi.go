@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"golang-weatherbycep/weather"
+)
+
+// brasilAPICEPResolver implementa CEPResolver consultando a API de CEP v2 da
+// BrasilAPI, usada como alternativa quando o ViaCEP está fora do ar
+type brasilAPICEPResolver struct{}
+
+func (brasilAPICEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	return searchBrasilAPI(ctx, cep)
+}
+
+// searchBrasilAPI consulta o CEP na BrasilAPI e normaliza a resposta para CEPData
+func searchBrasilAPI(ctx context.Context, cep string) (_ *CEPData, cepErr *CustomError) {
+	ctx, span := startSpan(ctx, spanBrasilAPILookup, attribute.String("cep", formatCEP(cep)))
+	defer func() { endSpan(span, cepErr) }()
+
+	if detail := cepValidationDetail(cep); detail != "" {
+		return nil, &CustomError{Code: 422, Message: "invalid zipcode", Detail: detail, Err: weather.ErrInvalidCEP}
+	}
+	formattedCEP := formatCEP(cep)
+
+	ctx, cancel := withUpstreamTimeout(ctx, config.timeoutFor(providerBrasilAPI, config.ViaCEPTimeout))
+	defer cancel()
+
+	var address struct {
+		CEP          string `json:"cep"`
+		State        string `json:"state"`
+		City         string `json:"city"`
+		Neighborhood string `json:"neighborhood"`
+		Street       string `json:"street"`
+	}
+	err := newUpstreamClient(ctx).GetJSON(ctx, fmt.Sprintf("%s/%s", config.BrasilAPIBaseURL, formattedCEP), &address)
+	// A BrasilAPI responde 404 quando nenhum dos serviços encontra o CEP
+	var statusErr *weather.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		logger.InfoContext(ctx, "CEP não encontrado", "provider", providerBrasilAPI, "cep", formattedCEP)
+		return nil, &CustomError{Code: 404, Message: "can not find zipcode", Err: weather.ErrCEPNotFound}
+	}
+	if err != nil {
+		return nil, upstreamError(ctx, providerBrasilAPI, err, "cep", formattedCEP)
+	}
+
+	// Mesmo formato do ViaCEP: CEP com hífen e nomes de campos em português.
+	// Campos que só o ViaCEP informa (IBGE, DDD etc.) ficam vazios.
+	span.SetAttributes(attribute.String("city", address.City), attribute.String("state", address.State))
+	return &CEPData{
+		CEP:        formattedCEP[:5] + "-" + formattedCEP[5:],
+		Logradouro: address.Street,
+		Bairro:     address.Neighborhood,
+		Localidade: address.City,
+		UF:         address.State,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChainedCEPResolver(t *testing.T) {
+	tests := []struct {
+		name            string
+		primaryErr      *CustomError
+		expectedErr     string
+		secondaryCalled bool
+	}{
+		{"Primário responde", nil, "", false},
+		{"Primário indisponível", &CustomError{Code: 502, Message: "bad gateway"}, "", true},
+		{"Primário com timeout", &CustomError{Code: 504, Message: "upstream timeout"}, "", true},
+		{"CEP inexistente não usa fallback", &CustomError{Code: 404, Message: "can not find zipcode"}, "can not find zipcode", false},
+		{"CEP inválido não usa fallback", &CustomError{Code: 422, Message: "invalid zipcode"}, "invalid zipcode", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newFakeCEPResolver()
+			primary.err = tt.primaryErr
+			secondary := newFakeCEPResolver()
+
+			data, err := ChainedCEPResolver{Primary: primary, Secondary: secondary}.ResolveCEP(context.Background(), "01310100")
+			if tt.expectedErr != "" {
+				if err == nil || err.Message != tt.expectedErr {
+					t.Errorf("erro = %v, want %q", err, tt.expectedErr)
+				}
+			} else if err != nil || data.Localidade != "São Paulo" {
+				t.Errorf("ResolveCEP = (%+v, %v), want São Paulo", data, err)
+			}
+			if called := secondary.calls > 0; called != tt.secondaryCalled {
+				t.Errorf("secundário chamado = %v, want %v", called, tt.secondaryCalled)
+			}
+		})
+	}
+}
+
+func TestChainedCEPResolverBothFail(t *testing.T) {
+	primaryErr := &CustomError{Code: 502, Message: "bad gateway"}
+	chain := ChainedCEPResolver{
+		Primary:   &fakeCEPResolver{err: primaryErr},
+		Secondary: &fakeCEPResolver{err: &CustomError{Code: 500, Message: "internal server error"}},
+	}
+
+	if _, err := chain.ResolveCEP(context.Background(), "01310100"); err != primaryErr {
+		t.Errorf("erro = %v, want o erro do primário", err)
+	}
+}
+
+func TestChainedCEPResolverSkipsSecondaryWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	secondary := newFakeCEPResolver()
+	chain := ChainedCEPResolver{
+		Primary:   &fakeCEPResolver{err: &CustomError{Code: 500, Message: "request canceled"}},
+		Secondary: secondary,
+	}
+
+	if _, err := chain.ResolveCEP(ctx, "01310100"); err == nil {
+		t.Fatal("ResolveCEP deveria falhar com o contexto cancelado")
+	}
+	if secondary.calls != 0 {
+		t.Errorf("secundário não deveria ser consultado: %d chamadas", secondary.calls)
+	}
+}
+
+func TestSearchBrasilAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/cep/v2/01310100":
+			w.Write([]byte(`{"cep": "01310100", "state": "SP", "city": "São Paulo", "neighborhood": "Bela Vista", "street": "Avenida Paulista", "service": "open-cep"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"name": "CepPromiseError", "message": "Todos os serviços de CEP retornaram erro.", "type": "service_error"}`))
+		}
+	}))
+	defer server.Close()
+	useConfig(t, Config{BrasilAPIBaseURL: server.URL + "/api/cep/v2"})
+
+	data, err := searchBrasilAPI(context.Background(), "01310-100")
+	if err != nil {
+		t.Fatalf("searchBrasilAPI retornou erro: %v", err)
+	}
+	expected := CEPData{CEP: "01310-100", Logradouro: "Avenida Paulista", Bairro: "Bela Vista", Localidade: "São Paulo", UF: "SP"}
+	if *data != expected {
+		t.Errorf("dados normalizados = %+v, want %+v", *data, expected)
+	}
+
+	if _, err := searchBrasilAPI(context.Background(), "99999999"); err == nil || err.Code != 404 {
+		t.Errorf("erro = %v, want 404", err)
+	}
+}
+
+func TestWeatherByCEPHandlerFallsBackToBrasilAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/cep/v2/01310100":
+			w.Write([]byte(`{"cep": "01310100", "state": "SP", "city": "São Paulo", "neighborhood": "Bela Vista", "street": "Avenida Paulista"}`))
+		default:
+			// O ViaCEP está fora do ar
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	useRetryBaseDelay(t, 0)
+	useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws", BrasilAPIBaseURL: server.URL + "/api/cep/v2"})
+
+	weatherResolver := &fakeWeatherResolver{tempC: 24}
+	handler := NewWeatherHandler(ChainedCEPResolver{Primary: viaCEPResolver{}, Secondary: brasilAPICEPResolver{}}, weatherResolver)
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		Address MinAddress `json:"address"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (MinAddress{CEP: "01310-100", Localidade: "São Paulo", UF: "SP"}); resp.Address != expected {
+		t.Errorf("address = %+v, want %+v", resp.Address, expected)
+	}
+}
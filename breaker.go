@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBreakerThreshold é o número de falhas consecutivas que abre o circuito
+	defaultBreakerThreshold = 5
+	// defaultBreakerCooldown é o tempo que o circuito fica aberto antes de testar o upstream
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// Circuit breakers dos upstreams consultados por searchCEP, fetchWttr e pelo
+// nominatimGeocoder. Ficam nil (desativados) até serem configurados pelo main.
+var (
+	viaCEPBreaker   *circuitBreaker
+	wttrBreaker     *circuitBreaker
+	geocoderBreaker *circuitBreaker
+)
+
+// breakerState é o estado de um circuit breaker
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker interrompe as chamadas a um upstream após falhas
+// consecutivas. Aberto, recusa as chamadas até o fim do cooldown; então
+// deixa passar uma única chamada de teste (half-open), que fecha o circuito
+// se tiver sucesso ou o reabre se falhar.
+type circuitBreaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker cria um circuit breaker fechado para o upstream informado
+func newCircuitBreaker(provider string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{provider: provider, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// newCircuitBreakerFromEnv configura o circuit breaker a partir de
+// CIRCUIT_BREAKER_THRESHOLD e CIRCUIT_BREAKER_COOLDOWN. Um limite 0 desativa
+// o circuit breaker.
+func newCircuitBreakerFromEnv(provider string) *circuitBreaker {
+	threshold := envInt("CIRCUIT_BREAKER_THRESHOLD", defaultBreakerThreshold)
+	if threshold == 0 {
+		return nil
+	}
+	cooldown := envDuration("CIRCUIT_BREAKER_COOLDOWN", defaultBreakerCooldown)
+
+	return newCircuitBreaker(provider, threshold, cooldown)
+}
+
+// Allow indica se a chamada ao upstream pode ser feita. Um breaker nil sempre permite.
+func (b *circuitBreaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		logger.Info("testando o upstream após o cooldown", "provider", b.provider)
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// Apenas uma chamada de teste por vez
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record registra o resultado de uma chamada permitida por Allow
+func (b *circuitBreaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.open()
+			return
+		}
+		logger.Info("circuito fechado", "provider", b.provider)
+		b.state = breakerClosed
+		b.failures = 0
+	case breakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+// Release libera a chamada de teste sem registrar resultado, quando o
+// próprio cliente desistiu da requisição
+func (b *circuitBreaker) Release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Done registra o resultado da consulta: erros 5xx contam como falha do
+// upstream, a menos que o cliente tenha cancelado a requisição ou que a
+// chamada nem tenha saído por falta de vaga no limitador
+func (b *circuitBreaker) Done(ctx context.Context, err *CustomError) {
+	if err != nil && (errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, errServerBusy)) {
+		b.Release()
+		return
+	}
+	b.Record(err != nil && err.Code >= 500)
+}
+
+// State retorna o estado atual do circuito
+func (b *circuitBreaker) State() breakerState {
+	if b == nil {
+		return breakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// open abre o circuito; deve ser chamado com o mutex travado
+func (b *circuitBreaker) open() {
+	logger.Warn("circuito aberto", "provider", b.provider, "failures", b.failures, "cooldown", b.cooldown.String())
+	b.state = breakerOpen
+	b.openedAt = b.now()
+	b.failures = 0
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useBreakers substitui os circuit breakers dos upstreams durante o teste
+func useBreakers(t *testing.T, viaCEP, wttr *circuitBreaker) {
+	t.Helper()
+	previousViaCEP, previousWttr := viaCEPBreaker, wttrBreaker
+	viaCEPBreaker, wttrBreaker = viaCEP, wttr
+	t.Cleanup(func() { viaCEPBreaker, wttrBreaker = previousViaCEP, previousWttr })
+}
+
+// newTestBreaker cria um circuit breaker com relógio controlado pelo teste
+func newTestBreaker(threshold int, cooldown time.Duration, now *time.Time) *circuitBreaker {
+	b := newCircuitBreaker("teste", threshold, cooldown)
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(3, 30*time.Second, &now)
+
+	// Falhas abaixo do limite mantêm o circuito fechado, e um sucesso zera a contagem
+	for i := 0; i < 2; i++ {
+		b.Allow()
+		b.Record(true)
+	}
+	b.Allow()
+	b.Record(false)
+	if b.State() != breakerClosed {
+		t.Fatalf("estado = %v, want closed", b.State())
+	}
+
+	// Falhas consecutivas abrem o circuito
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatalf("chamada %d recusada com o circuito fechado", i+1)
+		}
+		b.Record(true)
+	}
+	if b.State() != breakerOpen {
+		t.Fatalf("estado = %v, want open", b.State())
+	}
+	if b.Allow() {
+		t.Error("o circuito aberto deveria recusar as chamadas")
+	}
+
+	// Após o cooldown apenas uma chamada de teste passa
+	now = now.Add(30 * time.Second)
+	if !b.Allow() {
+		t.Fatal("o circuito deveria permitir a chamada de teste após o cooldown")
+	}
+	if b.State() != breakerHalfOpen {
+		t.Fatalf("estado = %v, want half-open", b.State())
+	}
+	if b.Allow() {
+		t.Error("apenas uma chamada de teste deveria passar no half-open")
+	}
+
+	// A chamada de teste falhou: o circuito reabre por mais um cooldown
+	b.Record(true)
+	if b.State() != breakerOpen || b.Allow() {
+		t.Fatalf("estado = %v, want open após a falha do teste", b.State())
+	}
+
+	// Nova chamada de teste bem-sucedida fecha o circuito
+	now = now.Add(30 * time.Second)
+	if !b.Allow() {
+		t.Fatal("o circuito deveria permitir a chamada de teste após o cooldown")
+	}
+	b.Record(false)
+	if b.State() != breakerClosed || !b.Allow() {
+		t.Errorf("estado = %v, want closed após o sucesso do teste", b.State())
+	}
+}
+
+func TestCircuitBreakerReleaseOnCancel(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(1, time.Second, &now)
+	b.Allow()
+	b.Record(true)
+	now = now.Add(time.Second)
+	b.Allow()
+
+	// O cliente desistiu da chamada de teste: ela não conta e outra pode ser feita
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Done(ctx, &CustomError{Code: 500, Message: "request canceled"})
+	if b.State() != breakerHalfOpen {
+		t.Fatalf("estado = %v, want half-open", b.State())
+	}
+	if !b.Allow() {
+		t.Error("a chamada de teste cancelada deveria liberar uma nova tentativa")
+	}
+}
+
+func TestCircuitBreakerShortCircuitsUpstreams(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws", WeatherBaseURL: server.URL + "/weather"})
+	useRetryBaseDelay(t, 0)
+
+	tests := []struct {
+		name    string
+		use     func(b *circuitBreaker)
+		lookup  func() *CustomError
+		message string
+	}{
+		{
+			name: "ViaCEP",
+			use:  func(b *circuitBreaker) { useBreakers(t, b, nil) },
+			lookup: func() *CustomError {
+				_, err := searchCEP(context.Background(), "01310100")
+				return err
+			},
+			message: "zipcode lookup temporarily unavailable",
+		},
+		{
+			name: "wttr.in",
+			use:  func(b *circuitBreaker) { useBreakers(t, nil, b) },
+			lookup: func() *CustomError {
+				_, err := getWeatherData(context.Background(), "São Paulo", "SP")
+				return err
+			},
+			message: "weather temporarily unavailable",
+		},
+		{
+			name: "Nominatim",
+			use: func(b *circuitBreaker) {
+				previous := geocoderBreaker
+				geocoderBreaker = b
+				t.Cleanup(func() { geocoderBreaker = previous })
+			},
+			lookup: func() *CustomError {
+				_, err := (&nominatimGeocoder{baseURL: server.URL + "/search"}).Geocode(context.Background(), "São Paulo, SP, Brasil")
+				return err
+			},
+			message: "geocoder temporarily unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			tt.use(newTestBreaker(2, time.Minute, &now))
+
+			for i := 0; i < 2; i++ {
+				if err := tt.lookup(); err == nil || err.Code != 502 {
+					t.Fatalf("consulta %d: erro = %v, want 502", i+1, err)
+				}
+			}
+
+			calls.Store(0)
+			err := tt.lookup()
+			if err == nil || err.Code != 503 || err.Message != tt.message {
+				t.Fatalf("erro = %v, want 503 %q", err, tt.message)
+			}
+			if n := calls.Load(); n != 0 {
+				t.Errorf("upstream recebeu %d chamadas com o circuito aberto, want 0", n)
+			}
+
+			// Após o cooldown o upstream volta a ser consultado
+			now = now.Add(time.Minute)
+			if err := tt.lookup(); err == nil || err.Code != 502 {
+				t.Errorf("erro = %v, want 502 da chamada de teste", err)
+			}
+			if calls.Load() == 0 {
+				t.Error("a chamada de teste deveria consultar o upstream")
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"erro": true}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := newTestBreaker(1, time.Minute, &now)
+	useBreakers(t, breaker, nil)
+	useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws"})
+
+	for i := 0; i < 3; i++ {
+		if _, err := searchCEP(context.Background(), "01310100"); err == nil || err.Code != 404 {
+			t.Fatalf("erro = %v, want 404", err)
+		}
+	}
+	if breaker.State() != breakerClosed {
+		t.Errorf("estado = %v, want closed: CEP inexistente não é falha do upstream", breaker.State())
+	}
+}
+
+func TestNewCircuitBreakerFromEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string
+		cooldown  string
+		disabled  bool
+		limit     int
+		wait      time.Duration
+	}{
+		{"Padrão", "", "", false, defaultBreakerThreshold, defaultBreakerCooldown},
+		{"Configurado", "3", "10s", false, 3, 10 * time.Second},
+		{"Valores inválidos usam o padrão", "muitos", "-1s", false, defaultBreakerThreshold, defaultBreakerCooldown},
+		{"Desativado", "0", "", true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CIRCUIT_BREAKER_THRESHOLD", tt.threshold)
+			t.Setenv("CIRCUIT_BREAKER_COOLDOWN", tt.cooldown)
+
+			b := newCircuitBreakerFromEnv(providerWttr)
+			if tt.disabled {
+				if b != nil {
+					t.Errorf("breaker = %+v, want nil", b)
+				}
+				return
+			}
+			if b == nil || b.threshold != tt.limit || b.cooldown != tt.wait {
+				t.Errorf("breaker = %+v, want limite %d e cooldown %v", b, tt.limit, tt.wait)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresServerBusy(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := newTestBreaker(1, 30*time.Second, &now)
+
+	// Uma chamada que nem saiu por falta de vaga não é falha do upstream
+	b.Allow()
+	b.Done(context.Background(), serverBusyError())
+	if b.State() != breakerClosed {
+		t.Errorf("estado = %v, want closed", b.State())
+	}
+}
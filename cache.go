@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+// defaultCEPCacheTTL é o tempo de vida padrão das entradas; endereços raramente mudam
+const defaultCEPCacheTTL = 24 * time.Hour
+
+// cacheStats conta as consultas atendidas (hits) e não atendidas (misses)
+// por um cache, expostas em /metrics
+type cacheStats struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// record contabiliza o resultado de uma consulta ao cache
+func (s *cacheStats) record(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+// Stats retorna a quantidade de hits e misses desde a criação do cache
+func (s *cacheStats) Stats() (hits, misses uint64) {
+	return s.hits.Load(), s.misses.Load()
+}
+
+// CEPCache mantém em memória os dados de CEPs já consultados, por um tempo
+// limitado, chaveados pelo CEP formatado
+type CEPCache struct {
+	*ttlCache[*CEPData]
+}
+
+// NewCEPCache cria um cache com o TTL informado e inicia a remoção periódica
+// das entradas expiradas
+func NewCEPCache(ttl time.Duration) *CEPCache {
+	return &CEPCache{newTTLCache[*CEPData](ttl, defaultCacheMaxEntries)}
+}
+
+// newCEPCacheFromEnv cria o cache usando o TTL de CEP_CACHE_TTL (ex.: "12h")
+// e o tamanho de CEP_CACHE_MAX_ENTRIES
+func newCEPCacheFromEnv() *CEPCache {
+	return &CEPCache{newTTLCache[*CEPData](
+		envDuration("CEP_CACHE_TTL", defaultCEPCacheTTL),
+		envIntWhere("CEP_CACHE_MAX_ENTRIES", defaultCacheMaxEntries, positive[int]),
+	)}
+}
+
+// cepCacheKey monta a chave do CEP nos caches de CEPs, apenas com letras e
+// dígitos, para que "01310-100" e "01310100" compartilhem a entrada
+func cepCacheKey(cep string) string {
+	return formatCEP(cep)
+}
+
+// Get retorna os dados do CEP se estiverem no cache, no namespace do
+// contexto, e ainda não tiverem expirado
+func (c *CEPCache) Get(ctx context.Context, cep string) (*CEPData, bool) {
+	data, _, ok := c.get(namespacedKey(ctx, cepCacheKey(cep)))
+	return data, ok
+}
+
+// Set armazena os dados do CEP, no namespace do contexto, pelo TTL configurado
+func (c *CEPCache) Set(ctx context.Context, cep string, data *CEPData) {
+	c.set(namespacedKey(ctx, cepCacheKey(cep)), data)
+}
+
+// cachingCEPResolver consulta o cache antes de delegar ao resolver informado,
+// armazenando as consultas bem-sucedidas e, em notFound, os CEPs inexistentes
+type cachingCEPResolver struct {
+	cache    *CEPCache
+	notFound *NotFoundCache
+	next     CEPResolver
+}
+
+func (r cachingCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	if data, ok := r.cache.Get(ctx, cep); ok {
+		return data, nil
+	}
+	if r.notFound.Contains(ctx, cep) {
+		return nil, &CustomError{Code: 404, Message: "can not find zipcode", Err: weather.ErrCEPNotFound}
+	}
+
+	data, err := r.next.ResolveCEP(ctx, cep)
+	if err != nil {
+		if errors.Is(err, weather.ErrCEPNotFound) {
+			r.notFound.Add(ctx, cep)
+		}
+		return nil, err
+	}
+	r.cache.Set(ctx, cep, data)
+	return data, nil
+}
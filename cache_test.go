@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestCEPCache cria um cache com relógio controlado pelo teste
+func newTestCEPCache(t *testing.T, ttl time.Duration, now *time.Time) *CEPCache {
+	t.Helper()
+	cache := NewCEPCache(ttl)
+	cache.now = func() time.Time { return *now }
+	t.Cleanup(cache.Stop)
+	return cache
+}
+
+func TestCEPCacheGetSet(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestCEPCache(t, time.Hour, &now)
+
+	if _, ok := cache.Get(context.Background(), "01310100"); ok {
+		t.Fatal("cache vazio não deveria retornar dados")
+	}
+
+	data := &CEPData{CEP: "01310-100", Localidade: "São Paulo", UF: "SP"}
+	cache.Set(context.Background(), "01310-100", data)
+
+	// A chave é o CEP formatado, com ou sem hífen
+	for _, cep := range []string{"01310100", "01310-100"} {
+		if got, ok := cache.Get(context.Background(), cep); !ok || got != data {
+			t.Errorf("Get(%q) = (%v, %v), want (%v, true)", cep, got, ok, data)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := cache.Get(context.Background(), "01310100"); ok {
+		t.Error("entrada expirada não deveria ser retornada")
+	}
+}
+
+func TestCEPCacheEvictExpired(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestCEPCache(t, time.Hour, &now)
+
+	cache.Set(context.Background(), "01310100", &CEPData{CEP: "01310-100"})
+	now = now.Add(30 * time.Minute)
+	cache.Set(context.Background(), "20040002", &CEPData{CEP: "20040-002"})
+
+	now = now.Add(45 * time.Minute)
+	cache.evictExpired()
+
+	if _, ok := cache.entries["01310100"]; ok {
+		t.Error("entrada expirada deveria ter sido removida")
+	}
+	if _, ok := cache.entries["20040002"]; !ok {
+		t.Error("entrada válida não deveria ter sido removida")
+	}
+}
+
+func TestCachingCEPResolver(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	upstream := newFakeCEPResolver()
+	resolver := cachingCEPResolver{cache: newTestCEPCache(t, time.Hour, &now), next: upstream}
+
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.ResolveCEP(context.Background(), "01310100"); err != nil {
+			t.Fatalf("ResolveCEP retornou erro: %v", err)
+		}
+	}
+	if upstream.calls != 1 {
+		t.Errorf("upstream chamado %d vezes, want 1", upstream.calls)
+	}
+
+	// Erros não são armazenados
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.ResolveCEP(context.Background(), "99999999"); err == nil {
+			t.Fatal("ResolveCEP deveria retornar erro para CEP desconhecido")
+		}
+	}
+	if upstream.calls != 3 {
+		t.Errorf("upstream chamado %d vezes, want 3", upstream.calls)
+	}
+}
+
+func TestWeatherByCEPHandlerUsesCEPCache(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	upstream := newFakeCEPResolver()
+	resolver := cachingCEPResolver{cache: newTestCEPCache(t, time.Hour, &now), next: upstream}
+	handler := NewWeatherHandler(resolver, &fakeWeatherResolver{tempC: 23})
+
+	for _, path := range []string{"/weatherbycep/01310100", "/weatherbycep/01310-100"} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", path, nil))
+		if rr.Code != 200 {
+			t.Fatalf("GET %s: status = %d, want 200", path, rr.Code)
+		}
+	}
+
+	if upstream.calls != 1 {
+		t.Errorf("ViaCEP consultado %d vezes, want 1", upstream.calls)
+	}
+}
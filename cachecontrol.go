@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultCacheMaxAge é o tempo, em segundos, que clientes e CDNs podem
+// reaproveitar uma resposta de sucesso; a temperatura é estável por minutos
+const defaultCacheMaxAge = 600
+
+// cacheMaxAge é o max-age enviado no Cache-Control das respostas de sucesso
+var cacheMaxAge = newCacheMaxAgeFromEnv()
+
+// newCacheMaxAgeFromEnv lê CACHE_MAX_AGE (em segundos; 0 desativa o cache nos clientes)
+func newCacheMaxAgeFromEnv() int {
+	return envInt("CACHE_MAX_AGE", defaultCacheMaxAge)
+}
+
+// setNoStore impede que respostas de erro sejam guardadas por clientes e CDNs
+func setNoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// setCacheHeaders marca uma resposta de sucesso como reaproveitável por
+// cacheMaxAge segundos e informa em Last-Modified quando os dados foram
+// obtidos; com lastModified zero (momento desconhecido) o header é omitido
+func setCacheHeaders(w http.ResponseWriter, lastModified time.Time) {
+	if cacheMaxAge == 0 {
+		setNoStore(w)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(cacheMaxAge))
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWeatherByCEPHandlerCacheControl(t *testing.T) {
+	fetchedAt := time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		path                 string
+		fetchedAt            time.Time
+		expectedStatus       int
+		expectedCacheControl string
+		expectedLastModified string
+	}{
+		{"Sucesso", "/weatherbycep/01310100", fetchedAt, http.StatusOK, "public, max-age=600", "Wed, 15 Jan 2025 12:30:00 GMT"},
+		{"Momento da consulta desconhecido", "/weatherbycep/01310100", time.Time{}, http.StatusOK, "public, max-age=600", ""},
+		{"CEP não encontrado", "/weatherbycep/99999999", fetchedAt, http.StatusNotFound, "no-store", ""},
+		{"CEP inválido", "/weatherbycep/123", fetchedAt, http.StatusUnprocessableEntity, "no-store", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20, fetchedAt: tt.fetchedAt})
+
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != tt.expectedCacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.expectedCacheControl)
+			}
+			if got := rr.Header().Get("Last-Modified"); got != tt.expectedLastModified {
+				t.Errorf("Last-Modified = %q, want %q", got, tt.expectedLastModified)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerLastModifiedFromCache(t *testing.T) {
+	cache := NewWeatherCache(time.Minute)
+	t.Cleanup(cache.Stop)
+
+	upstream := &fakeWeatherResolver{tempC: 20, fetchedAt: time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC)}
+	handler := NewWeatherHandler(newFakeCEPResolver(), cachingWeatherResolver{cache: cache, next: upstream})
+
+	first := httptest.NewRecorder()
+	handler(first, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	// Uma nova consulta ao provedor teria outro momento; o acerto no cache não
+	upstream.fetchedAt = upstream.fetchedAt.Add(5 * time.Minute)
+	second := httptest.NewRecorder()
+	handler(second, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if upstream.calls != 1 {
+		t.Fatalf("chamadas ao provedor = %d, want 1", upstream.calls)
+	}
+	want := "Wed, 15 Jan 2025 12:30:00 GMT"
+	for name, rr := range map[string]*httptest.ResponseRecorder{"primeira": first, "segunda": second} {
+		if got := rr.Header().Get("Last-Modified"); got != want {
+			t.Errorf("Last-Modified da %s resposta = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNewCacheMaxAgeFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", defaultCacheMaxAge},
+		{"60", 60},
+		{"0", 0},
+		{"-1", defaultCacheMaxAge},
+		{"dez", defaultCacheMaxAge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("CACHE_MAX_AGE", tt.value)
+			if got := newCacheMaxAgeFromEnv(); got != tt.expected {
+				t.Errorf("newCacheMaxAgeFromEnv() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
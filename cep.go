@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// NewCEPHandler cria o handler de GET /cep/{cep}, que retorna apenas o
+// endereço completo do CEP, sem consultar a temperatura
+func NewCEPHandler(cepResolver CEPResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			setAllow(w, http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+			return
+		}
+
+		cep := cepFromPath(r, "/cep/")
+		if cep == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "cep parameter is required"})
+			return
+		}
+
+		// Valida o formato antes de consultar o resolver
+		if detail := cepValidationDetail(cep); detail != "" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid zipcode", Detail: detail})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		cepData, cepErr := cepResolver.ResolveCEP(ctx, cep)
+		if cepErr != nil {
+			w.WriteHeader(cepErr.Code)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: cepErr.Message, Detail: cepErr.Detail})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(cepData)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCEPHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{"CEP válido", "GET", "/cep/01310-100", http.StatusOK, ""},
+		{"CEP com formato inválido", "GET", "/cep/123", http.StatusUnprocessableEntity, "invalid zipcode"},
+		{"CEP não encontrado", "GET", "/cep/20040002", http.StatusNotFound, "can not find zipcode"},
+		{"CEP não fornecido", "GET", "/cep/", http.StatusBadRequest, "cep parameter is required"},
+		{"Método não permitido", "POST", "/cep/01310100", http.StatusMethodNotAllowed, "method not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCEPHandler(newFakeCEPResolver())
+
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				var resp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+				}
+				if resp.Message != tt.expectedMsg {
+					t.Errorf("message = %q, want %q", resp.Message, tt.expectedMsg)
+				}
+				return
+			}
+
+			var data CEPData
+			if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if data.CEP != "01310-100" || data.Logradouro != "Avenida Paulista" || data.Localidade != "São Paulo" || data.UF != "SP" {
+				t.Errorf("endereço incorreto: %+v", data)
+			}
+		})
+	}
+}
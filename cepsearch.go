@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// cepSearchMinLength é o tamanho mínimo da cidade e do logradouro exigido
+// pela busca reversa do ViaCEP
+const cepSearchMinLength = 3
+
+// searchAddress faz a busca reversa no ViaCEP, retornando os CEPs do
+// logradouro informado na cidade e UF
+func searchAddress(ctx context.Context, uf, city, street string) ([]CEPData, *CustomError) {
+	searchURL := fmt.Sprintf("%s/%s/%s/%s/json/", config.ViaCEPBaseURL,
+		url.PathEscape(strings.ToUpper(uf)), url.PathEscape(city), url.PathEscape(street))
+
+	ctx, cancel := withUpstreamTimeout(ctx, config.timeoutFor(providerViaCEP, config.ViaCEPTimeout))
+	defer cancel()
+
+	results := []CEPData{}
+	if err := newUpstreamClient(ctx).GetJSON(ctx, searchURL, &results); err != nil {
+		return nil, upstreamError(ctx, providerViaCEP, err, "uf", uf, "city", city, "street", street)
+	}
+	return results, nil
+}
+
+// cepSearchHandler lida com as requisições GET para /cepsearch?uf=&city=&street=
+func cepSearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		setAllow(w, http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+		return
+	}
+
+	query := r.URL.Query()
+	uf := strings.TrimSpace(query.Get("uf"))
+	city := strings.TrimSpace(query.Get("city"))
+	street := strings.TrimSpace(query.Get("street"))
+
+	if uf == "" || city == "" || street == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "uf, city and street parameters are required"})
+		return
+	}
+	if !isValidUF(uf) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid uf parameter", Detail: "uf must be a Brazilian state code, such as SP"})
+		return
+	}
+	if utf8.RuneCountInString(city) < cepSearchMinLength || utf8.RuneCountInString(street) < cepSearchMinLength {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Message: "search terms too short",
+			Detail:  fmt.Sprintf("city and street must have at least %d characters", cepSearchMinLength),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+	defer cancel()
+
+	results, searchErr := searchAddress(ctx, uf, city, street)
+	if searchErr != nil {
+		w.WriteHeader(searchErr.Code)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: searchErr.Message})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
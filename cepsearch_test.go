@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCEPSearchHandler(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/ws/SP/São Paulo/Paulista/json/":
+			w.Write([]byte(`[
+				{"cep": "01310-100", "logradouro": "Avenida Paulista", "bairro": "Bela Vista", "localidade": "São Paulo", "uf": "SP"},
+				{"cep": "01311-000", "logradouro": "Avenida Paulista", "bairro": "Bela Vista", "localidade": "São Paulo", "uf": "SP"}
+			]`))
+		case "/ws/RJ/Rio de Janeiro/Inexistente/json/":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	useRetryBaseDelay(t, 0)
+	useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws"})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCEPs   []string
+		expectedMsg    string
+	}{
+		{"Logradouro encontrado", "?uf=sp&city=S%C3%A3o+Paulo&street=Paulista", http.StatusOK, []string{"01310-100", "01311-000"}, ""},
+		{"Nenhum resultado", "?uf=RJ&city=Rio+de+Janeiro&street=Inexistente", http.StatusOK, []string{}, ""},
+		{"Sem UF", "?city=S%C3%A3o+Paulo&street=Paulista", http.StatusBadRequest, nil, "uf, city and street parameters are required"},
+		{"Sem logradouro", "?uf=SP&city=S%C3%A3o+Paulo", http.StatusBadRequest, nil, "uf, city and street parameters are required"},
+		{"UF inválida", "?uf=XX&city=S%C3%A3o+Paulo&street=Paulista", http.StatusBadRequest, nil, "invalid uf parameter"},
+		{"Logradouro curto", "?uf=SP&city=S%C3%A3o+Paulo&street=Pa", http.StatusBadRequest, nil, "search terms too short"},
+		{"Upstream indisponível", "?uf=MG&city=Belo+Horizonte&street=Afonso+Pena", http.StatusBadGateway, nil, "bad gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			cepSearchHandler(rr, httptest.NewRequest("GET", "/cepsearch"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s, requisições: %v)", rr.Code, tt.expectedStatus, rr.Body.String(), requested)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				var errorResp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+					t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+				}
+				if errorResp.Message != tt.expectedMsg {
+					t.Errorf("message = %q, want %q", errorResp.Message, tt.expectedMsg)
+				}
+				return
+			}
+
+			var results []CEPData
+			if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+				t.Fatalf("Resposta não é um array JSON: %v (%s)", err, rr.Body.String())
+			}
+			if results == nil {
+				t.Fatalf("resposta deveria ser um array, got %s", rr.Body.String())
+			}
+			if len(results) != len(tt.expectedCEPs) {
+				t.Fatalf("resultados = %d, want %d", len(results), len(tt.expectedCEPs))
+			}
+			for i, cep := range tt.expectedCEPs {
+				if results[i].CEP != cep {
+					t.Errorf("resultado %d = %s, want %s", i, results[i].CEP, cep)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "net/http"
+
+// Middleware envolve um handler acrescentando um comportamento
+type Middleware func(http.Handler) http.Handler
+
+// Chain aplica os middlewares ao handler na ordem informada: o primeiro é o
+// mais externo, ou seja, o primeiro a receber a requisição
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// handlerFuncMiddleware adapta os middlewares no formato
+// func(http.HandlerFunc) http.HandlerFunc, como os métodos Wrap, a Middleware
+func handlerFuncMiddleware(wrap func(http.HandlerFunc) http.HandlerFunc) Middleware {
+	return func(next http.Handler) http.Handler {
+		return wrap(next.ServeHTTP)
+	}
+}
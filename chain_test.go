@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// appendHeader cria um middleware que acrescenta o valor ao header X-Order
+func appendHeader(value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Order", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Order", "handler")
+	}), appendHeader("primeiro"), appendHeader("segundo"))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	expected := []string{"primeiro", "segundo", "handler"}
+	if got := rr.Header().Values("X-Order"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("X-Order = %v, want %v", got, expected)
+	}
+}
+
+func TestChainWithoutMiddlewares(t *testing.T) {
+	handler := Chain(http.HandlerFunc(healthzHandler))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rr.Code)
+	}
+}
+
+func TestHandlerFuncMiddleware(t *testing.T) {
+	maintenance := &maintenanceMode{enabled: true, retryAfter: 120}
+	handler := Chain(http.HandlerFunc(healthzHandler), appendHeader("externo"), handlerFuncMiddleware(maintenance.Wrap))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rr.Code)
+	}
+	if got := rr.Header().Get("X-Order"); got != "externo" {
+		t.Errorf("X-Order = %q, want %q", got, "externo")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// isValidUF indica se o código informado é uma das 27 UFs brasileiras
+func isValidUF(uf string) bool {
+	_, ok := ufTimezones[strings.ToUpper(uf)]
+	return ok
+}
+
+// hasCompleteAddress indica se o CEP tem a cidade e uma UF válida, necessárias
+// para consultar a temperatura
+func hasCompleteAddress(cepData *CEPData) bool {
+	return strings.TrimSpace(cepData.Localidade) != "" && isValidUF(cepData.UF)
+}
+
+// NewCityWeatherHandler cria o handler de GET /weatherbycity?city=&uf=, que
+// consulta a temperatura diretamente pela cidade, sem passar pelo CEP
+func NewCityWeatherHandler(weatherResolver WeatherResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			setAllow(w, http.MethodGet)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+			return
+		}
+
+		city := strings.TrimSpace(r.URL.Query().Get("city"))
+		if city == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "city parameter is required"})
+			return
+		}
+
+		uf := strings.TrimSpace(r.URL.Query().Get("uf"))
+		if !isValidUF(uf) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid uf parameter", Detail: "uf must be a Brazilian state code, such as SP"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		weatherData, weatherErr := weatherResolver.ResolveWeather(ctx, city, strings.ToUpper(uf))
+		if weatherErr != nil {
+			w.WriteHeader(weatherErr.Code)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: weatherErr.Message})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		encodeJSON(w, weatherData)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCityWeatherHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{"Consulta válida", "GET", "?city=Sao+Paulo&uf=SP", http.StatusOK, ""},
+		{"UF minúscula", "GET", "?city=Recife&uf=pe", http.StatusOK, ""},
+		{"Cidade ausente", "GET", "?uf=SP", http.StatusBadRequest, "city parameter is required"},
+		{"Cidade em branco", "GET", "?city=+&uf=SP", http.StatusBadRequest, "city parameter is required"},
+		{"UF inválida", "GET", "?city=Sao+Paulo&uf=XX", http.StatusBadRequest, "invalid uf parameter"},
+		{"UF ausente", "GET", "?city=Sao+Paulo", http.StatusBadRequest, "invalid uf parameter"},
+		{"Método não permitido", "POST", "?city=Sao+Paulo&uf=SP", http.StatusMethodNotAllowed, "method not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weather := &fakeWeatherResolver{tempC: 25}
+			rr := httptest.NewRecorder()
+			NewCityWeatherHandler(weather)(rr, httptest.NewRequest(tt.method, "/weatherbycity"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				var resp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+				}
+				if resp.Message != tt.expectedMsg {
+					t.Errorf("message = %q, want %q", resp.Message, tt.expectedMsg)
+				}
+				if weather.calls != 0 {
+					t.Errorf("temperatura consultada %d vezes em uma requisição inválida", weather.calls)
+				}
+				return
+			}
+
+			var data WeatherData
+			if err := json.Unmarshal(rr.Body.Bytes(), &data); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if data.TempC != 25 || data.TempF != 77 || data.TempK != 298.2 {
+				t.Errorf("temperaturas incorretas: %+v", data)
+			}
+		})
+	}
+}
+
+func TestCityWeatherHandlerUpstreamError(t *testing.T) {
+	weather := &fakeWeatherResolver{err: &CustomError{Code: 502, Message: "bad gateway"}}
+	rr := httptest.NewRecorder()
+	NewCityWeatherHandler(weather)(rr, httptest.NewRequest("GET", "/weatherbycity?city=Sao+Paulo&uf=SP", nil))
+
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rr.Code)
+	}
+}
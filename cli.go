@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Códigos de saída do modo de linha de comando (--cep)
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitInvalidCEP = 2
+	exitNotFound   = 3
+)
+
+// exitCodeFor converte o status HTTP de um CustomError no código de saída do processo
+func exitCodeFor(err *CustomError) int {
+	switch err.Code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return exitInvalidCEP
+	case http.StatusNotFound:
+		return exitNotFound
+	default:
+		return exitFailure
+	}
+}
+
+// lookupCEP executa a consulta única do modo de linha de comando: resolve o
+// CEP e a temperatura, escreve a resposta JSON em stdout (ou o erro em stderr)
+// e retorna o código de saída do processo
+func lookupCEP(ctx context.Context, stdout, stderr io.Writer, cepResolver CEPResolver, weatherResolver WeatherResolver, cep string) int {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	fail := func(err *CustomError) int {
+		json.NewEncoder(stderr).Encode(ErrorResponse{Message: err.Message, Detail: err.Detail})
+		return exitCodeFor(err)
+	}
+
+	cepData, cepErr := cepResolver.ResolveCEP(ctx, cep)
+	if cepErr != nil {
+		return fail(cepErr)
+	}
+
+	weatherData, weatherErr := weatherResolver.ResolveWeather(ctx, cepData.Localidade, cepData.UF)
+	if weatherErr != nil {
+		return fail(weatherErr)
+	}
+
+	encodeJSON(stdout, WeatherResponse{
+		WeatherData: *weatherData,
+		Address:     buildAddress(addressModeMin, cepData),
+		Coordinates: locateCEP(ctx, cepData),
+	})
+	return exitOK
+}
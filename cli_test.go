@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestLookupCEP(t *testing.T) {
+	tests := []struct {
+		name            string
+		cep             string
+		cepErr          *CustomError
+		weatherErr      *CustomError
+		expectedCode    int
+		expectedMessage string
+	}{
+		{name: "CEP válido", cep: "01310-100", expectedCode: exitOK},
+		{name: "CEP inválido", cep: "123", cepErr: &CustomError{Code: 422, Message: "invalid zipcode"}, expectedCode: exitInvalidCEP, expectedMessage: "invalid zipcode"},
+		{name: "CEP não encontrado", cep: "99999999", expectedCode: exitNotFound, expectedMessage: "can not find zipcode"},
+		{
+			name:            "Falha no provedor de temperatura",
+			cep:             "01310100",
+			weatherErr:      &CustomError{Code: 500, Message: "weather data not available"},
+			expectedCode:    exitFailure,
+			expectedMessage: "weather data not available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			cepResolver := newFakeCEPResolver()
+			cepResolver.err = tt.cepErr
+			weatherResolver := &fakeWeatherResolver{tempC: 22, err: tt.weatherErr}
+
+			code := lookupCEP(context.Background(), &stdout, &stderr, cepResolver, weatherResolver, tt.cep)
+			if code != tt.expectedCode {
+				t.Fatalf("código de saída = %d, want %d (stderr: %s)", code, tt.expectedCode, stderr.String())
+			}
+
+			if tt.expectedCode != exitOK {
+				if stdout.Len() != 0 {
+					t.Errorf("stdout deveria estar vazio em caso de erro: %s", stdout.String())
+				}
+				var errorResp ErrorResponse
+				if err := json.Unmarshal(stderr.Bytes(), &errorResp); err != nil {
+					t.Fatalf("erro não é um JSON válido: %v", err)
+				}
+				if errorResp.Message != tt.expectedMessage {
+					t.Errorf("Mensagem de erro incorreta: got %q want %q", errorResp.Message, tt.expectedMessage)
+				}
+				return
+			}
+
+			var resp struct {
+				WeatherData
+				Address MinAddress `json:"address"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if resp.TempC != 22 || resp.TempF != 71.6 {
+				t.Errorf("temperaturas incorretas: %+v", resp.WeatherData)
+			}
+			if resp.Address.CEP != "01310-100" || resp.Address.Localidade != "São Paulo" {
+				t.Errorf("endereço incorreto: %+v", resp.Address)
+			}
+		})
+	}
+}
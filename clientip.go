@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// trustedProxies são os proxies (CIDRs ou IPs) cujo X-Forwarded-For é
+// considerado, lidos de TRUSTED_PROXIES. Vazio ignora o header.
+var trustedProxies = newTrustedProxiesFromEnv()
+
+// newTrustedProxiesFromEnv lê a lista de proxies confiáveis, separada por
+// vírgulas (ex.: "10.0.0.0/8,169.254.1.1"), descartando entradas inválidas
+func newTrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, ok := parseProxyPrefix(entry); !ok {
+			logger.Warn("entrada inválida em TRUSTED_PROXIES, ignorando", "value", entry)
+			continue
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies
+}
+
+// parseProxyPrefix interpreta um CIDR ou um IP isolado
+func parseProxyPrefix(entry string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return prefix.Masked(), true
+	}
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// isTrustedProxy indica se o IP pertence a algum dos proxies confiáveis
+func isTrustedProxy(ip netip.Addr, trustedProxies []string) bool {
+	for _, entry := range trustedProxies {
+		if prefix, ok := parseProxyPrefix(entry); ok && prefix.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP retorna o IP do cliente. Se o par direto (RemoteAddr) for um proxy
+// confiável, percorre o X-Forwarded-For da direita para a esquerda e usa a
+// primeira entrada que não é um proxy confiável; caso contrário usa o RemoteAddr.
+// Entradas inválidas interrompem a busca, pois não dá para confiar no que
+// vem antes delas.
+func clientIP(r *http.Request, trustedProxies []string) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !isTrustedProxy(addr, trustedProxies) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap().String()
+		if !isTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+	return client
+}
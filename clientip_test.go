@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		trusted    []string
+		expected   string
+	}{
+		{"Sem proxies confiáveis ignora o header", "203.0.113.7:1234", []string{"198.51.100.1"}, nil, "203.0.113.7"},
+		{"Par direto não confiável", "203.0.113.7:1234", []string{"198.51.100.1"}, []string{"10.0.0.0/8"}, "203.0.113.7"},
+		{"Proxy confiável sem header", "10.0.0.5:1234", nil, []string{"10.0.0.0/8"}, "10.0.0.5"},
+		{"Proxy confiável com um salto", "10.0.0.5:1234", []string{"198.51.100.1"}, []string{"10.0.0.0/8"}, "198.51.100.1"},
+		{"Entrada forjada à esquerda é ignorada", "10.0.0.5:1234", []string{"1.2.3.4, 198.51.100.1"}, []string{"10.0.0.0/8"}, "198.51.100.1"},
+		{"Cadeia de proxies confiáveis", "10.0.0.5:1234", []string{"198.51.100.1, 10.0.0.9, 10.0.0.8"}, []string{"10.0.0.0/8"}, "198.51.100.1"},
+		{"Vários headers", "10.0.0.5:1234", []string{"198.51.100.1", "10.0.0.9"}, []string{"10.0.0.0/8"}, "198.51.100.1"},
+		{"IP isolado como proxy", "169.254.1.1:80", []string{"198.51.100.1"}, []string{"169.254.1.1"}, "198.51.100.1"},
+		{"Todos confiáveis usa o mais à esquerda", "10.0.0.5:1234", []string{"10.0.0.7, 10.0.0.9"}, []string{"10.0.0.0/8"}, "10.0.0.7"},
+		{"Entrada inválida interrompe a busca", "10.0.0.5:1234", []string{"198.51.100.1, lixo, 10.0.0.9"}, []string{"10.0.0.0/8"}, "10.0.0.9"},
+		{"IPv6", "[2001:db8::1]:443", []string{"2001:db8:ffff::2"}, []string{"2001:db8::/64"}, "2001:db8:ffff::2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := clientIP(req, tt.trusted); got != tt.expected {
+				t.Errorf("clientIP = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewTrustedProxiesFromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8, 169.254.1.1 ,não-é-ip, ,2001:db8::/32")
+
+	expected := []string{"10.0.0.0/8", "169.254.1.1", "2001:db8::/32"}
+	if got := newTrustedProxiesFromEnv(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("proxies = %v, want %v", got, expected)
+	}
+}
+
+func TestRateLimiterPerIPBehindTrustedProxy(t *testing.T) {
+	previous := trustedProxies
+	trustedProxies = []string{"10.0.0.0/8"}
+	t.Cleanup(func() { trustedProxies = previous })
+
+	limiter := newRateLimiter(1, 1, true)
+	handler := limiter.Wrap(func(w http.ResponseWriter, r *http.Request) {})
+
+	// Dois clientes atrás do mesmo proxy têm limites separados
+	for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+		req.RemoteAddr = "10.0.0.5:1234"
+		req.Header.Set("X-Forwarded-For", client)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("cliente %s: status = %d, want 200", client, rr.Code)
+		}
+	}
+}
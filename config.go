@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLs padrão dos upstreams
+const (
+	defaultViaCEPBaseURL    = "https://viacep.com.br/ws"
+	defaultWeatherBaseURL   = "https://wttr.in"
+	defaultGeocoderBaseURL  = "https://nominatim.openstreetmap.org/search"
+	defaultOpenMeteoBaseURL = "https://api.open-meteo.com"
+	defaultBrasilAPIBaseURL = "https://brasilapi.com.br/api/cep/v2"
+)
+
+// defaultUserAgent identifica a aplicação nas chamadas aos upstreams; o
+// wttr.in e o Nominatim pedem um User-Agent descritivo
+const defaultUserAgent = "weatherbycep/1.0 (+https://github.com/lucasfeitozas/golang-wheaterbycep)"
+
+// Prazos padrão de cada chamada aos upstreams. O geocodificador tem o menor
+// prazo porque as coordenadas são opcionais e não devem atrasar a resposta.
+const (
+	defaultViaCEPTimeout   = 5 * time.Second
+	defaultWeatherTimeout  = 8 * time.Second
+	defaultGeocoderTimeout = 3 * time.Second
+)
+
+// Padrões do pool de conexões do httpClient, dimensionados para manter
+// conexões abertas com os poucos upstreams sob carga
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 20
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// Config reúne os endereços dos upstreams, permitindo apontar para servidores
+// de teste ou espelhos, e o prazo de cada chamada a eles
+type Config struct {
+	ViaCEPBaseURL   string
+	WeatherBaseURL  string
+	GeocoderBaseURL string
+	// OpenMeteoBaseURL é o provedor de temperatura usado quando o wttr.in falha
+	OpenMeteoBaseURL string
+	// BrasilAPIBaseURL é o provedor de CEP usado quando o ViaCEP falha
+	BrasilAPIBaseURL string
+
+	// Prazos por chamada, independentes do timeout geral do httpClient. O
+	// prazo de CEP vale para o ViaCEP e para a BrasilAPI, e o de temperatura
+	// para o wttr.in e para o Open-Meteo; zero desativa o prazo específico do provedor.
+	ViaCEPTimeout   time.Duration
+	WeatherTimeout  time.Duration
+	GeocoderTimeout time.Duration
+
+	// Prazos e tentativas por provedor definidos pela política de resolução;
+	// um provedor ausente usa os prazos acima e upstreamMaxAttempts
+	ProviderTimeouts    map[string]time.Duration
+	ProviderMaxAttempts map[string]int
+
+	// UserAgent é enviado em todas as chamadas aos upstreams; vazio mantém o padrão do Go
+	UserAgent string
+
+	// AllowInsecureFallback permite repetir a consulta ao ViaCEP via HTTP
+	// quando a chamada HTTPS falha; desligado, a falha é devolvida ao cliente
+	AllowInsecureFallback bool
+
+	// Pool de conexões do httpClient. MaxConnsPerHost zero não limita as
+	// conexões por host.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+}
+
+// config é a configuração carregada do ambiente na inicialização
+var config = loadConfigFromEnv()
+
+// loadConfigFromEnv lê VIACEP_BASE_URL, WEATHER_BASE_URL, GEOCODER_BASE_URL,
+// OPEN_METEO_BASE_URL e BRASILAPI_BASE_URL, usando os serviços públicos como padrão, os prazos
+// VIACEP_TIMEOUT, WEATHER_TIMEOUT e GEOCODER_TIMEOUT, o UPSTREAM_USER_AGENT, o ALLOW_INSECURE_FALLBACK e o pool de conexões
+// (HTTP_MAX_IDLE_CONNS, HTTP_MAX_IDLE_CONNS_PER_HOST, HTTP_MAX_CONNS_PER_HOST e
+// HTTP_IDLE_CONN_TIMEOUT)
+func loadConfigFromEnv() Config {
+	return Config{
+		ViaCEPBaseURL:    envURL("VIACEP_BASE_URL", defaultViaCEPBaseURL),
+		WeatherBaseURL:   envURL("WEATHER_BASE_URL", defaultWeatherBaseURL),
+		GeocoderBaseURL:  envURL("GEOCODER_BASE_URL", defaultGeocoderBaseURL),
+		OpenMeteoBaseURL: envURL("OPEN_METEO_BASE_URL", defaultOpenMeteoBaseURL),
+		BrasilAPIBaseURL: envURL("BRASILAPI_BASE_URL", defaultBrasilAPIBaseURL),
+		ViaCEPTimeout:    envDuration("VIACEP_TIMEOUT", defaultViaCEPTimeout),
+		WeatherTimeout:   envDuration("WEATHER_TIMEOUT", defaultWeatherTimeout),
+		GeocoderTimeout:  envDuration("GEOCODER_TIMEOUT", defaultGeocoderTimeout),
+		UserAgent:        envString("UPSTREAM_USER_AGENT", defaultUserAgent),
+
+		AllowInsecureFallback: envBool("ALLOW_INSECURE_FALLBACK"),
+
+		MaxIdleConns:        envInt("HTTP_MAX_IDLE_CONNS", defaultMaxIdleConns),
+		MaxIdleConnsPerHost: envInt("HTTP_MAX_IDLE_CONNS_PER_HOST", defaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:     envInt("HTTP_MAX_CONNS_PER_HOST", 0),
+		IdleConnTimeout:     envDuration("HTTP_IDLE_CONN_TIMEOUT", defaultIdleConnTimeout),
+	}
+}
+
+// timeoutFor retorna o prazo do provedor definido pela política ou, sem ele, fallback
+func (c Config) timeoutFor(provider string, fallback time.Duration) time.Duration {
+	if timeout, ok := c.ProviderTimeouts[provider]; ok {
+		return timeout
+	}
+	return fallback
+}
+
+// maxAttemptsFor retorna as tentativas por chamada ao provedor definidas
+// pela política ou, sem elas, upstreamMaxAttempts
+func (c Config) maxAttemptsFor(provider string) int {
+	if attempts, ok := c.ProviderMaxAttempts[provider]; ok {
+		return attempts
+	}
+	return upstreamMaxAttempts
+}
+
+// envURL lê uma URL base do ambiente, sem a barra final, usando o valor padrão quando ausente
+func envURL(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return strings.TrimSuffix(value, "/")
+	}
+	return fallback
+}
+
+// envString lê um texto do ambiente, usando o valor padrão quando ausente
+func envString(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// setUserAgent identifica a aplicação na requisição a um upstream
+func setUserAgent(req *http.Request) {
+	if config.UserAgent != "" {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
+}
+
+// envValue lê e converte uma variável de ambiente com parse. Ausente, usa o
+// valor padrão; inválida ou recusada por valid, avisa no log e também usa o
+// padrão, para que uma configuração errada nunca derrube o servidor.
+func envValue[T any](name string, fallback T, parse func(string) (T, error), valid func(T) bool) T {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := parse(value)
+	if err != nil || !valid(parsed) {
+		logger.Warn(name+" inválido, usando o padrão", "value", value, "default", fmt.Sprint(fallback))
+		return fallback
+	}
+	return parsed
+}
+
+// positive aceita apenas valores maiores que zero
+func positive[T int | int64 | float64 | time.Duration](value T) bool {
+	return value > 0
+}
+
+// nonNegative aceita zero e valores positivos
+func nonNegative[T int | int64 | float64 | time.Duration](value T) bool {
+	return value >= 0
+}
+
+// envDuration lê uma duração positiva do ambiente (ex.: "3s"), usando o valor
+// padrão quando ausente ou inválida
+func envDuration(name string, fallback time.Duration) time.Duration {
+	return envValue(name, fallback, time.ParseDuration, positive[time.Duration])
+}
+
+// envDurationWhere lê uma duração do ambiente aceita por valid, como
+// nonNegative nos casos em que zero desativa o recurso
+func envDurationWhere(name string, fallback time.Duration, valid func(time.Duration) bool) time.Duration {
+	return envValue(name, fallback, time.ParseDuration, valid)
+}
+
+// envInt lê um inteiro não negativo do ambiente, usando o valor padrão
+// quando ausente ou inválido
+func envInt(name string, fallback int) int {
+	return envIntWhere(name, fallback, nonNegative[int])
+}
+
+// envIntWhere lê um inteiro do ambiente aceito por valid
+func envIntWhere(name string, fallback int, valid func(int) bool) int {
+	return envValue(name, fallback, strconv.Atoi, valid)
+}
+
+// envInt64 lê um inteiro de 64 bits do ambiente aceito por valid
+func envInt64(name string, fallback int64, valid func(int64) bool) int64 {
+	return envValue(name, fallback, func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	}, valid)
+}
+
+// envFloat lê um número real finito do ambiente aceito por valid
+func envFloat(name string, fallback float64, valid func(float64) bool) float64 {
+	return envValue(name, fallback, func(value string) (float64, error) {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err == nil && (math.IsNaN(parsed) || math.IsInf(parsed, 0)) {
+			return 0, strconv.ErrRange
+		}
+		return parsed, err
+	}, valid)
+}
+
+// hostOf retorna o host de uma URL base, ou vazio se ela for inválida
+func hostOf(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useConfig substitui a configuração dos upstreams durante o teste
+func useConfig(t *testing.T, cfg Config) {
+	t.Helper()
+	previous := config
+	config = cfg
+	t.Cleanup(func() { config = previous })
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected Config
+	}{
+		{
+			name: "Valores padrão",
+			env:  map[string]string{},
+			expected: Config{
+				ViaCEPBaseURL:    "https://viacep.com.br/ws",
+				WeatherBaseURL:   "https://wttr.in",
+				GeocoderBaseURL:  "https://nominatim.openstreetmap.org/search",
+				OpenMeteoBaseURL: "https://api.open-meteo.com",
+				BrasilAPIBaseURL: "https://brasilapi.com.br/api/cep/v2",
+				ViaCEPTimeout:    5 * time.Second,
+				WeatherTimeout:   8 * time.Second,
+				GeocoderTimeout:  3 * time.Second,
+				UserAgent:        defaultUserAgent,
+
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 20,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		{
+			name: "Espelhos configurados",
+			env: map[string]string{
+				"VIACEP_BASE_URL":     "http://viacep.interno/ws/",
+				"WEATHER_BASE_URL":    " http://wttr.interno ",
+				"GEOCODER_BASE_URL":   "http://geo.interno/search",
+				"OPEN_METEO_BASE_URL": "http://meteo.interno",
+				"BRASILAPI_BASE_URL":  "http://brasilapi.interno/api/cep/v2",
+				"VIACEP_TIMEOUT":      "2s",
+				"WEATHER_TIMEOUT":     "1500ms",
+				"GEOCODER_TIMEOUT":    "750ms",
+				"UPSTREAM_USER_AGENT": "meu-servico/2.0",
+
+				"ALLOW_INSECURE_FALLBACK": "true",
+
+				"HTTP_MAX_IDLE_CONNS":          "200",
+				"HTTP_MAX_IDLE_CONNS_PER_HOST": "50",
+				"HTTP_MAX_CONNS_PER_HOST":      "64",
+				"HTTP_IDLE_CONN_TIMEOUT":       "2m",
+			},
+			expected: Config{
+				ViaCEPBaseURL:    "http://viacep.interno/ws",
+				WeatherBaseURL:   "http://wttr.interno",
+				GeocoderBaseURL:  "http://geo.interno/search",
+				OpenMeteoBaseURL: "http://meteo.interno",
+				BrasilAPIBaseURL: "http://brasilapi.interno/api/cep/v2",
+				ViaCEPTimeout:    2 * time.Second,
+				WeatherTimeout:   1500 * time.Millisecond,
+				GeocoderTimeout:  750 * time.Millisecond,
+				UserAgent:        "meu-servico/2.0",
+
+				AllowInsecureFallback: true,
+
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 50,
+				MaxConnsPerHost:     64,
+				IdleConnTimeout:     2 * time.Minute,
+			},
+		},
+		{
+			name: "Prazos inválidos usam o padrão",
+			env: map[string]string{
+				"VIACEP_TIMEOUT":  "-1s",
+				"WEATHER_TIMEOUT": "rápido",
+
+				"HTTP_MAX_IDLE_CONNS":    "-5",
+				"HTTP_IDLE_CONN_TIMEOUT": "sempre",
+			},
+			expected: Config{
+				ViaCEPBaseURL:    "https://viacep.com.br/ws",
+				WeatherBaseURL:   "https://wttr.in",
+				GeocoderBaseURL:  "https://nominatim.openstreetmap.org/search",
+				OpenMeteoBaseURL: "https://api.open-meteo.com",
+				BrasilAPIBaseURL: "https://brasilapi.com.br/api/cep/v2",
+				ViaCEPTimeout:    5 * time.Second,
+				WeatherTimeout:   8 * time.Second,
+				GeocoderTimeout:  3 * time.Second,
+				UserAgent:        defaultUserAgent,
+
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 20,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"VIACEP_BASE_URL", "WEATHER_BASE_URL", "GEOCODER_BASE_URL", "OPEN_METEO_BASE_URL", "BRASILAPI_BASE_URL", "VIACEP_TIMEOUT", "WEATHER_TIMEOUT", "GEOCODER_TIMEOUT", "UPSTREAM_USER_AGENT", "ALLOW_INSECURE_FALLBACK",
+				"HTTP_MAX_IDLE_CONNS", "HTTP_MAX_IDLE_CONNS_PER_HOST", "HTTP_MAX_CONNS_PER_HOST", "HTTP_IDLE_CONN_TIMEOUT"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if cfg := loadConfigFromEnv(); !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("loadConfigFromEnv() = %+v, want %+v", cfg, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnvHelpersWarnAndFallBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		read     func() interface{}
+		expected interface{}
+		warns    bool
+	}{
+		{"Inteiro válido", "7", func() interface{} { return envInt("TEST_ENV_VALUE", 3) }, 7, false},
+		{"Inteiro negativo", "-7", func() interface{} { return envInt("TEST_ENV_VALUE", 3) }, 3, true},
+		{"Zero recusado por positive", "0", func() interface{} { return envIntWhere("TEST_ENV_VALUE", 3, positive[int]) }, 3, true},
+		{"Int64 grande", "8589934592", func() interface{} { return envInt64("TEST_ENV_VALUE", 1, positive[int64]) }, int64(8589934592), false},
+		{"Int64 inválido", "muito", func() interface{} { return envInt64("TEST_ENV_VALUE", 1, positive[int64]) }, int64(1), true},
+		{"Real válido", "0.25", func() interface{} { return envFloat("TEST_ENV_VALUE", 0.5, nonNegative[float64]) }, 0.25, false},
+		{"Real infinito", "+Inf", func() interface{} { return envFloat("TEST_ENV_VALUE", 0.5, nonNegative[float64]) }, 0.5, true},
+		{"Real NaN", "NaN", func() interface{} { return envFloat("TEST_ENV_VALUE", 0.5, nonNegative[float64]) }, 0.5, true},
+		{"Duração zero aceita por nonNegative", "0s", func() interface{} {
+			return envDurationWhere("TEST_ENV_VALUE", time.Second, nonNegative[time.Duration])
+		}, time.Duration(0), false},
+		{"Duração zero recusada", "0s", func() interface{} { return envDuration("TEST_ENV_VALUE", time.Second) }, time.Second, true},
+		{"Ausente usa o padrão sem aviso", "", func() interface{} { return envInt("TEST_ENV_VALUE", 3) }, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := useLogger(t)
+			t.Setenv("TEST_ENV_VALUE", tt.value)
+
+			if got := tt.read(); got != tt.expected {
+				t.Errorf("valor = %v (%T), want %v (%T)", got, got, tt.expected, tt.expected)
+			}
+			lines := logLines(t, buf)
+			if warned := len(lines) == 1 && lines[0]["msg"] == "TEST_ENV_VALUE inválido, usando o padrão"; warned != tt.warns {
+				t.Errorf("aviso = %v, want %v (logs: %v)", warned, tt.warns, lines)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientAppliesPoolConfig(t *testing.T) {
+	client := newHTTPClient(Config{
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: 50,
+		MaxConnsPerHost:     64,
+		IdleConnTimeout:     2 * time.Minute,
+	})
+
+	limit, ok := client.Transport.(*limitTransport)
+	if !ok {
+		t.Fatalf("transporte = %T, want *limitTransport", client.Transport)
+	}
+	tracking, ok := limit.next.(*errorTrackingTransport)
+	if !ok {
+		t.Fatalf("transporte interno = %T, want *errorTrackingTransport", limit.next)
+	}
+	metrics, ok := tracking.next.(*metricsTransport)
+	if !ok {
+		t.Fatalf("transporte interno = %T, want *metricsTransport", tracking.next)
+	}
+	transport, ok := metrics.next.(*http.Transport)
+	if !ok {
+		t.Fatalf("transporte base = %T, want *http.Transport", metrics.next)
+	}
+
+	if transport.MaxIdleConns != 200 || transport.MaxIdleConnsPerHost != 50 || transport.MaxConnsPerHost != 64 {
+		t.Errorf("pool = (%d, %d, %d), want (200, 50, 64)",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 2*time.Minute {
+		t.Errorf("IdleConnTimeout = %v, want 2m", transport.IdleConnTimeout)
+	}
+}
+
+func TestUpstreamsUseConfiguredBaseURLs(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/ws/01310100/json/":
+			w.Write([]byte(`{"cep": "01310-100", "localidade": "São Paulo", "uf": "SP"}`))
+		case "/weather/São+Paulo,SP,Brazil":
+			w.Write([]byte(`{"current_condition": [{"temp_C": "21", "humidity": "60"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws", WeatherBaseURL: server.URL + "/weather"})
+
+	cepData, cepErr := searchCEP(context.Background(), "01310-100")
+	if cepErr != nil {
+		t.Fatalf("searchCEP retornou erro: %v (requisições: %v)", cepErr, paths)
+	}
+	if cepData.Localidade != "São Paulo" {
+		t.Errorf("localidade = %q, want São Paulo", cepData.Localidade)
+	}
+
+	weather, weatherErr := getWeatherData(context.Background(), cepData.Localidade, cepData.UF)
+	if weatherErr != nil {
+		t.Fatalf("getWeatherData retornou erro: %v (requisições: %v)", weatherErr, paths)
+	}
+	if weather.TempC != 21 {
+		t.Errorf("temp_C = %v, want 21", weather.TempC)
+	}
+}
+
+func TestUpstreamTimeouts(t *testing.T) {
+	// O servidor demora mais que o prazo configurado para cada provedor
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	useConfig(t, Config{
+		ViaCEPBaseURL:  server.URL + "/ws",
+		WeatherBaseURL: server.URL + "/weather",
+		ViaCEPTimeout:  50 * time.Millisecond,
+		WeatherTimeout: 50 * time.Millisecond,
+	})
+
+	tests := []struct {
+		name   string
+		lookup func(ctx context.Context) *CustomError
+	}{
+		{"ViaCEP", func(ctx context.Context) *CustomError {
+			_, err := searchCEP(ctx, "01310100")
+			return err
+		}},
+		{"wttr.in", func(ctx context.Context) *CustomError {
+			_, err := getWeatherData(ctx, "São Paulo", "SP")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.lookup(context.Background())
+			elapsed := time.Since(start)
+
+			if err == nil {
+				t.Fatal("a consulta deveria falhar por estouro de prazo")
+			}
+			if err.Code != 504 || err.Message != "upstream timeout" {
+				t.Errorf("erro = %d %q, want 504 %q", err.Code, err.Message, "upstream timeout")
+			}
+			if elapsed > time.Second {
+				t.Errorf("a consulta levou %v, deveria respeitar o prazo do provedor", elapsed)
+			}
+		})
+	}
+}
+
+func TestUpstreamsSendUserAgent(t *testing.T) {
+	agents := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents[r.URL.Path] = r.UserAgent()
+		switch r.URL.Path {
+		case "/ws/01310100/json/":
+			w.Write([]byte(`{"cep": "01310-100", "localidade": "São Paulo", "uf": "SP"}`))
+		default:
+			w.Write([]byte(`{"current_condition": [{"temp_C": "21"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	useConfig(t, Config{
+		ViaCEPBaseURL:  server.URL + "/ws",
+		WeatherBaseURL: server.URL + "/weather",
+		UserAgent:      defaultUserAgent,
+	})
+
+	if _, err := searchCEP(context.Background(), "01310100"); err != nil {
+		t.Fatalf("searchCEP retornou erro: %v", err)
+	}
+	if _, err := getWeatherData(context.Background(), "São Paulo", "SP"); err != nil {
+		t.Fatalf("getWeatherData retornou erro: %v", err)
+	}
+
+	for _, path := range []string{"/ws/01310100/json/", "/weather/São+Paulo,SP,Brazil"} {
+		if agent := agents[path]; agent != defaultUserAgent {
+			t.Errorf("User-Agent em %s = %q, want %q", path, agent, defaultUserAgent)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"os"
+)
+
+// defaultCORSAllowOrigin libera o acesso a partir de qualquer origem
+const defaultCORSAllowOrigin = "*"
+
+// corsMethods são os métodos anunciados aos navegadores; o lote e o /rpc usam POST
+const corsMethods = "GET, POST, OPTIONS"
+
+// corsPolicy adiciona os cabeçalhos de CORS para que front-ends no navegador
+// consigam chamar a API diretamente
+type corsPolicy struct {
+	allowOrigin string
+}
+
+// newCORSPolicyFromEnv lê CORS_ALLOW_ORIGIN
+func newCORSPolicyFromEnv() *corsPolicy {
+	policy := &corsPolicy{allowOrigin: defaultCORSAllowOrigin}
+	if value := os.Getenv("CORS_ALLOW_ORIGIN"); value != "" {
+		policy.allowOrigin = value
+	}
+	return policy
+}
+
+// Wrap aplica a política de CORS ao handler informado, respondendo as
+// requisições de preflight com 204 sem repassá-las adiante
+func (c *corsPolicy) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", c.allowOrigin)
+		w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+		if c.allowOrigin != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-ID")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// setAllow anuncia no header Allow o método aceito pelo endpoint, usado nas
+// respostas 405. OPTIONS é sempre aceito, pois o preflight é respondido pela corsPolicy.
+func setAllow(w http.ResponseWriter, method string) {
+	w.Header().Set("Allow", method+", "+http.MethodOptions)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	called := false
+	next := func(w http.ResponseWriter, r *http.Request) { called = true }
+
+	req := httptest.NewRequest("OPTIONS", "/weatherbycep/01310100", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	(&corsPolicy{allowOrigin: defaultCORSAllowOrigin}).Wrap(next)(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if called {
+		t.Error("preflight não deveria chegar ao handler")
+	}
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type, X-Request-ID",
+	}
+	for header, want := range expected {
+		if got := rr.Header().Get(header); got != want {
+			t.Errorf("%s incorreto: got %q want %q", header, got, want)
+		}
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	t.Setenv("CORS_ALLOW_ORIGIN", "https://app.example.com")
+	policy := newCORSPolicyFromEnv()
+
+	rr := httptest.NewRecorder()
+	policy.Wrap(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20}))(rr,
+		httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin incorreto: got %q", got)
+	}
+	if got := rr.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Vary incorreto: got %q want %q", got, "Origin")
+	}
+}
+
+func TestMethodNotAllowedSetsAllow(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		handler  http.HandlerFunc
+		expected string
+	}{
+		{"weatherbycep", "POST", "/weatherbycep/01310100", NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20}), "GET, OPTIONS"},
+		{"cep", "DELETE", "/cep/01310100", NewCEPHandler(newFakeCEPResolver()), "GET, OPTIONS"},
+		{"batch", "GET", "/weatherbycep/batch", NewBatchWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20}), "POST, OPTIONS"},
+		{"rpc", "GET", "/rpc", NewRPCHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23}), "POST, OPTIONS"},
+		{"healthz", "POST", "/healthz", healthzHandler, "GET, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want 405", rr.Code)
+			}
+			if allow := rr.Header().Get("Allow"); allow != tt.expected {
+				t.Errorf("Allow = %q, want %q", allow, tt.expected)
+			}
+		})
+	}
+}
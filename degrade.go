@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// defaultDegrade habilita a degradação para todas as requisições que não
+// informam ?degrade=, a partir de DEGRADE_ON_WEATHER_ERROR
+var defaultDegrade = envBool("DEGRADE_ON_WEATHER_ERROR")
+
+// DegradedResponse é a resposta parcial enviada quando o CEP foi resolvido,
+// mas a temperatura não pôde ser obtida
+type DegradedResponse struct {
+	Address      *CEPData      `json:"address"`
+	WeatherError ErrorResponse `json:"weather_error"`
+}
+
+// parseDegrade lê ?degrade=, usando o padrão configurado quando ausente
+func parseDegrade(r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("degrade")
+	if value == "" {
+		return defaultDegrade, true
+	}
+	degrade, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return degrade, true
+}
+
+// writeDegraded responde 206 com o endereço resolvido e o erro da consulta de temperatura
+func writeDegraded(w http.ResponseWriter, cepData *CEPData, weatherErr *CustomError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPartialContent)
+	json.NewEncoder(w).Encode(DegradedResponse{
+		Address:      cepData,
+		WeatherError: ErrorResponse{Message: weatherErr.Message, Detail: weatherErr.Detail},
+	})
+}
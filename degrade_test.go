@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// useDefaultDegrade substitui o padrão de DEGRADE_ON_WEATHER_ERROR durante o teste
+func useDefaultDegrade(t *testing.T, degrade bool) {
+	t.Helper()
+	previous := defaultDegrade
+	defaultDegrade = degrade
+	t.Cleanup(func() { defaultDegrade = previous })
+}
+
+func TestWeatherByCEPHandlerDegrade(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultDegrade bool
+		path           string
+		accept         string
+		weatherErr     *CustomError
+		expectedStatus int
+	}{
+		{"Degradação pela query", false, "/weatherbycep/01310100?degrade=true", "", &CustomError{Code: 500, Message: "weather data not available"}, http.StatusPartialContent},
+		{"Degradação pelo padrão do ambiente", true, "/weatherbycep/01310100", "", &CustomError{Code: 504, Message: "upstream timeout"}, http.StatusPartialContent},
+		{"Query desativa o padrão", true, "/weatherbycep/01310100?degrade=false", "", &CustomError{Code: 502, Message: "bad gateway"}, http.StatusBadGateway},
+		{"Sem degradação", false, "/weatherbycep/01310100", "", &CustomError{Code: 502, Message: "bad gateway"}, http.StatusBadGateway},
+		{"Formato XML não degrada", false, "/weatherbycep/01310100?degrade=true", "application/xml", &CustomError{Code: 502, Message: "bad gateway"}, http.StatusBadGateway},
+		{"Temperatura disponível", false, "/weatherbycep/01310100?degrade=true", "", nil, http.StatusOK},
+		{"Parâmetro inválido", false, "/weatherbycep/01310100?degrade=talvez", "", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDefaultDegrade(t, tt.defaultDegrade)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23, err: tt.weatherErr})(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusPartialContent {
+				return
+			}
+
+			var body DegradedResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if body.Address == nil || body.Address.Localidade != "São Paulo" || body.Address.Logradouro != "Avenida Paulista" {
+				t.Errorf("address = %+v, want o endereço completo do CEP", body.Address)
+			}
+			if body.WeatherError.Message != tt.weatherErr.Message {
+				t.Errorf("weather_error = %q, want %q", body.WeatherError.Message, tt.weatherErr.Message)
+			}
+			if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+		})
+	}
+}
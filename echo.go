@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// debugMode habilita recursos de depuração, como o ?echo=true, via DEBUG
+var debugMode = envBool("DEBUG")
+
+// RequestEcho descreve como o servidor interpretou os parâmetros da requisição,
+// já normalizados, para ajudar na depuração de integrações
+type RequestEcho struct {
+	CEP      string `json:"cep"`
+	Address  string `json:"address"`
+	Units    string `json:"units"`
+	Verbose  bool   `json:"verbose"`
+	Lang     string `json:"lang"`
+	Forecast int    `json:"forecast"`
+	Format   string `json:"format"`
+}
+
+// setCacheKeyHeader informa em X-Cache-Key, apenas no modo de depuração, as
+// chaves normalizadas dos caches de CEP e de temperatura usadas na
+// requisição, como "cep=01310100; weather=sao paulo|sp|pt", já com o
+// namespace do contexto. Sem cidade, antes de o CEP ser resolvido, apenas a
+// chave do CEP é informada.
+func setCacheKeyHeader(ctx context.Context, w http.ResponseWriter, cep, city, state, lang string) {
+	if !debugMode {
+		return
+	}
+	value := "cep=" + namespacedKey(ctx, cepCacheKey(cep))
+	if city != "" {
+		value += "; weather=" + namespacedKey(ctx, weatherCacheKey(city, state, lang))
+	}
+	w.Header().Set("X-Cache-Key", value)
+}
+
+// parseEcho lê o parâmetro ?echo= da query string. Fora do modo de depuração
+// o parâmetro é ignorado.
+func parseEcho(r *http.Request) (bool, bool) {
+	if !debugMode {
+		return false, true
+	}
+	value := r.URL.Query().Get("echo")
+	if value == "" {
+		return false, true
+	}
+	echo, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return echo, true
+}
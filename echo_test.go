@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// useDebugMode altera o modo de depuração durante o teste
+func useDebugMode(t *testing.T, enabled bool) {
+	t.Helper()
+	previous := debugMode
+	debugMode = enabled
+	t.Cleanup(func() { debugMode = previous })
+}
+
+func TestWeatherByCEPHandlerEcho(t *testing.T) {
+	tests := []struct {
+		name     string
+		debug    bool
+		path     string
+		accept   string
+		expected *RequestEcho
+	}{
+		{
+			name:  "Parâmetros normalizados",
+			debug: true,
+			path:  "/weatherbycep/01310-100?echo=true&address=full&units=explicit&verbose=1",
+			expected: &RequestEcho{
+				CEP:     "01310100",
+				Address: addressModeFull,
+				Units:   unitsModeExplicit,
+				Verbose: true,
+				Format:  formatJSON,
+			},
+		},
+		{
+			name:  "Valores padrão",
+			debug: true,
+			path:  "/weatherbycep/01310100?echo=1",
+			expected: &RequestEcho{
+				CEP:     "01310100",
+				Address: addressModeMin,
+				Units:   unitsModeFlat,
+				Verbose: false,
+				Format:  formatJSON,
+			},
+		},
+		{
+			name:   "Idioma, previsão e formato",
+			debug:  true,
+			path:   "/weatherbycep/01310100?echo=true&lang=PT-BR&forecast=2",
+			accept: "application/xml;q=0.5, application/json",
+			expected: &RequestEcho{
+				CEP:      "01310100",
+				Address:  addressModeMin,
+				Units:    unitsModeFlat,
+				Lang:     "pt-br",
+				Forecast: 2,
+				Format:   formatJSON,
+			},
+		},
+		{
+			name:     "Sem echo",
+			debug:    true,
+			path:     "/weatherbycep/01310100",
+			expected: nil,
+		},
+		{
+			name:     "Fora do modo de depuração",
+			debug:    false,
+			path:     "/weatherbycep/01310100?echo=true",
+			expected: nil,
+		},
+	}
+
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDebugMode(t, tt.debug)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+			if rr.Code != 200 {
+				t.Fatalf("status = %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+			}
+
+			var resp struct {
+				Echo *RequestEcho `json:"echo"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if !reflect.DeepEqual(resp.Echo, tt.expected) {
+				t.Errorf("echo = %+v, want %+v", resp.Echo, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerInvalidEcho(t *testing.T) {
+	useDebugMode(t, true)
+
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100?echo=sim", nil))
+
+	if rr.Code != 400 {
+		t.Errorf("status = %d, want 400", rr.Code)
+	}
+}
+
+func TestWeatherByCEPHandlerCacheKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		debug    bool
+		path     string
+		expected string
+	}{
+		{"CEP com hífen", true, "/weatherbycep/01310-100", "cep=01310100; weather=sao paulo|sp|" + defaultWeatherLang},
+		{"CEP sem hífen", true, "/weatherbycep/01310100", "cep=01310100; weather=sao paulo|sp|" + defaultWeatherLang},
+		{"CEP na query string", true, "/weatherbycep?cep=01310-100", "cep=01310100; weather=sao paulo|sp|" + defaultWeatherLang},
+		{"Idioma na chave", true, "/weatherbycep/01310100?lang=en", "cep=01310100; weather=sao paulo|sp|en"},
+		{"CEP não encontrado", true, "/weatherbycep/99999-999", "cep=99999999"},
+		{"Fora do modo de depuração", false, "/weatherbycep/01310-100", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useDebugMode(t, tt.debug)
+
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if got := rr.Header().Get("X-Cache-Key"); got != tt.expected {
+				t.Errorf("X-Cache-Key = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// weatherETag calcula um ETag fraco a partir do corpo JSON da resposta e do
+// formato negociado, para que JSON, XML e texto da mesma URL não se confundam
+func weatherETag(format string, body interface{}) string {
+	h := fnv.New64a()
+	h.Write([]byte(format))
+	h.Write([]byte{0})
+	json.NewEncoder(h).Encode(body)
+	return fmt.Sprintf(`W/"%016x"`, h.Sum64())
+}
+
+// etagMatches indica se algum dos ETags de If-None-Match corresponde ao
+// ETag informado, usando a comparação fraca da RFC 9110
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWeatherByCEPHandlerConditionalGet(t *testing.T) {
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 22})
+
+	// Primeira requisição: 200 com ETag fraco
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rr.Code)
+	}
+	etag := rr.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("ETag = %q, want ETag fraco", etag)
+	}
+
+	tests := []struct {
+		name           string
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{"ETag igual", etag, http.StatusNotModified},
+		{"ETag forte equivalente", strings.TrimPrefix(etag, "W/"), http.StatusNotModified},
+		{"Lista com o ETag", `"outro", ` + etag, http.StatusNotModified},
+		{"Curinga", "*", http.StatusNotModified},
+		{"ETag diferente", `W/"0000000000000000"`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+			if rr.Header().Get("ETag") != etag {
+				t.Errorf("ETag = %q, want %q", rr.Header().Get("ETag"), etag)
+			}
+			if tt.expectedStatus == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 não deveria ter corpo: %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWeatherETagVariesWithRepresentation(t *testing.T) {
+	body := WeatherResponse{WeatherData: weatherFromCelsius(22)}
+	other := WeatherResponse{WeatherData: weatherFromCelsius(23)}
+
+	if weatherETag(formatJSON, body) != weatherETag(formatJSON, body) {
+		t.Error("ETag deveria ser estável para o mesmo corpo")
+	}
+	if weatherETag(formatJSON, body) == weatherETag(formatJSON, other) {
+		t.Error("ETag deveria mudar com a temperatura")
+	}
+	if weatherETag(formatJSON, body) == weatherETag(formatXML, body) {
+		t.Error("ETag deveria mudar com o formato")
+	}
+}
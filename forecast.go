@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+// ForecastDay representa a previsão de um dia; é o mesmo tipo da biblioteca
+// do pacote weather
+type ForecastDay = weather.ForecastDay
+
+// parseForecastDays lê o parâmetro ?forecast= da query string: a quantidade de
+// dias de previsão a incluir na resposta, zero (sem previsão) quando ausente
+func parseForecastDays(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("forecast")
+	if value == "" {
+		return 0, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days <= 0 {
+		return 0, false
+	}
+	return days, true
+}
+
+// limitForecast retorna os primeiros days dias da previsão, limitados aos
+// disponíveis no provedor
+func limitForecast(forecast []ForecastDay, days int) []ForecastDay {
+	if days > len(forecast) {
+		days = len(forecast)
+	}
+	return forecast[:days]
+}
+
+// noForecastHour indica que ?hour= não foi informado
+const noForecastHour = -1
+
+// parseForecastHour lê o parâmetro ?hour= da query string: a hora do dia
+// (0 a 23) cuja próxima ocorrência deve ser incluída na resposta, ou
+// noForecastHour quando ausente
+func parseForecastHour(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("hour")
+	if value == "" {
+		return noForecastHour, true
+	}
+	hour, err := strconv.Atoi(value)
+	if err != nil || hour < 0 || hour > 23 {
+		return noForecastHour, false
+	}
+	return hour, true
+}
+
+// hourForecast retorna a previsão para a próxima ocorrência da hora
+// informada no fuso da UF, a partir de now: o horário pedido com a
+// temperatura da leitura horária do wttr.in que o cobre, a última do mesmo
+// dia que não seja posterior a ele. Retorna nil sem leituras horárias para
+// esse dia, como quando a temperatura vem do Open-Meteo.
+func (d *WeatherDetails) hourForecast(uf string, hour int, now time.Time) *TempPoint {
+	loc, ok := timezoneForUF(uf)
+	if d == nil || !ok {
+		return nil
+	}
+
+	now = now.In(loc)
+	target := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, loc)
+	if target.Before(now) {
+		target = target.AddDate(0, 0, 1)
+	}
+
+	var covering *TempPoint
+	var coveringAt time.Time
+	for _, h := range d.raw.Hourly {
+		at, tempC, ok := parseHourlyReading(h, loc)
+		if !ok || at.After(target) || at.YearDay() != target.YearDay() || at.Year() != target.Year() {
+			continue
+		}
+		if covering == nil || at.After(coveringAt) {
+			covering, coveringAt = &TempPoint{Time: target.Format(time.RFC3339), TempC: tempC}, at
+		}
+	}
+	return covering
+}
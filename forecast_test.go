@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// forecastFixtureDays são os dias da fixture testdata/wttr/forecast.json
+var forecastFixtureDays = []ForecastDay{
+	{Date: "2025-01-15", MinTempC: 19, MaxTempC: 29},
+	{Date: "2025-01-16", MinTempC: 20, MaxTempC: 31},
+	{Date: "2025-01-17", MinTempC: 18, MaxTempC: 26},
+}
+
+func TestParseWttrResponseForecast(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "wttr", "forecast.json"))
+	if err != nil {
+		t.Fatalf("erro ao ler fixture: %v", err)
+	}
+
+	weatherData := parseWttr(t, body)
+	if !reflect.DeepEqual(weatherData.ForecastDays, forecastFixtureDays) {
+		t.Errorf("previsão = %+v, want %+v", weatherData.ForecastDays, forecastFixtureDays)
+	}
+}
+
+func TestWeatherByCEPHandlerForecast(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "wttr", "forecast.json"))
+	if err != nil {
+		t.Fatalf("erro ao ler fixture: %v", err)
+	}
+	wttr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer wttr.Close()
+	useConfig(t, Config{WeatherBaseURL: wttr.URL})
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedForecast []ForecastDay
+	}{
+		{"Sem previsão", "", http.StatusOK, nil},
+		{"Dois dias", "?forecast=2", http.StatusOK, forecastFixtureDays[:2]},
+		{"Limitado aos dias disponíveis", "?forecast=10", http.StatusOK, forecastFixtureDays},
+		{"Zero", "?forecast=0", http.StatusBadRequest, nil},
+		{"Negativo", "?forecast=-1", http.StatusBadRequest, nil},
+		{"Não numérico", "?forecast=tres", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(newFakeCEPResolver(), wttrWeatherResolver{})(rr, httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				var errResp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || errResp.Message != "invalid forecast parameter" {
+					t.Errorf("resposta = %s, want invalid forecast parameter", rr.Body.String())
+				}
+				return
+			}
+
+			var resp struct {
+				Forecast []ForecastDay `json:"forecast"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if len(resp.Forecast) != len(tt.expectedForecast) {
+				t.Fatalf("forecast com %d dias, want %d (corpo: %s)", len(resp.Forecast), len(tt.expectedForecast), rr.Body.String())
+			}
+			if len(tt.expectedForecast) > 0 && !reflect.DeepEqual(resp.Forecast, tt.expectedForecast) {
+				t.Errorf("forecast = %+v, want %+v", resp.Forecast, tt.expectedForecast)
+			}
+		})
+	}
+}
+
+func TestParseForecastHour(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected int
+		valid    bool
+	}{
+		{"", noForecastHour, true},
+		{"?hour=0", 0, true},
+		{"?hour=8", 8, true},
+		{"?hour=23", 23, true},
+		{"?hour=24", noForecastHour, false},
+		{"?hour=-1", noForecastHour, false},
+		{"?hour=8h", noForecastHour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil)
+			hour, ok := parseForecastHour(req)
+			if hour != tt.expected || ok != tt.valid {
+				t.Errorf("parseForecastHour(%s) = (%d, %v), want (%d, %v)", tt.query, hour, ok, tt.expected, tt.valid)
+			}
+		})
+	}
+}
+
+func TestHourForecast(t *testing.T) {
+	// Leituras a cada 3 horas, no horário local, para dois dias
+	var hourly []wttrHourly
+	for _, date := range []string{"2025-01-15", "2025-01-16"} {
+		for hhmm := 0; hhmm <= 2100; hhmm += 300 {
+			tempC := strconv.Itoa(hhmm / 100)
+			if date == "2025-01-16" {
+				tempC = strconv.Itoa(100 + hhmm/100)
+			}
+			hourly = append(hourly, wttrHourly{Date: date, Time: strconv.Itoa(hhmm), TempC: tempC})
+		}
+	}
+	details := &WeatherDetails{raw: wttrRawDetails{Hourly: hourly}}
+	// 10h30 em São Paulo
+	now := time.Date(2025, 1, 15, 13, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		uf       string
+		hour     int
+		expected *TempPoint
+	}{
+		{"Mais tarde no mesmo dia", "SP", 14, &TempPoint{Time: "2025-01-15T14:00:00-03:00", TempC: 12}},
+		{"Hora já passada vai para o dia seguinte", "SP", 8, &TempPoint{Time: "2025-01-16T08:00:00-03:00", TempC: 106}},
+		{"Hora atual já iniciada vai para o dia seguinte", "SP", 10, &TempPoint{Time: "2025-01-16T10:00:00-03:00", TempC: 109}},
+		{"Meia-noite usa a primeira leitura do dia", "SP", 0, &TempPoint{Time: "2025-01-16T00:00:00-03:00", TempC: 100}},
+		{"Fuso do Acre", "AC", 9, &TempPoint{Time: "2025-01-15T09:00:00-05:00", TempC: 9}},
+		{"UF desconhecida", "XX", 14, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := details.hourForecast(tt.uf, tt.hour, now)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("hourForecast(%s, %d) = %+v, want %+v", tt.uf, tt.hour, got, tt.expected)
+			}
+		})
+	}
+
+	// Sem leituras para o dia da próxima ocorrência
+	lastDay := &WeatherDetails{raw: wttrRawDetails{Hourly: hourly[:8]}}
+	if got := lastDay.hourForecast("SP", 8, now); got != nil {
+		t.Errorf("hourForecast sem leituras do dia = %+v, want nil", got)
+	}
+	// Sem detalhes do wttr.in, como no Open-Meteo
+	if got := (*WeatherDetails)(nil).hourForecast("SP", 8, now); got != nil {
+		t.Errorf("hourForecast sem detalhes = %+v, want nil", got)
+	}
+}
+
+func TestWeatherByCEPHandlerForecastHour(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedHour   bool
+	}{
+		{"Sem hora", "", http.StatusOK, false},
+		{"Hora válida", "?hour=8", http.StatusOK, true},
+		{"Hora inválida", "?hour=24", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(mockCEPResolver{}, mockWeatherResolver{})(rr, httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			var resp struct {
+				Message      string     `json:"message"`
+				HourForecast *TempPoint `json:"hour_forecast"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				if resp.Message != "invalid hour parameter" {
+					t.Errorf("mensagem = %q, want invalid hour parameter", resp.Message)
+				}
+				return
+			}
+			if !tt.expectedHour {
+				if resp.HourForecast != nil {
+					t.Errorf("hour_forecast = %+v, want ausente", resp.HourForecast)
+				}
+				return
+			}
+			if resp.HourForecast == nil || !strings.Contains(resp.HourForecast.Time, "T08:00:00-03:00") {
+				t.Errorf("hour_forecast = %+v, want a próxima ocorrência das 8h", resp.HourForecast)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Coordinates é a latitude e a longitude da localidade de um CEP
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// earthRadiusKm é o raio médio da Terra usado no cálculo de distâncias
+const earthRadiusKm = 6371.0
+
+// distanceKm calcula a distância em km entre dois pontos pela fórmula de haversine
+func distanceKm(a, b Coordinates) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// Geocoder converte a localidade de um CEP em coordenadas
+type Geocoder interface {
+	Locate(ctx context.Context, cepData *CEPData) (*Coordinates, *CustomError)
+}
+
+// localityGeocoder acrescenta lat/lon às respostas de /weatherbycep/{cep};
+// configurado em main, nil desativa as coordenadas
+var localityGeocoder Geocoder
+
+// addressLocalityGeocoder implementa Geocoder buscando a cidade e a UF no
+// geocodificador de endereços
+type addressLocalityGeocoder struct {
+	geocoder AddressGeocoder
+}
+
+func (g addressLocalityGeocoder) Locate(ctx context.Context, cepData *CEPData) (*Coordinates, *CustomError) {
+	results, geoErr := g.geocoder.Geocode(ctx, fmt.Sprintf("%s, %s, Brasil", cepData.Localidade, cepData.UF))
+	if geoErr != nil {
+		return nil, geoErr
+	}
+	if len(results) == 0 {
+		return nil, &CustomError{Code: 404, Message: "can not find coordinates"}
+	}
+	return &Coordinates{Lat: results[0].Lat, Lon: results[0].Lon}, nil
+}
+
+// geocoderMissTTL é o tempo em que uma falha ao localizar um município é
+// lembrada; as coordenadas são opcionais, então é melhor respondê-las
+// ausentes do que consultar de novo um geocodificador com problemas
+const geocoderMissTTL = 5 * time.Minute
+
+// cachingGeocoder guarda as coordenadas por código IBGE do município, que
+// não mudam, e por geocoderMissTTL os municípios cuja consulta falhou; CEPs
+// sem código IBGE sempre consultam o geocodificador
+type cachingGeocoder struct {
+	mu      sync.RWMutex
+	entries map[string]Coordinates
+	misses  map[string]geocoderMiss
+	next    Geocoder
+	now     func() time.Time
+}
+
+// geocoderMiss guarda a falha de uma consulta e o momento em que expira
+type geocoderMiss struct {
+	err       *CustomError
+	expiresAt time.Time
+}
+
+// newCachingGeocoder cria o cache em frente ao geocodificador informado
+func newCachingGeocoder(next Geocoder) *cachingGeocoder {
+	return &cachingGeocoder{
+		entries: make(map[string]Coordinates),
+		misses:  make(map[string]geocoderMiss),
+		next:    next,
+		now:     time.Now,
+	}
+}
+
+func (c *cachingGeocoder) Locate(ctx context.Context, cepData *CEPData) (*Coordinates, *CustomError) {
+	// O código IBGE identifica o município; o namespace separa os inquilinos
+	key := namespacedKey(ctx, cepData.IBGE)
+	if cepData.IBGE != "" {
+		c.mu.RLock()
+		coords, ok := c.entries[key]
+		miss, missed := c.misses[key]
+		c.mu.RUnlock()
+		if ok {
+			return &coords, nil
+		}
+		if missed && c.now().Before(miss.expiresAt) {
+			return nil, miss.err
+		}
+	}
+
+	coords, err := c.next.Locate(ctx, cepData)
+	if cepData.IBGE == "" {
+		return coords, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// O cancelamento pelo cliente não diz nada sobre o geocodificador
+	if err != nil {
+		if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.misses[key] = geocoderMiss{err: err, expiresAt: c.now().Add(geocoderMissTTL)}
+		}
+		return nil, err
+	}
+	delete(c.misses, key)
+	c.entries[key] = *coords
+	return coords, nil
+}
+
+// locateCEP retorna as coordenadas da localidade do CEP, ou nil se o
+// geocodificador estiver desativado ou falhar; as coordenadas são opcionais
+// e não invalidam a resposta
+func locateCEP(ctx context.Context, cepData *CEPData) *Coordinates {
+	if localityGeocoder == nil {
+		return nil
+	}
+	coords, err := localityGeocoder.Locate(ctx, cepData)
+	if err != nil {
+		logger.WarnContext(ctx, "coordenadas indisponíveis", "provider", providerGeocoder, "ibge", cepData.IBGE, "error", err.Message)
+		return nil
+	}
+	return coords
+}
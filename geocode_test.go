@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGeocoder retorna coordenadas fixas ou um erro configurado
+type fakeGeocoder struct {
+	mu     sync.Mutex
+	coords Coordinates
+	err    *CustomError
+	calls  int
+}
+
+func (f *fakeGeocoder) Locate(ctx context.Context, cepData *CEPData) (*Coordinates, *CustomError) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	coords := f.coords
+	return &coords, nil
+}
+
+// useLocalityGeocoder ativa o geocodificador informado durante o teste
+func useLocalityGeocoder(t *testing.T, g Geocoder) {
+	t.Helper()
+	previous := localityGeocoder
+	localityGeocoder = g
+	t.Cleanup(func() { localityGeocoder = previous })
+}
+
+func TestWeatherByCEPHandlerCoordinates(t *testing.T) {
+	tests := []struct {
+		name        string
+		geocoder    *fakeGeocoder
+		expectCoord bool
+	}{
+		{"Coordenadas encontradas", &fakeGeocoder{coords: Coordinates{Lat: -23.5505, Lon: -46.6333}}, true},
+		{"Falha no geocodificador", &fakeGeocoder{err: &CustomError{Code: 502, Message: "bad gateway"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useLocalityGeocoder(t, tt.geocoder)
+			handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 22})
+
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+			if rr.Code != 200 {
+				t.Fatalf("status = %d, want 200 (%s)", rr.Code, rr.Body.String())
+			}
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &fields); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.expectCoord {
+				if _, ok := fields["lat"]; ok {
+					t.Errorf("lat deveria ser omitida: %s", rr.Body.String())
+				}
+				return
+			}
+			if fields["lat"] != -23.5505 || fields["lon"] != -46.6333 {
+				t.Errorf("lat/lon = %v/%v, want -23.5505/-46.6333", fields["lat"], fields["lon"])
+			}
+		})
+	}
+}
+
+func TestCachingGeocoder(t *testing.T) {
+	upstream := &fakeGeocoder{coords: Coordinates{Lat: -23.5505, Lon: -46.6333}}
+	geocoder := newCachingGeocoder(upstream)
+
+	saoPaulo := &CEPData{Localidade: "São Paulo", UF: "SP", IBGE: "3550308"}
+	for i := 0; i < 2; i++ {
+		if _, err := geocoder.Locate(context.Background(), saoPaulo); err != nil {
+			t.Fatalf("Locate retornou erro: %v", err)
+		}
+	}
+	if upstream.calls != 1 {
+		t.Errorf("geocodificador chamado %d vezes, want 1", upstream.calls)
+	}
+
+	// Sem código IBGE não há chave de cache
+	semIBGE := &CEPData{Localidade: "São Paulo", UF: "SP"}
+	for i := 0; i < 2; i++ {
+		geocoder.Locate(context.Background(), semIBGE)
+	}
+	if upstream.calls != 3 {
+		t.Errorf("geocodificador chamado %d vezes, want 3", upstream.calls)
+	}
+
+	// Falhas são lembradas por geocoderMissTTL, sem nova consulta
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	geocoder.now = func() time.Time { return now }
+	upstream.err = &CustomError{Code: 502, Message: "bad gateway"}
+	rio := &CEPData{Localidade: "Rio de Janeiro", UF: "RJ", IBGE: "3304557"}
+	for i := 0; i < 2; i++ {
+		if _, err := geocoder.Locate(context.Background(), rio); err == nil || err.Code != 502 {
+			t.Fatalf("Locate erro = %v, want 502", err)
+		}
+	}
+	if upstream.calls != 4 {
+		t.Errorf("geocodificador chamado %d vezes, want 4", upstream.calls)
+	}
+
+	// Passado o TTL da falha, o geocodificador é consultado de novo
+	upstream.err = nil
+	now = now.Add(geocoderMissTTL)
+	if _, err := geocoder.Locate(context.Background(), rio); err != nil {
+		t.Fatalf("Locate retornou erro: %v", err)
+	}
+	if upstream.calls != 5 {
+		t.Errorf("geocodificador chamado %d vezes, want 5", upstream.calls)
+	}
+}
+
+func TestCachingGeocoderIgnoresCancellation(t *testing.T) {
+	upstream := &fakeGeocoder{err: &CustomError{Code: 500, Message: "request canceled"}}
+	geocoder := newCachingGeocoder(upstream)
+	saoPaulo := &CEPData{Localidade: "São Paulo", UF: "SP", IBGE: "3550308"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	geocoder.Locate(ctx, saoPaulo)
+
+	// O cancelamento pelo cliente não deve esconder as coordenadas das próximas requisições
+	upstream.err = nil
+	if _, err := geocoder.Locate(context.Background(), saoPaulo); err != nil {
+		t.Fatalf("Locate retornou erro: %v", err)
+	}
+	if upstream.calls != 2 {
+		t.Errorf("geocodificador chamado %d vezes, want 2", upstream.calls)
+	}
+}
+
+func TestNominatimGeocoderTimeout(t *testing.T) {
+	nominatim := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer nominatim.Close()
+	useRetryBaseDelay(t, 0)
+	useConfig(t, Config{GeocoderTimeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err := (&nominatimGeocoder{baseURL: nominatim.URL}).Geocode(context.Background(), "São Paulo, SP, Brasil")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Geocode demorou %v, deveria respeitar GEOCODER_TIMEOUT", elapsed)
+	}
+	if err == nil || err.Code != http.StatusGatewayTimeout {
+		t.Errorf("erro = %+v, want 504 upstream timeout", err)
+	}
+}
+
+func TestAddressLocalityGeocoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		results   []GeocodeResult
+		expectErr bool
+	}{
+		{"Resultado encontrado", []GeocodeResult{{City: "São Paulo", State: "SP", Lat: -23.5505, Lon: -46.6333}}, false},
+		{"Sem resultados", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := addressLocalityGeocoder{geocoder: &stubGeocoder{results: tt.results}}
+			coords, err := g.Locate(context.Background(), &CEPData{Localidade: "São Paulo", UF: "SP"})
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Locate erro = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && (coords.Lat != -23.5505 || coords.Lon != -46.6333) {
+				t.Errorf("coordenadas = %+v", coords)
+			}
+		})
+	}
+}
+
+func TestAddressLocalityGeocoderRespectsContext(t *testing.T) {
+	// Nominatim que só responde quando o cliente desiste
+	nominatim := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer nominatim.Close()
+	useRetryBaseDelay(t, 0)
+
+	g := addressLocalityGeocoder{geocoder: &nominatimGeocoder{baseURL: nominatim.URL}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := g.Locate(ctx, &CEPData{Localidade: "São Paulo", UF: "SP"})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Locate demorou %v, deveria respeitar o prazo do contexto", elapsed)
+	}
+	if err == nil || err.Code != http.StatusGatewayTimeout {
+		t.Errorf("erro = %+v, want 504 upstream timeout", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// validCEPPrefix aceita prefixos de 1 a 3 dígitos (região, sub-região e setor do CEP)
+var validCEPPrefix = regexp.MustCompile(`^[0-9]{1,3}$`)
+
+// allowedCEPs é a lista de prefixos de CEP atendidos, carregada do ambiente na inicialização
+var allowedCEPs = newCEPAllowListFromEnv()
+
+// cepAllowList restringe os CEPs atendidos aos prefixos informados; sem
+// prefixos, todos os CEPs são permitidos
+type cepAllowList struct {
+	prefixes []string
+}
+
+// newCEPAllowListFromEnv lê CEP_ALLOWED_PREFIXES, uma lista de prefixos
+// separados por vírgula (ex.: "01,02,130"); prefixos inválidos são ignorados
+func newCEPAllowListFromEnv() *cepAllowList {
+	list := &cepAllowList{}
+	for _, value := range strings.Split(os.Getenv("CEP_ALLOWED_PREFIXES"), ",") {
+		prefix := strings.TrimSpace(value)
+		if prefix == "" {
+			continue
+		}
+		if !validCEPPrefix.MatchString(prefix) {
+			logger.Warn("prefixo de CEP_ALLOWED_PREFIXES inválido, ignorando", "value", prefix)
+			continue
+		}
+		list.prefixes = append(list.prefixes, prefix)
+	}
+	return list
+}
+
+// Allows indica se o CEP, já normalizado, começa com algum dos prefixos permitidos
+func (l *cepAllowList) Allows(cep string) bool {
+	if len(l.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(cep, prefix) {
+			return true
+		}
+	}
+	return false
+}
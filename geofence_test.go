@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNewCEPAllowListFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{"Ausente", "", nil},
+		{"Lista", "01, 02,130", []string{"01", "02", "130"}},
+		{"Inválidos ignorados", "01,1234,ab,,2", []string{"01", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CEP_ALLOWED_PREFIXES", tt.value)
+			if list := newCEPAllowListFromEnv(); !reflect.DeepEqual(list.prefixes, tt.expected) {
+				t.Errorf("prefixos = %v, want %v", list.prefixes, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCEPAllowListAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		cep      string
+		expected bool
+	}{
+		{"Lista vazia permite tudo", nil, "69900000", true},
+		{"Prefixo permitido", []string{"01", "130"}, "01310100", true},
+		{"Prefixo de 3 dígitos", []string{"01", "130"}, "13083970", true},
+		{"Fora da lista", []string{"01", "130"}, "20040002", false},
+		{"Prefixo parcial não basta", []string{"130"}, "13183970", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := &cepAllowList{prefixes: tt.prefixes}
+			if result := list.Allows(tt.cep); result != tt.expected {
+				t.Errorf("Allows(%s) = %v, want %v", tt.cep, result, tt.expected)
+			}
+		})
+	}
+}
+
+// useCEPAllowList restringe os CEPs atendidos durante o teste
+func useCEPAllowList(t *testing.T, prefixes ...string) {
+	t.Helper()
+	previous := allowedCEPs
+	allowedCEPs = &cepAllowList{prefixes: prefixes}
+	t.Cleanup(func() { allowedCEPs = previous })
+}
+
+func TestWeatherByCEPHandlerAllowList(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"CEP permitido", "/weatherbycep/01310-100", http.StatusOK},
+		{"CEP fora da lista", "/weatherbycep/20040002", http.StatusForbidden},
+		{"CEP inválido mantém 422", "/weatherbycep/123", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCassette(t, "01310100")
+			useCEPAllowList(t, "01", "130")
+
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusForbidden {
+				var resp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Message != "zipcode not allowed" {
+					t.Errorf("message = %q, want %q", resp.Message, "zipcode not allowed")
+				}
+			}
+		})
+	}
+}
+
+func TestSearchCEPAllowListSkipsUpstream(t *testing.T) {
+	useCEPAllowList(t, "01")
+	calls := 0
+	original := httpClient.Transport
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("não deveria consultar o upstream")
+	})
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	_, cepErr := searchCEP(context.Background(), "20040-002")
+	if cepErr == nil || cepErr.Code != http.StatusForbidden {
+		t.Fatalf("searchCEP erro = %v, want 403", cepErr)
+	}
+	if calls != 0 {
+		t.Errorf("upstream consultado %d vezes, want 0", calls)
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// withH2C permite que o handler também atenda HTTP/2 sem TLS (h2c), usado
+// por chamadores internos da malha de serviços. Clientes HTTP/1.1 continuam
+// sendo atendidos normalmente.
+func withH2C(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestWithH2C(t *testing.T) {
+	server := httptest.NewServer(withH2C(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})))
+	defer server.Close()
+
+	// Cliente h2c: HTTP/2 direto sobre TCP, sem TLS
+	h2cClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+
+	tests := []struct {
+		name          string
+		client        *http.Client
+		expectedProto int
+	}{
+		{"Cliente h2c", h2cClient, 2},
+		{"Cliente HTTP/1.1", server.Client(), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.client.Get(server.URL + "/weatherbycep/01310100")
+			if err != nil {
+				t.Fatalf("requisição falhou: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.ProtoMajor != tt.expectedProto {
+				t.Errorf("protocolo = %s, want HTTP/%d", resp.Proto, tt.expectedProto)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+			var weather WeatherResponse
+			if err := json.NewDecoder(resp.Body).Decode(&weather); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if weather.TempC != 23 {
+				t.Errorf("temp_C = %v, want 23", weather.TempC)
+			}
+		})
+	}
+}
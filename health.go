@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessTimeout é o prazo de cada verificação de dependência do /readyz
+const readinessTimeout = 2 * time.Second
+
+// HealthResponse representa a resposta dos endpoints de liveness e readiness.
+// Failing lista as dependências inacessíveis quando a instância não está pronta.
+type HealthResponse struct {
+	Status  string   `json:"status"`
+	Failing []string `json:"failing,omitempty"`
+}
+
+// readinessDependency é um upstream verificado pelo /readyz
+type readinessDependency struct {
+	Name string
+	URL  string
+}
+
+// readinessChecker verifica se os upstreams estão acessíveis antes de a
+// instância receber tráfego
+type readinessChecker struct {
+	dependencies []readinessDependency
+	client       *http.Client
+}
+
+// newReadinessChecker cria o verificador com os upstreams usados pelo serviço.
+// Ele usa um cliente próprio para que as verificações não entrem na taxa de
+// erro usada pelo descarte de carga.
+func newReadinessChecker() *readinessChecker {
+	return &readinessChecker{
+		dependencies: []readinessDependency{
+			{Name: "viacep", URL: config.ViaCEPBaseURL + "/"},
+			{Name: "wttr", URL: config.WeatherBaseURL + "/"},
+		},
+		client: &http.Client{Timeout: readinessTimeout},
+	}
+}
+
+// check faz um HEAD na dependência; qualquer resposta abaixo de 500 indica que ela está acessível
+func (c *readinessChecker) check(ctx context.Context, dep readinessDependency) bool {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, dep.URL, nil)
+	if err != nil {
+		return false
+	}
+	setUserAgent(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// failing verifica as dependências em paralelo e retorna as inacessíveis, na ordem configurada
+func (c *readinessChecker) failing(ctx context.Context) []string {
+	reachable := make([]bool, len(c.dependencies))
+
+	var wg sync.WaitGroup
+	for i, dep := range c.dependencies {
+		wg.Add(1)
+		go func(i int, dep readinessDependency) {
+			defer wg.Done()
+			reachable[i] = c.check(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	var failing []string
+	for i, dep := range c.dependencies {
+		if !reachable[i] {
+			failing = append(failing, dep.Name)
+		}
+	}
+	return failing
+}
+
+// readyzHandler responde 200 apenas quando todos os upstreams estão acessíveis
+func (c *readinessChecker) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		setAllow(w, http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+		return
+	}
+
+	if failing := c.failing(r.Context()); len(failing) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(HealthResponse{Status: "unavailable", Failing: failing})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+}
+
+// healthzHandler responde às verificações de liveness sem consultar os upstreams
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		setAllow(w, http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	tests := []struct {
+		method         string
+		expectedStatus int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+		{http.MethodPut, http.StatusMethodNotAllowed},
+		{http.MethodDelete, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			healthzHandler(rr, httptest.NewRequest(tt.method, "/healthz", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp HealthResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if resp.Status != "ok" {
+				t.Errorf("status = %q, want %q", resp.Status, "ok")
+			}
+		})
+	}
+}
+
+// newUpstreamStub cria um servidor que responde sempre com o status informado
+func newUpstreamStub(t *testing.T, status int) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestReadyzHandler(t *testing.T) {
+	// Endereço de um servidor já encerrado, para simular uma dependência inacessível
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name            string
+		viacepURL       string
+		wttrURL         string
+		expectedStatus  int
+		expectedFailing []string
+	}{
+		{"Ambos acessíveis", newUpstreamStub(t, 200), newUpstreamStub(t, 200), http.StatusOK, nil},
+		{"4xx conta como acessível", newUpstreamStub(t, 404), newUpstreamStub(t, 405), http.StatusOK, nil},
+		{"ViaCEP com 5xx", newUpstreamStub(t, 503), newUpstreamStub(t, 200), http.StatusServiceUnavailable, []string{"viacep"}},
+		{"wttr.in inacessível", newUpstreamStub(t, 200), closedURL, http.StatusServiceUnavailable, []string{"wttr"}},
+		{"Ambos com falha", newUpstreamStub(t, 500), closedURL, http.StatusServiceUnavailable, []string{"viacep", "wttr"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &readinessChecker{
+				dependencies: []readinessDependency{
+					{Name: "viacep", URL: tt.viacepURL},
+					{Name: "wttr", URL: tt.wttrURL},
+				},
+				client: &http.Client{Timeout: readinessTimeout},
+			}
+
+			rr := httptest.NewRecorder()
+			checker.readyzHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var resp HealthResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if !reflect.DeepEqual(resp.Failing, tt.expectedFailing) {
+				t.Errorf("failing = %v, want %v", resp.Failing, tt.expectedFailing)
+			}
+		})
+	}
+}
+
+func TestReadyzHandlerMethodNotAllowed(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newReadinessChecker().readyzHandler(rr, httptest.NewRequest("POST", "/readyz", nil))
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// upstreamHarness sobe servidores locais que imitam o ViaCEP e o wttr.in,
+// permitindo exercitar os handlers de ponta a ponta sem acessar a rede
+type upstreamHarness struct {
+	ViaCEP *httptest.Server
+	Wttr   *httptest.Server
+
+	mu          sync.Mutex
+	viaCEPReply upstreamReply
+	wttrReply   upstreamReply
+	requests    []string
+}
+
+// upstreamReply é a resposta que um upstream falso devolve
+type upstreamReply struct {
+	Status int
+	Body   string
+}
+
+const (
+	harnessViaCEPBody = `{"cep": "01310-100", "logradouro": "Avenida Paulista", "bairro": "Bela Vista", "localidade": "São Paulo", "uf": "SP", "ibge": "3550308"}`
+	harnessWttrBody   = `{"current_condition": [{"temp_C": "23", "FeelsLikeC": "24", "humidity": "65", "weatherDesc": [{"value": "Sunny"}]}]}`
+)
+
+// newUpstreamHarness sobe os upstreams falsos, já respondendo com sucesso, e
+// aponta a configuração para eles durante o teste
+func newUpstreamHarness(t *testing.T) *upstreamHarness {
+	t.Helper()
+
+	h := &upstreamHarness{
+		viaCEPReply: upstreamReply{Status: http.StatusOK, Body: harnessViaCEPBody},
+		wttrReply:   upstreamReply{Status: http.StatusOK, Body: harnessWttrBody},
+	}
+	h.ViaCEP = httptest.NewServer(h.serve(func() upstreamReply { return h.viaCEPReply }))
+	h.Wttr = httptest.NewServer(h.serve(func() upstreamReply { return h.wttrReply }))
+	t.Cleanup(h.ViaCEP.Close)
+	t.Cleanup(h.Wttr.Close)
+
+	useConfig(t, Config{ViaCEPBaseURL: h.ViaCEP.URL + "/ws", WeatherBaseURL: h.Wttr.URL})
+	useRetryBaseDelay(t, 0)
+	return h
+}
+
+// serve devolve a resposta configurada no momento da requisição
+func (h *upstreamHarness) serve(reply func() upstreamReply) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.mu.Lock()
+		h.requests = append(h.requests, r.URL.Path)
+		resp := reply()
+		h.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.Status)
+		w.Write([]byte(resp.Body))
+	}
+}
+
+// SetViaCEP altera a resposta do ViaCEP falso
+func (h *upstreamHarness) SetViaCEP(status int, body string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.viaCEPReply = upstreamReply{Status: status, Body: body}
+}
+
+// SetWttr altera a resposta do wttr.in falso
+func (h *upstreamHarness) SetWttr(status int, body string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.wttrReply = upstreamReply{Status: status, Body: body}
+}
+
+// Requests retorna os caminhos requisitados aos upstreams falsos
+func (h *upstreamHarness) Requests() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.requests...)
+}
+
+func TestWeatherByCEPHandlerIntegration(t *testing.T) {
+	tests := []struct {
+		name            string
+		viaCEP          upstreamReply
+		wttr            upstreamReply
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:           "Caminho feliz",
+			viaCEP:         upstreamReply{http.StatusOK, harnessViaCEPBody},
+			wttr:           upstreamReply{http.StatusOK, harnessWttrBody},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "ViaCEP não encontra o CEP",
+			viaCEP:          upstreamReply{http.StatusOK, `{"erro": true}`},
+			wttr:            upstreamReply{http.StatusOK, harnessWttrBody},
+			expectedStatus:  http.StatusNotFound,
+			expectedMessage: "can not find zipcode",
+		},
+		{
+			name:            "ViaCEP fora do ar",
+			viaCEP:          upstreamReply{http.StatusServiceUnavailable, `<html>indisponível</html>`},
+			wttr:            upstreamReply{http.StatusOK, harnessWttrBody},
+			expectedStatus:  http.StatusBadGateway,
+			expectedMessage: "bad gateway",
+		},
+		{
+			name:            "wttr.in com JSON malformado",
+			viaCEP:          upstreamReply{http.StatusOK, harnessViaCEPBody},
+			wttr:            upstreamReply{http.StatusOK, `{"current_condition": [`},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedMessage: "internal server error",
+		},
+		{
+			name:            "wttr.in sem current_condition",
+			viaCEP:          upstreamReply{http.StatusOK, harnessViaCEPBody},
+			wttr:            upstreamReply{http.StatusOK, `{"current_condition": []}`},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedMessage: "weather data not available",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newUpstreamHarness(t)
+			h.SetViaCEP(tt.viaCEP.Status, tt.viaCEP.Body)
+			h.SetWttr(tt.wttr.Status, tt.wttr.Body)
+
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s, requisições: %v)", rr.Code, tt.expectedStatus, rr.Body.String(), h.Requests())
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var weather WeatherResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &weather); err != nil {
+					t.Fatalf("resposta não é um JSON válido: %v", err)
+				}
+				if weather.TempC != 23 {
+					t.Errorf("temp_C = %v, want 23", weather.TempC)
+				}
+				return
+			}
+
+			var errResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("resposta de erro não é um JSON válido: %v", err)
+			}
+			if errResp.Message != tt.expectedMessage {
+				t.Errorf("mensagem = %q, want %q", errResp.Message, tt.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestUpstreamHarnessRoutesRequests(t *testing.T) {
+	h := newUpstreamHarness(t)
+
+	rr := httptest.NewRecorder()
+	NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})(rr, httptest.NewRequest("GET", "/weatherbycep/01310-100", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (corpo: %s)", rr.Code, rr.Body.String())
+	}
+
+	requests := h.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("requisições = %v, want uma para cada upstream", requests)
+	}
+	if requests[0] != "/ws/01310100/json/" {
+		t.Errorf("requisição ao ViaCEP = %q, want /ws/01310100/json/", requests[0])
+	}
+	if !strings.HasPrefix(requests[1], "/São+Paulo,SP,Brazil") {
+		t.Errorf("requisição ao wttr.in = %q, want /São+Paulo,SP,Brazil", requests[1])
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// supportedWeatherLangs são os idiomas aceitos para a descrição do tempo,
+// repassados ao wttr.in no parâmetro lang
+var supportedWeatherLangs = map[string]bool{
+	"de":    true,
+	"en":    true,
+	"es":    true,
+	"fr":    true,
+	"it":    true,
+	"pt":    true,
+	"pt-br": true,
+}
+
+// defaultWeatherLang é o idioma usado quando a requisição não informa ?lang=;
+// vazio mantém o padrão do wttr.in (inglês)
+var defaultWeatherLang = newWeatherLangFromEnv()
+
+// newWeatherLangFromEnv lê o idioma padrão de WEATHER_LANG
+func newWeatherLangFromEnv() string {
+	value := os.Getenv("WEATHER_LANG")
+	if value == "" {
+		return ""
+	}
+	lang := strings.ToLower(strings.TrimSpace(value))
+	if !supportedWeatherLangs[lang] {
+		logger.Warn("WEATHER_LANG inválido, usando o padrão do provedor", "value", value)
+		return ""
+	}
+	return lang
+}
+
+// weatherLangKey é a chave do idioma da descrição do tempo no contexto
+type weatherLangKey struct{}
+
+// withWeatherLang guarda no contexto o idioma da descrição do tempo
+func withWeatherLang(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, weatherLangKey{}, lang)
+}
+
+// weatherLangFromContext retorna o idioma guardado no contexto ou, se não
+// houver, o padrão configurado
+func weatherLangFromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(weatherLangKey{}).(string); ok {
+		return lang
+	}
+	return defaultWeatherLang
+}
+
+// parseWeatherLang lê o idioma de ?lang=, usando o padrão configurado quando ausente
+func parseWeatherLang(r *http.Request) (string, bool) {
+	value := r.URL.Query().Get("lang")
+	if value == "" {
+		return defaultWeatherLang, true
+	}
+	lang := strings.ToLower(value)
+	if !supportedWeatherLangs[lang] {
+		return "", false
+	}
+	return lang, true
+}
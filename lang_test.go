@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useWeatherLang substitui o idioma padrão durante o teste
+func useWeatherLang(t *testing.T, lang string) {
+	t.Helper()
+	previous := defaultWeatherLang
+	defaultWeatherLang = lang
+	t.Cleanup(func() { defaultWeatherLang = previous })
+}
+
+func TestWeatherLangInWttrURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultLang    string
+		path           string
+		expectedStatus int
+		expectedLang   string
+	}{
+		{"Sem idioma", "", "/weatherbycep/01310100", http.StatusOK, ""},
+		{"Idioma da query", "", "/weatherbycep/01310100?lang=pt", http.StatusOK, "pt"},
+		{"Idioma padrão do ambiente", "pt", "/weatherbycep/01310100", http.StatusOK, "pt"},
+		{"Query sobrepõe o padrão", "pt", "/weatherbycep/01310100?lang=ES", http.StatusOK, "es"},
+		{"Idioma fora da lista", "", "/weatherbycep/01310100?lang=xx", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useWeatherLang(t, tt.defaultLang)
+
+			var query map[string][]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				w.Write([]byte(`{"current_condition": [{"temp_C": "20", "weatherDesc": [{"value": "Ensolarado"}]}]}`))
+			}))
+			defer server.Close()
+			useConfig(t, Config{WeatherBaseURL: server.URL})
+
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(newFakeCEPResolver(), wttrWeatherResolver{})(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				if query != nil {
+					t.Error("o wttr.in não deveria ser consultado com idioma inválido")
+				}
+				return
+			}
+
+			got, present := query["lang"]
+			if tt.expectedLang == "" {
+				if present {
+					t.Errorf("lang = %v, want ausente", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0] != tt.expectedLang {
+				t.Errorf("lang = %v, want %q", got, tt.expectedLang)
+			}
+		})
+	}
+}
+
+func TestCachingWeatherResolverKeysByLang(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	next := &fakeWeatherResolver{tempC: 21}
+	resolver := cachingWeatherResolver{cache: newTestWeatherCache(t, time.Minute, &now), next: next}
+
+	for _, lang := range []string{"pt", "en", "pt"} {
+		if _, err := resolver.ResolveWeather(withWeatherLang(context.Background(), lang), "São Paulo", "SP"); err != nil {
+			t.Fatalf("ResolveWeather(%s) retornou erro: %v", lang, err)
+		}
+	}
+	if next.calls != 2 {
+		t.Errorf("chamadas ao resolver = %d, want 2 (uma por idioma)", next.calls)
+	}
+}
+
+func TestNewWeatherLangFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", ""},
+		{"pt", "pt"},
+		{" PT-BR ", "pt-br"},
+		{"klingon", ""},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("WEATHER_LANG", tt.value)
+		if got := newWeatherLangFromEnv(); got != tt.expected {
+			t.Errorf("WEATHER_LANG=%q: idioma = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
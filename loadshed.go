@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// upstreamErrorWindow é a quantidade de chamadas consideradas na taxa de erro
+	upstreamErrorWindow = 100
+	// loadShedMinSamples evita descartar requisições com poucas amostras na janela
+	loadShedMinSamples = 20
+	// defaultShedFraction é a fração de requisições descartadas quando a taxa de erro é alta
+	defaultShedFraction = 0.5
+	// upstreamErrorMaxAge é a idade a partir da qual um resultado deixa de contar
+	// na taxa de erro. Como só as requisições atendidas são registradas, sem
+	// essa expiração uma janela cheia de erros manteria o descarte ligado
+	// indefinidamente, mesmo depois de os upstreams se recuperarem.
+	upstreamErrorMaxAge = 30 * time.Second
+)
+
+// upstreamErrors acompanha o resultado das chamadas feitas pelo httpClient
+var upstreamErrors = newUpstreamErrorTracker(upstreamErrorWindow)
+
+// upstreamErrorTracker mantém uma janela deslizante com o resultado das
+// últimas chamadas aos upstreams; resultados mais antigos que maxAge são ignorados
+type upstreamErrorTracker struct {
+	mu     sync.Mutex
+	window []upstreamResult
+	next   int
+	count  int
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// upstreamResult é o resultado de uma chamada e o momento em que terminou
+type upstreamResult struct {
+	failed bool
+	at     time.Time
+}
+
+// newUpstreamErrorTracker cria um tracker com uma janela do tamanho informado
+func newUpstreamErrorTracker(size int) *upstreamErrorTracker {
+	return &upstreamErrorTracker{
+		window: make([]upstreamResult, size),
+		maxAge: upstreamErrorMaxAge,
+		now:    time.Now,
+	}
+}
+
+// Record registra o resultado de uma chamada, descartando a mais antiga se a janela estiver cheia
+func (t *upstreamErrorTracker) Record(failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.count < len(t.window) {
+		t.count++
+	}
+	t.window[t.next] = upstreamResult{failed: failed, at: t.now()}
+	t.next = (t.next + 1) % len(t.window)
+}
+
+// ErrorRate retorna a taxa de erro atual e o número de amostras recentes na janela
+func (t *upstreamErrorTracker) ErrorRate() (float64, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := t.now().Add(-t.maxAge)
+	samples, failures := 0, 0
+	for _, result := range t.window[:t.count] {
+		if result.at.Before(cutoff) {
+			continue
+		}
+		samples++
+		if result.failed {
+			failures++
+		}
+	}
+	if samples == 0 {
+		return 0, 0
+	}
+	return float64(failures) / float64(samples), samples
+}
+
+// errorTrackingTransport registra falhas de rede e respostas 5xx dos upstreams
+type errorTrackingTransport struct {
+	next    http.RoundTripper
+	tracker *upstreamErrorTracker
+}
+
+func (t *errorTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	t.tracker.Record(err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
+
+// loadShedder descarta parte das novas requisições enquanto a taxa de erro dos
+// upstreams estiver acima do limite, dando tempo para eles se recuperarem
+type loadShedder struct {
+	tracker   *upstreamErrorTracker
+	threshold float64
+	fraction  float64
+	random    func() float64
+}
+
+// newLoadShedderFromEnv configura o descarte de carga a partir de
+// LOAD_SHED_ERROR_THRESHOLD e LOAD_SHED_FRACTION. Sem um limite configurado
+// o descarte fica desativado.
+func newLoadShedderFromEnv() *loadShedder {
+	return &loadShedder{
+		tracker: upstreamErrors,
+		threshold: envFloat("LOAD_SHED_ERROR_THRESHOLD", 0, func(threshold float64) bool {
+			return threshold > 0 && threshold <= 1
+		}),
+		// Descartar todas as requisições impediria perceber a recuperação dos upstreams
+		fraction: envFloat("LOAD_SHED_FRACTION", defaultShedFraction, func(fraction float64) bool {
+			return fraction >= 0 && fraction < 1
+		}),
+		random: rand.Float64,
+	}
+}
+
+// shouldShed decide se a requisição atual deve ser descartada
+func (s *loadShedder) shouldShed() bool {
+	if s.threshold <= 0 {
+		return false
+	}
+	rate, samples := s.tracker.ErrorRate()
+	if samples < loadShedMinSamples || rate < s.threshold {
+		return false
+	}
+	return s.random() < s.fraction
+}
+
+// Wrap aplica o descarte de carga ao handler informado
+func (s *loadShedder) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.shouldShed() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "service overloaded"})
+			return
+		}
+		next(w, r)
+	}
+}
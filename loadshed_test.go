@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUpstreamErrorTracker(t *testing.T) {
+	tracker := newUpstreamErrorTracker(4)
+
+	for _, failed := range []bool{true, true, false, false} {
+		tracker.Record(failed)
+	}
+	if rate, samples := tracker.ErrorRate(); rate != 0.5 || samples != 4 {
+		t.Errorf("ErrorRate() = (%v, %d), want (0.5, 4)", rate, samples)
+	}
+
+	// Janela cheia: as falhas mais antigas saem da conta
+	tracker.Record(false)
+	tracker.Record(false)
+	if rate, samples := tracker.ErrorRate(); rate != 0 || samples != 4 {
+		t.Errorf("ErrorRate() = (%v, %d), want (0, 4)", rate, samples)
+	}
+}
+
+func TestErrorTrackingTransport(t *testing.T) {
+	tracker := newUpstreamErrorTracker(10)
+	transport := &errorTrackingTransport{
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/erro":
+				return nil, errors.New("conexão recusada")
+			case "/503":
+				return &http.Response{StatusCode: http.StatusServiceUnavailable}, nil
+			default:
+				return &http.Response{StatusCode: http.StatusNotFound}, nil
+			}
+		}),
+		tracker: tracker,
+	}
+
+	for _, path := range []string{"/erro", "/503", "/404", "/404"} {
+		req := httptest.NewRequest("GET", "http://upstream"+path, nil)
+		transport.RoundTrip(req)
+	}
+
+	// Apenas erros de rede e 5xx contam como falha do upstream
+	if rate, _ := tracker.ErrorRate(); rate != 0.5 {
+		t.Errorf("ErrorRate() = %v, want 0.5", rate)
+	}
+}
+
+func TestLoadShedderShedsDuringHighErrorRate(t *testing.T) {
+	tracker := newUpstreamErrorTracker(upstreamErrorWindow)
+	for i := 0; i < upstreamErrorWindow; i++ {
+		tracker.Record(i%10 != 0) // 90% de erros
+	}
+
+	// Sequência determinística: metade dos sorteios abaixo da fração
+	draws := []float64{0.1, 0.9}
+	calls := 0
+	shedder := &loadShedder{
+		tracker:   tracker,
+		threshold: 0.5,
+		fraction:  0.5,
+		random: func() float64 {
+			calls++
+			return draws[calls%len(draws)]
+		},
+	}
+
+	handler := shedder.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	shed := 0
+	for i := 0; i < 10; i++ {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+		if rr.Code == http.StatusServiceUnavailable {
+			shed++
+		}
+	}
+
+	if shed != 5 {
+		t.Errorf("esperadas 5 requisições descartadas, got %d", shed)
+	}
+}
+
+func TestLoadShedderServesBelowThreshold(t *testing.T) {
+	tracker := newUpstreamErrorTracker(upstreamErrorWindow)
+	for i := 0; i < upstreamErrorWindow; i++ {
+		tracker.Record(i%10 == 0) // 10% de erros
+	}
+
+	shedder := &loadShedder{
+		tracker:   tracker,
+		threshold: 0.5,
+		fraction:  1,
+		random:    func() float64 { return 0 },
+	}
+
+	if shedder.shouldShed() {
+		t.Error("não deveria descartar requisições abaixo do limite de erro")
+	}
+
+	// Sem limite configurado o descarte fica desativado
+	shedder.threshold = 0
+	for i := 0; i < upstreamErrorWindow; i++ {
+		tracker.Record(true)
+	}
+	if shedder.shouldShed() {
+		t.Error("não deveria descartar requisições com o descarte desativado")
+	}
+}
+
+func TestLoadShedderRecoversAfterErrorsExpire(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	tracker := newUpstreamErrorTracker(upstreamErrorWindow)
+	tracker.now = func() time.Time { return now }
+	for i := 0; i < upstreamErrorWindow; i++ {
+		tracker.Record(true)
+	}
+
+	// Com a fração máxima aceita quase tudo é descartado e nada novo é
+	// registrado na janela
+	shedder := &loadShedder{
+		tracker:   tracker,
+		threshold: 0.5,
+		fraction:  0.99,
+		random:    func() float64 { return 0 },
+	}
+	if !shedder.shouldShed() {
+		t.Fatal("deveria descartar requisições com a janela cheia de erros")
+	}
+
+	// Os erros antigos deixam de contar e as requisições voltam a ser atendidas
+	now = now.Add(upstreamErrorMaxAge + time.Second)
+	if shedder.shouldShed() {
+		t.Error("não deveria descartar requisições depois que os erros expiram")
+	}
+	if rate, samples := tracker.ErrorRate(); rate != 0 || samples != 0 {
+		t.Errorf("ErrorRate() = (%v, %d), want (0, 0)", rate, samples)
+	}
+
+	// Novos resultados voltam a compor a taxa normalmente
+	for i := 0; i < loadShedMinSamples; i++ {
+		tracker.Record(false)
+	}
+	if rate, samples := tracker.ErrorRate(); rate != 0 || samples != loadShedMinSamples {
+		t.Errorf("ErrorRate() = (%v, %d), want (0, %d)", rate, samples, loadShedMinSamples)
+	}
+}
+
+func TestNewLoadShedderFromEnvFraction(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		{"", defaultShedFraction},
+		{"0.8", 0.8},
+		{"0", 0},
+		{"1", defaultShedFraction},
+		{"1.5", defaultShedFraction},
+		{"-0.1", defaultShedFraction},
+		{"metade", defaultShedFraction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("LOAD_SHED_FRACTION", tt.value)
+			if got := newLoadShedderFromEnv().fraction; got != tt.expected {
+				t.Errorf("fraction = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// roundTripFunc adapta uma função para a interface http.RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Nomes dos upstreams usados no campo provider dos logs e nas métricas
+const (
+	providerViaCEP    = "viacep"
+	providerWttr      = "wttr"
+	providerGeocoder  = "geocoder"
+	providerOpenMeteo = "openmeteo"
+	providerBrasilAPI = "brasilapi"
+)
+
+// logger é o logger estruturado da aplicação, configurado em main para
+// emitir JSON
+var logger = slog.Default()
+
+// newJSONLogger cria um logger que escreve uma linha JSON por evento a partir
+// do nível informado. As chamadas com contexto incluem o request_id da
+// requisição em andamento.
+func newJSONLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(requestIDLogHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// parseLogLevel converte o valor de LOG_LEVEL (debug, info, warn ou error,
+// sem diferenciar maiúsculas) no nível do slog, usando info para valores
+// desconhecidos
+func parseLogLevel(s string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
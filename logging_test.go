@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// useLogger direciona os logs da aplicação para um buffer durante o teste
+func useLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := logger
+	logger = newJSONLogger(&buf, slog.LevelInfo)
+	t.Cleanup(func() { logger = previous })
+	return &buf
+}
+
+// logLines decodifica as linhas JSON emitidas pelo logger
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("linha de log não é um JSON válido: %q (%v)", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestWeatherByCEPHandlerLogsRequestOnce(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus float64
+	}{
+		{"Sucesso", "/weatherbycep/01310-100", 200},
+		{"Não encontrado", "/weatherbycep/99999999", 404},
+		{"Inválido", "/weatherbycep/123", 422},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := useLogger(t)
+			// O log de acesso é o único registro por requisição; o handler não
+			// emite uma linha própria
+			handler := newAccessLog(logger).Wrap(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23}))
+			handler(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			lines := logLines(t, buf)
+			if len(lines) != 1 {
+				t.Fatalf("esperada 1 linha de log, got %d: %v", len(lines), lines)
+			}
+			line := lines[0]
+			if line["path"] != tt.path {
+				t.Errorf("path = %v, want %v", line["path"], tt.path)
+			}
+			if line["status"] != tt.expectedStatus {
+				t.Errorf("status = %v, want %v", line["status"], tt.expectedStatus)
+			}
+			if _, ok := line["duration_ms"].(float64); !ok {
+				t.Errorf("duration_ms ausente ou não numérico: %v", line["duration_ms"])
+			}
+		})
+	}
+}
+
+func TestUpstreamErrorLogsProvider(t *testing.T) {
+	useRetryBaseDelay(t, 0)
+	original := httpClient.Transport
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("conexão recusada")
+	})
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	buf := useLogger(t)
+	if _, weatherErr := getWeatherData(context.Background(), "São Paulo", "SP"); weatherErr == nil {
+		t.Fatal("getWeatherData deveria retornar erro")
+	}
+
+	for _, line := range logLines(t, buf) {
+		if line["level"] == "ERROR" {
+			if line["provider"] != providerWttr {
+				t.Errorf("provider = %v, want %v", line["provider"], providerWttr)
+			}
+			if line["error"] == nil || line["error"] == "" {
+				t.Error("campo error ausente no log de erro")
+			}
+			return
+		}
+	}
+	t.Error("nenhuma linha de log de erro emitida")
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"info", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"warning", slog.LevelWarn},
+		{"error", slog.LevelError},
+		{" DEBUG ", slog.LevelDebug},
+		{"", slog.LevelInfo},
+		{"verbose", slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if level := parseLogLevel(tt.value); level != tt.expected {
+				t.Errorf("parseLogLevel(%q) = %v, want %v", tt.value, level, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewJSONLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := newJSONLogger(&buf, slog.LevelWarn)
+	l.Info("descartada")
+	l.Warn("registrada")
+
+	lines := logLines(t, &buf)
+	if len(lines) != 1 || lines[0]["msg"] != "registrada" {
+		t.Errorf("linhas = %v, want apenas a de nível WARN", lines)
+	}
+}
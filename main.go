@@ -1,277 +1,818 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
-	"regexp"
+	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+
+	"golang-weatherbycep/weather"
 )
 
-// httpClient é um cliente HTTP personalizado com configuração TLS tolerante para Cloud Run
-var httpClient = &http.Client{
-	Timeout: 30 * time.Second,
-	Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: false, // Mantém a verificação de certificado
-			MinVersion:         tls.VersionTLS12,
-		},
-		MaxIdleConns:       10,
-		IdleConnTimeout:    30 * time.Second,
-		DisableCompression: false,
-		ForceAttemptHTTP2:  true,
-	},
+// httpClient é o cliente HTTP usado em todas as chamadas aos upstreams
+var httpClient = newHTTPClient(config)
+
+// newHTTPClient cria um cliente HTTP com configuração TLS tolerante para Cloud
+// Run e o pool de conexões da configuração informada. O transporte limita as
+// chamadas simultâneas (MAX_UPSTREAM_CALLS) e registra as falhas dos upstreams
+// usadas pelo descarte de carga e a latência exposta em /metrics; apenas
+// redirecionamentos autorizados pela redirectPolicy são seguidos.
+func newHTTPClient(cfg Config) *http.Client {
+	return &http.Client{
+		Timeout:       30 * time.Second,
+		CheckRedirect: newRedirectPolicyFromEnv().check,
+		Transport: newUpstreamTransport(&http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: false, // Mantém a verificação de certificado
+				MinVersion:         tls.VersionTLS12,
+			},
+			MaxIdleConns:        cfg.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+			MaxConnsPerHost:     cfg.MaxConnsPerHost,
+			IdleConnTimeout:     cfg.IdleConnTimeout,
+			DisableCompression:  false,
+			ForceAttemptHTTP2:   true,
+		}),
+	}
 }
 
-// CEPData representa a estrutura de dados retornada pela API do ViaCEP
-type CEPData struct {
-	CEP         string      `json:"cep"`
-	Logradouro  string      `json:"logradouro"`
-	Complemento string      `json:"complemento"`
-	Bairro      string      `json:"bairro"`
-	Localidade  string      `json:"localidade"`
-	UF          string      `json:"uf"`
-	IBGE        string      `json:"ibge"`
-	GIA         string      `json:"gia"`
-	DDD         string      `json:"ddd"`
-	SIAFI       string      `json:"siafi"`
-	Erro        interface{} `json:"erro,omitempty"`
+// newUpstreamTransport envolve o transporte base com o limite de chamadas
+// simultâneas, o registro da taxa de erro e as métricas dos upstreams. O
+// limitador fica por fora para que as chamadas sem vaga não entrem nas
+// métricas nem na taxa de erro dos upstreams.
+func newUpstreamTransport(base http.RoundTripper) http.RoundTripper {
+	return &limitTransport{
+		limiter: newUpstreamLimiterFromEnv(),
+		next: &errorTrackingTransport{
+			next:    &metricsTransport{next: base},
+			tracker: upstreamErrors,
+		},
+	}
 }
 
+// CEPData representa a estrutura de dados retornada pela API do ViaCEP; o
+// tipo é compartilhado com a biblioteca do pacote weather
+type CEPData = weather.CEPData
+
 // WeatherData representa a estrutura de dados de temperatura
 type WeatherData struct {
-	TempC float64 `json:"temp_C"`
-	TempF float64 `json:"temp_F"`
-	TempK float64 `json:"temp_K"`
+	TempC float64 `json:"temp_C" xml:"temp_C"`
+	TempF float64 `json:"temp_F" xml:"temp_F"`
+	TempK float64 `json:"temp_K" xml:"temp_K"`
+
+	// Condições atuais informadas pelo provedor; omitidas quando indisponíveis
+	Humidity    int     `json:"humidity,omitempty" xml:"humidity,omitempty"`
+	FeelsLikeC  float64 `json:"feels_like_C,omitempty" xml:"feels_like_C,omitempty"`
+	Description string  `json:"description,omitempty" xml:"description,omitempty"`
+
+	// Details guarda os dados complementares exibidos apenas no modo verbose
+	Details *WeatherDetails `json:"-" xml:"-"`
+
+	// ForecastDays guarda a previsão dos próximos dias, exibida apenas com ?forecast=
+	ForecastDays []ForecastDay `json:"-" xml:"-"`
+
+	// Alerts guarda os alertas meteorológicos dos provedores que os
+	// informam, exibidos apenas no modo verbose
+	Alerts []WeatherAlert `json:"-" xml:"-"`
+
+	// FetchedAt é o momento em que os dados foram obtidos do provedor; o
+	// cache preserva o valor original. Zero quando desconhecido.
+	FetchedAt time.Time `json:"-" xml:"-"`
 }
 
-// ErrorResponse representa a estrutura de resposta de erro
+// MinAddress representa a versão reduzida do endereço: o CEP resolvido, a cidade e a UF
+type MinAddress struct {
+	CEP        string `json:"cep"`
+	Localidade string `json:"localidade"`
+	UF         string `json:"uf"`
+}
+
+// WeatherResponse representa a resposta de sucesso com temperatura e endereço
+type WeatherResponse struct {
+	WeatherData
+	*WeatherDetails
+	*Coordinates
+	Address          interface{}   `json:"address,omitempty"`
+	FormattedAddress string        `json:"formatted_address,omitempty"`
+	Forecast         []ForecastDay `json:"forecast,omitempty"`
+	HourForecast     *TempPoint    `json:"hour_forecast,omitempty"`
+	Echo             *RequestEcho  `json:"echo,omitempty"`
+}
+
+// Modos de retorno do endereço aceitos em ?address=
+const (
+	addressModeMin  = "min"
+	addressModeFull = "full"
+)
+
+// ErrorResponse representa a estrutura de resposta de erro. Message é
+// estável para os clientes; Detail explica o problema quando disponível.
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Message string `json:"message" xml:"message"`
+	Detail  string `json:"detail,omitempty" xml:"detail,omitempty"`
+}
+
+// knownInvalidCEPs são CEPs bem formados que os Correios nunca atribuíram e
+// que costumam ser digitados como teste; respondem 404 sem consultar o
+// ViaCEP. O 00000000 não precisa constar aqui porque já fica fora da faixa
+// atribuída e é recusado na validação.
+var knownInvalidCEPs = map[string]bool{
+	"99999999": true,
 }
 
-// isValidCEP valida se o CEP está no formato correto
+// isValidCEP valida se o CEP está no formato correto e dentro da faixa atribuída
 func isValidCEP(cep string) bool {
-	// Remove traços e espaços
-	cep = strings.ReplaceAll(cep, "-", "")
-	cep = strings.ReplaceAll(cep, " ", "")
+	return cepValidationDetail(cep) == ""
+}
+
+// cepValidationDetail descreve por que o CEP é inválido, ou retorna vazio se
+// ele for válido
+func cepValidationDetail(cep string) string {
+	return weather.ValidateCEP(cep)
+}
+
+// formatCEP normaliza o CEP removendo separadores e espaços
+func formatCEP(cep string) string {
+	return weather.FormatCEP(cep)
+}
 
-	// Verifica se tem 8 dígitos
-	if len(cep) != 8 {
-		return false
+// cepFromPath extrai o CEP do segmento que segue o prefixo informado,
+// decodificando o escape de URL (ex.: "%20"), removendo espaços ao redor e uma
+// barra final. Entradas inválidas continuam sendo recusadas pela validação.
+func cepFromPath(r *http.Request, prefix string) string {
+	segment := strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+	if decoded, err := url.PathUnescape(segment); err == nil {
+		segment = decoded
 	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(segment), "/"))
+}
 
-	// Verifica se contém apenas números
-	matched, _ := regexp.MatchString(`^\d{8}$`, cep)
-	return matched
+// weatherCEPParam retorna o CEP de /weatherbycep/{cep} ou, se o path não
+// trouxer um, de /weatherbycep?cep=. Quando ambos existem, o path prevalece.
+func weatherCEPParam(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/weatherbycep/") {
+		if cep := cepFromPath(r, "/weatherbycep/"); cep != "" {
+			return cep
+		}
+	}
+	return strings.TrimSpace(r.URL.Query().Get("cep"))
 }
 
-// formatCEP formata o CEP removendo caracteres especiais
-func formatCEP(cep string) string {
-	cep = strings.ReplaceAll(cep, "-", "")
-	cep = strings.ReplaceAll(cep, " ", "")
-	return cep
+// parseAddressMode lê o modo de endereço da query string, usando min como padrão
+func parseAddressMode(r *http.Request) (string, bool) {
+	mode := r.URL.Query().Get("address")
+	switch mode {
+	case "":
+		return addressModeMin, true
+	case addressModeMin, addressModeFull:
+		return mode, true
+	default:
+		return "", false
+	}
 }
 
-// CustomError representa erros customizados com códigos HTTP
-type CustomError struct {
-	Code    int
-	Message string
+// buildAddress monta o endereço da resposta de acordo com o modo solicitado
+func buildAddress(mode string, cepData *CEPData) interface{} {
+	if mode == addressModeFull {
+		return cepData
+	}
+	return MinAddress{CEP: cepData.CEP, Localidade: cepData.Localidade, UF: cepData.UF}
 }
 
-func (e *CustomError) Error() string {
-	return e.Message
+// CustomError representa erros customizados com códigos HTTP; é o mesmo
+// tipo de erro exportado pela biblioteca do pacote weather
+type CustomError = weather.Error
+
+// upstreamDoer faz as chamadas aos upstreams pelo httpClient, com o
+// User-Agent configurado e as retentativas do provedor. Com insecureFallback, uma chamada
+// HTTPS que falha é repetida via HTTP.
+type upstreamDoer struct {
+	insecureFallback bool
+}
+
+func (d upstreamDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	setUserAgent(req)
+	attempts := config.maxAttemptsFor(providerForHost(req.URL.Hostname()))
+	resp, err := doWithRetry(ctx, req, attempts)
+	// Se o cliente já desistiu da requisição, o fallback é inútil
+	if err == nil || !d.insecureFallback || req.URL.Scheme != "https" || ctx.Err() != nil {
+		return resp, err
+	}
+
+	logger.WarnContext(ctx, "erro com HTTPS, tentando HTTP", "provider", providerForHost(req.URL.Hostname()), "error", err)
+	fallback := req.Clone(ctx)
+	fallback.URL.Scheme = "http"
+	return doWithRetry(ctx, fallback, attempts)
+}
+
+// newUpstreamClient cria o cliente da biblioteca weather com as URLs da
+// configuração atual e o idioma da requisição
+func newUpstreamClient(ctx context.Context) *weather.Client {
+	return &weather.Client{
+		ViaCEPBaseURL:  config.ViaCEPBaseURL,
+		WeatherBaseURL: config.WeatherBaseURL,
+		HTTPClient:     upstreamDoer{},
+		Lang:           weatherLangFromContext(ctx),
+	}
+}
+
+// upstreamError registra a falha de uma consulta feita pela biblioteca
+// weather e a devolve como *CustomError. Com o contexto encerrado, o erro
+// reflete o prazo ou o cancelamento, e não a falha de rede que ele provocou.
+func upstreamError(ctx context.Context, provider string, err error, attrs ...any) *CustomError {
+	if ctx.Err() != nil {
+		logger.WarnContext(ctx, "requisição cancelada", append([]any{"provider", provider, "error", ctx.Err()}, attrs...)...)
+		return contextError(ctx)
+	}
+
+	if errors.Is(err, errServerBusy) {
+		return serverBusyError()
+	}
+
+	var customErr *CustomError
+	if !errors.As(err, &customErr) {
+		customErr = &CustomError{Code: 500, Message: "internal server error", Err: err}
+	}
+	// A causa embrulhada traz o status ou a falha de rede do upstream, que
+	// ficam só no log
+	cause := error(customErr)
+	if customErr.Err != nil {
+		cause = customErr.Err
+	}
+	args := append([]any{"provider", provider, "status", customErr.Code, "error", cause}, attrs...)
+	if customErr.Code < 500 {
+		logger.InfoContext(ctx, "consulta recusada pelo upstream", args...)
+	} else {
+		logger.ErrorContext(ctx, "erro ao consultar o upstream", args...)
+	}
+	return customErr
 }
 
 // searchCEP faz a consulta na API do ViaCEP
-func searchCEP(cep string) (*CEPData, *CustomError) {
+func searchCEP(ctx context.Context, cep string) (_ *CEPData, cepErr *CustomError) {
+	// Cada consulta gera um span filho com o CEP e, em caso de sucesso, a cidade resolvida
+	ctx, span := startSpan(ctx, spanViaCEPLookup, attribute.String("cep", formatCEP(cep)))
+	defer func() { endSpan(span, cepErr) }()
+
 	// Valida o CEP
-	if !isValidCEP(cep) {
-		return nil, &CustomError{Code: 422, Message: "invalid zipcode"}
+	if detail := cepValidationDetail(cep); detail != "" {
+		return nil, &CustomError{Code: 422, Message: "invalid zipcode", Detail: detail, Err: weather.ErrInvalidCEP}
 	}
 
 	// Formata o CEP
 	formattedCEP := formatCEP(cep)
 
-	// Monta a URL da API
-	url := fmt.Sprintf("https://viacep.com.br/ws/%s/json/", formattedCEP)
-
-	// Faz a requisição HTTP usando o cliente personalizado
-	resp, err := httpClient.Get(url)
-	if err != nil {
-		// Se falhar com HTTPS, tenta com HTTP como fallback
-		log.Printf("Erro com HTTPS, tentando HTTP: %v\n", err)
-		httpURL := fmt.Sprintf("http://viacep.com.br/ws/%s/json/", formattedCEP)
-		resp, err = httpClient.Get(httpURL)
-		if err != nil {
-			log.Printf("Erro ao fazer requisição para ViaCEP: %v\n", err)
-			return nil, &CustomError{Code: 500, Message: "internal server error"}
-		}
+	// CEPs sabidamente inexistentes não precisam de uma ida ao upstream
+	if knownInvalidCEPs[formattedCEP] {
+		logger.InfoContext(ctx, "CEP não encontrado", "provider", providerViaCEP, "cep", formattedCEP)
+		return nil, &CustomError{Code: 404, Message: "can not find zipcode", Err: weather.ErrCEPNotFound}
 	}
-	defer resp.Body.Close()
 
-	// Verifica se a resposta foi bem-sucedida
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Erro na resposta do ViaCEP: %s\n", resp.Status)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
+	// Recusa os CEPs fora dos prefixos permitidos antes de consultar o ViaCEP
+	if !allowedCEPs.Allows(formattedCEP) {
+		logger.InfoContext(ctx, "CEP fora da lista permitida", "cep", formattedCEP)
+		return nil, &CustomError{Code: 403, Message: "zipcode not allowed"}
 	}
 
-	// Lê o corpo da resposta
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("Erro ao ler o corpo da resposta: %v\n", err)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
+	// Com o circuito aberto o ViaCEP não é consultado até o fim do cooldown
+	if !viaCEPBreaker.Allow() {
+		logger.WarnContext(ctx, "circuito aberto, consulta ignorada", "provider", providerViaCEP, "cep", formattedCEP)
+		return nil, &CustomError{Code: 503, Message: "zipcode lookup temporarily unavailable", Err: weather.ErrUpstreamUnavailable}
 	}
+	defer func(ctx context.Context) { viaCEPBreaker.Done(ctx, cepErr) }(ctx)
 
-	// Decodifica o JSON
-	var cepData CEPData
-	if err := json.Unmarshal(body, &cepData); err != nil {
-		fmt.Printf("Erro ao decodificar JSON: %v\n", err)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
-	}
+	// O prazo do ViaCEP cobre também o fallback para HTTP
+	ctx, cancel := withUpstreamTimeout(ctx, config.timeoutFor(providerViaCEP, config.ViaCEPTimeout))
+	defer cancel()
 
-	// Verifica se o CEP foi encontrado
-	if cepData.Erro != nil {
-		fmt.Printf("CEP não encontrado: %s\n", cep)
-		return nil, &CustomError{Code: 404, Message: "can not find zipcode"}
+	client := newUpstreamClient(ctx)
+	client.HTTPClient = upstreamDoer{insecureFallback: config.AllowInsecureFallback}
+	cepData, err := client.SearchCEP(ctx, formattedCEP)
+	if err != nil {
+		return nil, upstreamError(ctx, providerViaCEP, err, "cep", formattedCEP)
 	}
 
-	return &cepData, nil
+	span.SetAttributes(attribute.String("city", cepData.Localidade), attribute.String("state", cepData.UF))
+	return cepData, nil
 }
 
 // getWeatherData busca os dados de temperatura usando uma API gratuita
-func getWeatherData(city, state string) (*WeatherData, *CustomError) {
+func getWeatherData(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	ctx, span := startSpan(ctx, spanWeatherLookup, attribute.String("city", city), attribute.String("state", state))
+
 	// Forma alternativa: usar wttr.in que é gratuito e não requer chave
-	cityFormatted := strings.ReplaceAll(city, " ", "+")
-	stateFormatted := strings.ReplaceAll(state, " ", "+")
-	location := fmt.Sprintf("%s,%s,Brazil", cityFormatted, stateFormatted)
+	weatherData, err := fetchWttr(ctx, city+","+state, func(ctx context.Context, client *weather.Client) (*weather.WeatherData, error) {
+		return client.CurrentWeather(ctx, city, state)
+	})
+	endSpan(span, err)
+	return weatherData, err
+}
 
-	// URL da API wttr.in em formato JSON
-	url := fmt.Sprintf("https://wttr.in/%s?format=j1", url.QueryEscape(location))
+// getWeatherByCoords busca os dados de temperatura de uma coordenada geográfica
+func getWeatherByCoords(ctx context.Context, lat, lon float64) (*WeatherData, *CustomError) {
+	return fetchWttr(ctx, fmt.Sprintf("%.4f,%.4f", lat, lon), func(ctx context.Context, client *weather.Client) (*weather.WeatherData, error) {
+		return client.CurrentWeatherAt(ctx, lat, lon)
+	})
+}
 
-	resp, err := httpClient.Get(url)
-	if err != nil {
-		fmt.Printf("Erro ao fazer requisição para wttr.in: %v\n", err)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
+// fetchWttr faz a consulta ao wttr.in com o circuit breaker e o prazo do
+// provedor; location identifica a localização nos logs
+func fetchWttr(ctx context.Context, location string, lookup func(context.Context, *weather.Client) (*weather.WeatherData, error)) (_ *WeatherData, weatherErr *CustomError) {
+	// Com o circuito aberto o wttr.in não é consultado até o fim do cooldown
+	if !wttrBreaker.Allow() {
+		logger.WarnContext(ctx, "circuito aberto, consulta ignorada", "provider", providerWttr, "location", location)
+		return nil, &CustomError{Code: 503, Message: "weather temporarily unavailable", Err: weather.ErrUpstreamUnavailable}
 	}
-	defer resp.Body.Close()
+	defer func(ctx context.Context) { wttrBreaker.Done(ctx, weatherErr) }(ctx)
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Erro na resposta da API wttr.in: %s\n", resp.Status)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
-	}
+	ctx, cancel := withUpstreamTimeout(ctx, config.timeoutFor(providerWttr, config.WeatherTimeout))
+	defer cancel()
 
-	body, err := io.ReadAll(resp.Body)
+	data, err := lookup(ctx, newUpstreamClient(ctx))
 	if err != nil {
-		fmt.Printf("Erro ao ler o corpo da resposta: %v\n", err)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
+		return nil, upstreamError(ctx, providerWttr, err, "location", location)
 	}
+	weatherData := weatherFromProvider(data)
+	weatherData.FetchedAt = time.Now()
+	return weatherData, nil
+}
 
-	// Estrutura específica para wttr.in
-	var wttrResponse struct {
-		CurrentCondition []struct {
-			TempC string `json:"temp_C"`
-		} `json:"current_condition"`
+// weatherFromProvider converte os dados da biblioteca weather, arredondando
+// as conversões e montando os dados do modo verbose quando o provedor
+// informou a observação completa
+func weatherFromProvider(data *weather.WeatherData) *WeatherData {
+	weatherData := weatherFromCelsius(data.TempC)
+	weatherData.Humidity = data.Humidity
+	weatherData.FeelsLikeC = data.FeelsLikeC
+	weatherData.Description = data.Description
+	weatherData.ForecastDays = data.Forecast
+	if data.Observation != nil {
+		weatherData.Details = buildWeatherDetails(data.TempC, *data.Observation)
 	}
+	return &weatherData
+}
 
-	if err := json.Unmarshal(body, &wttrResponse); err != nil {
-		fmt.Printf("Erro ao decodificar JSON: %v\n", err)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
-	}
+// Casas decimais das temperaturas: o padrão e o máximo aceito em
+// TEMPERATURE_PRECISION. Valores maiores não trazem informação útil e, muito
+// grandes, estouram o fator de arredondamento.
+const (
+	defaultTemperaturePrecision = 1
+	maxTemperaturePrecision     = 6
+)
+
+// temperaturePrecision é o número de casas decimais das conversões para F e K
+var temperaturePrecision = newTemperaturePrecisionFromEnv()
 
-	if len(wttrResponse.CurrentCondition) == 0 {
-		fmt.Println("Dados climáticos não disponíveis para a localização fornecida.")
-		return nil, &CustomError{Code: 500, Message: "weather data not available"}
+// newTemperaturePrecisionFromEnv lê TEMPERATURE_PRECISION, limitada a maxTemperaturePrecision
+func newTemperaturePrecisionFromEnv() int {
+	precision := envInt("TEMPERATURE_PRECISION", defaultTemperaturePrecision)
+	if precision > maxTemperaturePrecision {
+		logger.Warn("TEMPERATURE_PRECISION acima do máximo, usando o máximo", "value", precision, "max", maxTemperaturePrecision)
+		return maxTemperaturePrecision
 	}
+	return precision
+}
 
-	// Converte temperatura de string para float64
-	tempCStr := wttrResponse.CurrentCondition[0].TempC
-	tempC, err := strconv.ParseFloat(tempCStr, 64)
-	if err != nil {
-		fmt.Printf("Erro ao converter temperatura: %v\n", err)
-		return nil, &CustomError{Code: 500, Message: "internal server error"}
+// weatherFromCelsius monta os dados de temperatura calculando as conversões a partir de Celsius
+func weatherFromCelsius(tempC float64) WeatherData {
+	return WeatherData{
+		TempC: tempC,
+		TempF: round((tempC*9/5)+32, temperaturePrecision), // Celsius para Fahrenheit
+		TempK: round(tempC+273.15, temperaturePrecision),   // Celsius para Kelvin
 	}
+}
 
-	// Calcula as conversões de temperatura
-	tempF := (tempC * 9 / 5) + 32 // Celsius para Fahrenheit
-	tempK := tempC + 273.15       // Celsius para Kelvin
+// round arredonda val para o número de casas decimais indicado, eliminando
+// ruídos de ponto flutuante como 73.39999999999999
+func round(val float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(val*factor) / factor
+}
 
-	return &WeatherData{
-		TempC: tempC,
-		TempF: tempF,
-		TempK: tempK,
-	}, nil
+// handlerTimeout é o prazo total para as consultas aos upstreams de uma requisição
+var handlerTimeout = 10 * time.Second
+
+// NewWeatherHandler cria o handler das requisições GET para /weatherbycep/{cep}
+// usando os resolvers informados para o CEP e para a temperatura
+func NewWeatherHandler(cepResolver CEPResolver, weatherResolver WeatherResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Erros não devem ser guardados em cache; a resposta de sucesso
+		// substitui o cabeçalho antes de ser enviada
+		setNoStore(w)
+
+		// Formato da resposta negociado pelo header Accept, JSON por padrão
+		format := negotiateFormat(r)
+		w.Header().Add("Vary", "Accept")
+		if inferUnits {
+			w.Header().Add("Vary", "Accept-Language")
+		}
+
+		// Verifica se é um GET
+		if r.Method != http.MethodGet {
+			setAllow(w, http.MethodGet)
+			writeError(w, format, http.StatusMethodNotAllowed, ErrorResponse{Message: "method not allowed"})
+			return
+		}
+
+		// Extrai o CEP do path da URL ou, na forma /weatherbycep?cep=, da query
+		path := r.URL.Path
+		if path != "/weatherbycep" && !strings.HasPrefix(path, "/weatherbycep/") {
+			writeError(w, format, http.StatusNotFound, ErrorResponse{Message: "endpoint not found"})
+			return
+		}
+
+		cep := weatherCEPParam(r)
+		if cep == "" {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "cep parameter is required"})
+			return
+		}
+
+		// Valida o modo de retorno do endereço
+		addressMode, ok := parseAddressMode(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid address mode"})
+			return
+		}
+
+		// Valida o modo de unidades
+		unitsMode, ok := parseUnitsMode(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid units parameter"})
+			return
+		}
+
+		// Valida o modo verbose
+		verbose, ok := parseVerbose(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid verbose parameter"})
+			return
+		}
+
+		// Valida o eco dos parâmetros (apenas em modo de depuração)
+		echo, ok := parseEcho(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid echo parameter"})
+			return
+		}
+
+		// Valida o pedido de sugestões para CEPs não encontrados
+		suggest, ok := parseSuggest(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid suggest parameter"})
+			return
+		}
+
+		// Valida o modo de degradação para falhas na consulta de temperatura
+		degrade, ok := parseDegrade(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid degrade parameter"})
+			return
+		}
+
+		// Valida o idioma da descrição do tempo
+		lang, ok := parseWeatherLang(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid lang parameter"})
+			return
+		}
+
+		// Valida a quantidade de dias de previsão
+		forecastDays, ok := parseForecastDays(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid forecast parameter"})
+			return
+		}
+
+		// Valida a hora da previsão
+		forecastHour, ok := parseForecastHour(r)
+		if !ok {
+			writeError(w, format, http.StatusBadRequest, ErrorResponse{Message: "invalid hour parameter"})
+			return
+		}
+
+		// Valida o formato antes de consultar o resolver
+		if detail := cepValidationDetail(cep); detail != "" {
+			writeError(w, format, http.StatusUnprocessableEntity, ErrorResponse{Message: "invalid zipcode", Detail: detail})
+			return
+		}
+
+		// Limita o tempo total gasto com os upstreams; a desconexão do cliente
+		// também cancela as consultas em andamento
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		// Busca os dados do CEP
+		setCacheKeyHeader(ctx, w, cep, "", "", "")
+		cepData, cepErr := cepResolver.ResolveCEP(ctx, cep)
+		// As sugestões só existem no formato JSON
+		if cepErr != nil && cepErr.Code == http.StatusNotFound && suggest && format == formatJSON {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(cepErr.Code)
+			json.NewEncoder(w).Encode(SuggestionsResponse{
+				ErrorResponse: ErrorResponse{Message: cepErr.Message},
+				Suggestions:   suggestCEPs(ctx, cepResolver, cep),
+			})
+			return
+		}
+		if cepErr != nil {
+			writeError(w, format, cepErr.Code, ErrorResponse{Message: cepErr.Message, Detail: cepErr.Detail})
+			return
+		}
+
+		// Alguns CEPs especiais voltam sem cidade ou UF; consultar a temperatura
+		// com esses dados geraria uma busca sem sentido no provedor
+		if !hasCompleteAddress(cepData) {
+			logger.WarnContext(ctx, "endereço incompleto", "cep", formatCEP(cep), "city", cepData.Localidade, "state", cepData.UF)
+			writeError(w, format, http.StatusBadGateway, ErrorResponse{Message: "incomplete address data"})
+			return
+		}
+
+		// Busca dados climáticos, com a descrição no idioma solicitado
+		setCacheKeyHeader(ctx, w, cep, cepData.Localidade, cepData.UF, lang)
+		weatherData, weatherErr := weatherResolver.ResolveWeather(withWeatherLang(ctx, lang), cepData.Localidade, cepData.UF)
+		// Com a degradação habilitada o endereço é devolvido mesmo sem a
+		// temperatura; a resposta parcial só existe no formato JSON
+		if weatherErr != nil && degrade && format == formatJSON {
+			logger.WarnContext(ctx, "temperatura indisponível, respondendo apenas o endereço", "cep", formatCEP(cep), "error", weatherErr.Message)
+			writeDegraded(w, cepData, weatherErr)
+			return
+		}
+		if weatherErr != nil {
+			writeError(w, format, weatherErr.Code, ErrorResponse{Message: weatherErr.Message})
+			return
+		}
+
+		response := WeatherResponse{
+			WeatherData: *weatherData,
+			Coordinates: locateCEP(ctx, cepData),
+			Address:     buildAddress(addressMode, cepData),
+		}
+		if forecastDays > 0 {
+			response.Forecast = limitForecast(weatherData.ForecastDays, forecastDays)
+		}
+		if forecastHour != noForecastHour {
+			response.HourForecast = weatherData.Details.hourForecast(cepData.UF, forecastHour, time.Now())
+		}
+		if verbose {
+			response.WeatherDetails = weatherData.Details.localize(cepData.UF, weatherData.Alerts, time.Now()).withConfidence(response.Coordinates)
+			response.FormattedAddress = formatAddress(cepData)
+		}
+		if echo {
+			response.Echo = &RequestEcho{
+				CEP:      formatCEP(cep),
+				Address:  addressMode,
+				Units:    unitsMode,
+				Verbose:  verbose,
+				Lang:     lang,
+				Forecast: forecastDays,
+				Format:   format,
+			}
+		}
+
+		// Retorna os dados de temperatura e o endereço em caso de sucesso; se
+		// o cliente já tiver a mesma representação, responde 304 sem corpo.
+		// Last-Modified é o momento da consulta ao provedor, e não o da
+		// resposta, para que acertos no cache não pareçam dados novos.
+		body := renderWeatherResponse(response, unitsMode)
+		etag := weatherETag(format, body)
+		w.Header().Set("ETag", etag)
+		setCacheHeaders(w, weatherData.FetchedAt)
+		if etagMatches(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeWeather(w, format, body, response.WeatherData)
+	}
 }
 
-// weatherByCEPHandler lida com as requisições GET para /weatherbycep/{cep}
-func weatherByCEPHandler(w http.ResponseWriter, r *http.Request) {
-	// Verifica se é um GET
-	if r.Method != http.MethodGet {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
-		return
-	}
-
-	// Extrai o CEP do path da URL
-	// Remove o prefixo "/weatherbycep/" para obter o CEP
-	path := r.URL.Path
-	if !strings.HasPrefix(path, "/weatherbycep/") {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "endpoint not found"})
-		return
-	}
-
-	cep := strings.TrimPrefix(path, "/weatherbycep/")
-	if cep == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "cep parameter is required"})
-		return
-	}
-
-	// Busca os dados do CEP
-	cepData, cepErr := searchCEP(cep)
-	if cepErr != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(cepErr.Code)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: cepErr.Message})
-		return
-	}
-
-	// Busca dados climáticos
-	weather, weatherErr := getWeatherData(cepData.Localidade, cepData.UF)
-	if weatherErr != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(weatherErr.Code)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: weatherErr.Message})
-		return
-	}
-
-	// Retorna os dados de temperatura em caso de sucesso
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(weather)
+// defaultPort é a porta usada quando PORT não está definida
+const defaultPort = "8080"
+
+// resolvePort lê a porta do servidor de PORT, usada por plataformas como
+// Cloud Run e Heroku, e retorna o endereço no formato ":porta"
+func resolvePort() (string, error) {
+	port := strings.TrimSpace(os.Getenv("PORT"))
+	if port == "" {
+		port = defaultPort
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid PORT %q: must be a number between 1 and 65535", port)
+	}
+	return ":" + strconv.Itoa(n), nil
 }
 
 func main() {
-	// Configura o handler para o endpoint /weatherbycep/{cep}
-	http.HandleFunc("/weatherbycep/", weatherByCEPHandler)
+	cep := flag.String("cep", "", "consulta um único CEP, imprime o JSON em stdout e encerra sem iniciar o servidor")
+	flag.Parse()
+
+	// Logs estruturados em JSON, inclusive os emitidos pelo pacote slog padrão,
+	// a partir do nível de LOG_LEVEL.
+	// No modo de linha de comando os logs vão para stderr, deixando stdout só com a resposta
+	logOutput := os.Stdout
+	if *cep != "" {
+		logOutput = os.Stderr
+	}
+	logger = newJSONLogger(logOutput, parseLogLevel(os.Getenv("LOG_LEVEL")))
+	slog.SetDefault(logger)
+
+	// A política de resolução (RESOLUTION_POLICY ou RESOLUTION_POLICY_FILE)
+	// define a ordem dos provedores, os prazos, as tentativas, os TTLs e o
+	// circuit breaker; inválida, o servidor não inicia
+	policy, err := loadResolutionPolicyFromEnv()
+	if err != nil {
+		logger.Error("configuração inválida", "error", err)
+		os.Exit(1)
+	}
+	if policy != nil {
+		logger.Info("política de resolução carregada", "cep_providers", policy.CEPProviders, "weather_providers", policy.WeatherProviders)
+	}
+	policy.applyConfig(&config)
+
+	upstreamCEPResolver := policy.cepResolver()
+	upstreamWeatherResolver := policy.weatherResolver(addressGeocoder)
+
+	// No modo offline (MOCK_MODE) o CEP e a temperatura vêm de resolvers
+	// simulados; as demais chamadas aos upstreams são respondidas pelo
+	// transporte simulado, dentro da mesma cadeia de limite e métricas
+	mockMode := envBool("MOCK_MODE")
+	if mockMode {
+		logger.Warn("modo offline ativo: CEP e temperatura são dados de exemplo (MOCK_MODE)")
+		upstreamCEPResolver = mockCEPResolver{}
+		upstreamWeatherResolver = mockWeatherResolver{}
+		httpClient.Transport = newUpstreamTransport(mockTransport{})
+	}
+
+	cepCache := newCEPCacheFromEnv()
+	weatherCache := newWeatherCacheFromEnv()
+	notFoundCache := newNotFoundCacheFromEnv()
+	coordCache := newCoordWeatherCacheFromEnv()
+	policy.applyCaches(cepCache, weatherCache)
+	cepResolver := cachingCEPResolver{
+		cache:    cepCache,
+		notFound: notFoundCache,
+		next:     upstreamCEPResolver,
+	}
+	weatherResolver := cachingWeatherResolver{
+		cache:   weatherCache,
+		next:    upstreamWeatherResolver,
+		refresh: newStaleRefresherFromEnv(),
+	}
+
+	// Coordenadas da localidade incluídas nas respostas, em cache por código IBGE
+	localityGeocoder = newCachingGeocoder(addressLocalityGeocoder{geocoder: addressGeocoder})
+
+	// Circuit breakers que interrompem as chamadas a upstreams com falhas
+	// consecutivas, configurados antes do modo de linha de comando para que
+	// ele consulte os upstreams com as mesmas proteções do servidor
+	viaCEPBreaker = policy.circuitBreaker(providerViaCEP)
+	wttrBreaker = policy.circuitBreaker(providerWttr)
+	geocoderBreaker = policy.circuitBreaker(providerGeocoder)
+
+	if *cep != "" {
+		os.Exit(lookupCEP(context.Background(), os.Stdout, os.Stderr, cepResolver, weatherResolver, *cep))
+	}
+
+	// Traces exportados via OTLP quando configurado; sem endpoint os spans são descartados
+	shutdownTracing, err := initTracing(context.Background())
+	if err != nil {
+		logger.Error("erro ao configurar o exportador de traces", "error", err)
+		os.Exit(1)
+	}
+
+	// Configura os handlers; os endpoints que dependem dos upstreams
+	// passam pelo modo de manutenção, pelo limite de corpo, pelo limite de
+	// requisições e pelo descarte de carga, têm um prazo total, recebem
+	// Retry-After nas respostas 5xx, são contabilizados nas métricas e usam
+	// o namespace de cache do inquilino
+	registerMetrics(prometheus.DefaultRegisterer)
+	registerCacheMetrics(prometheus.DefaultRegisterer, "cep", cepCache)
+	registerCacheMetrics(prometheus.DefaultRegisterer, "weather", weatherCache)
+	registerCacheMetrics(prometheus.DefaultRegisterer, "weather_coords", coordCache)
+	if notFoundCache != nil {
+		registerCacheMetrics(prometheus.DefaultRegisterer, "cep_not_found", notFoundCache)
+	}
+	maintenance := newMaintenanceModeFromEnv()
+	limiter := newRateLimiterFromEnv()
+	shedder := newLoadShedderFromEnv()
+	retryAfter := newErrorRetryAfterFromEnv()
+	timeout := newRequestTimeoutFromEnv()
+	bodyLimit := newBodyLimitFromEnv()
+	namespace := newCacheNamespaceFromEnv()
+	dataHandler := func(h http.HandlerFunc) http.Handler {
+		return Chain(h,
+			handlerFuncMiddleware(instrumentRequests),
+			handlerFuncMiddleware(retryAfter.Wrap),
+			handlerFuncMiddleware(maintenance.Wrap),
+			handlerFuncMiddleware(bodyLimit.Wrap),
+			handlerFuncMiddleware(limiter.Wrap),
+			handlerFuncMiddleware(shedder.Wrap),
+			handlerFuncMiddleware(timeout.Wrap),
+			handlerFuncMiddleware(namespace.Wrap),
+		)
+	}
+	weatherHandler := NewWeatherHandler(cepResolver, weatherResolver)
+	http.Handle("/weatherbycep", dataHandler(weatherHandler))
+	http.Handle("/weatherbycep/", dataHandler(weatherHandler))
+	http.Handle("/weatherbycep/batch", dataHandler(NewBatchWeatherHandler(cepResolver, weatherResolver)))
+	http.Handle("/cep/", dataHandler(NewCEPHandler(cepResolver)))
+	http.Handle("/cepsearch", dataHandler(cepSearchHandler))
+	http.Handle("/weatherbycity", dataHandler(NewCityWeatherHandler(weatherResolver)))
+	http.Handle("/weatherbyaddress", dataHandler(NewWeatherByAddressHandler(addressGeocoder, weatherResolver)))
+	http.Handle("/weather/bbox", dataHandler(NewBBoxWeatherHandler(coordCache)))
+	http.Handle("/validate/", dataHandler(validateHandler))
+	http.HandleFunc("/ufs", ufsHandler)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/version", versionHandler)
+	readiness := newReadinessChecker()
+	if mockMode {
+		// Sem upstreams não há dependências a verificar
+		readiness.dependencies = nil
+	}
+	http.HandleFunc("/readyz", readiness.readyzHandler)
+	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/rpc", dataHandler(NewRPCHandler(cepResolver, weatherResolver)))
 
 	// Define a porta do servidor
-	port := ":8080"
+	port, err := resolvePort()
+	if err != nil {
+		logger.Error("configuração inválida", "error", err)
+		os.Exit(1)
+	}
 
-	fmt.Printf("🌡️  Servidor iniciado na porta %s\n", port)
-	fmt.Println("📡 Endpoint disponível: GET /weatherbycep/{cep}")
-	fmt.Println("📋 Exemplo de uso: GET /weatherbycep/01310100")
+	// HTTPS direto quando TLS_CERT_FILE e TLS_KEY_FILE estão definidas
+	tlsConfig, err := loadTLSConfigFromEnv()
+	if err != nil {
+		logger.Error("configuração inválida", "error", err)
+		os.Exit(1)
+	}
+
+	// HTTP/2 em texto puro para chamadas internas, quando habilitado; com TLS
+	// o HTTP/2 já é negociado normalmente
+	h2cEnabled := envBool("ENABLE_H2C") && !tlsConfig.Enabled()
+
+	logger.Info("servidor iniciado",
+		"port", port,
+		"tls", tlsConfig.Enabled(),
+		"h2c", h2cEnabled,
+		"endpoints", []string{
+			"GET /weatherbycep/{cep}",
+			"GET /weatherbycep?cep={cep}",
+			"POST /weatherbycep/batch",
+			"GET /cep/{cep}",
+			"GET /cepsearch?uf={UF}&city={cidade}&street={logradouro}",
+			"GET /weatherbycity?city={cidade}&uf={UF}",
+			"GET /weatherbyaddress?q={endereço}",
+			"GET /weather/bbox?minlat=&minlon=&maxlat=&maxlon=&step=",
+			"GET /ufs",
+			"GET /healthz",
+			"GET /version",
+			"GET /readyz",
+			"GET /metrics",
+			"POST /rpc",
+		},
+	)
 
 	// Inicia o servidor
-	log.Fatal(http.ListenAndServe(port, nil))
+	// Todas as rotas recebem o X-Request-ID, o log de acesso, os cabeçalhos
+	// de CORS e um span por requisição
+	accessLog := newAccessLog(logger)
+	cors := newCORSPolicyFromEnv()
+	traced := func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, "weatherbycep")
+	}
+	server := &http.Server{
+		Addr: port,
+		Handler: Chain(http.DefaultServeMux,
+			handlerFuncMiddleware(withRequestID),
+			handlerFuncMiddleware(accessLog.Wrap),
+			handlerFuncMiddleware(cors.Wrap),
+			traced,
+		),
+	}
+	if h2cEnabled {
+		server.Handler = withH2C(server.Handler)
+	}
+	if err := serve(server, tlsConfig); err != nil {
+		logger.Error("servidor encerrado", "error", err)
+		shutdownTracing(context.Background())
+		os.Exit(1)
+	}
 }
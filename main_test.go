@@ -1,12 +1,79 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"golang-weatherbycep/weather"
 )
 
+// fakeCEPResolver resolve CEPs a partir de um mapa em memória, sem acessar a rede
+type fakeCEPResolver struct {
+	mu    sync.Mutex
+	data  map[string]*CEPData
+	err   *CustomError
+	calls int
+}
+
+func (f *fakeCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	if data, ok := f.data[formatCEP(cep)]; ok {
+		return data, nil
+	}
+	return nil, &CustomError{Code: 404, Message: "can not find zipcode", Err: weather.ErrCEPNotFound}
+}
+
+// fakeWeatherResolver retorna uma temperatura fixa ou um erro configurado
+type fakeWeatherResolver struct {
+	mu    sync.Mutex
+	tempC float64
+	err   *CustomError
+	calls int
+	// city e state guardam a última localidade consultada
+	city, state string
+	// fetchedAt é o momento da consulta informado nos dados
+	fetchedAt time.Time
+}
+
+func (f *fakeWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	f.mu.Lock()
+	f.calls++
+	f.city, f.state = city, state
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	weatherData := weatherFromCelsius(f.tempC)
+	weatherData.FetchedAt = f.fetchedAt
+	return &weatherData, nil
+}
+
+// newFakeCEPResolver cria um resolver com o CEP da Avenida Paulista
+func newFakeCEPResolver() *fakeCEPResolver {
+	return &fakeCEPResolver{data: map[string]*CEPData{
+		"01310100": {CEP: "01310-100", Logradouro: "Avenida Paulista", Bairro: "Bela Vista", Localidade: "São Paulo", UF: "SP"},
+	}}
+}
+
 func TestWeatherByCEPHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -38,11 +105,18 @@ func TestWeatherByCEPHandler(t *testing.T) {
 		},
 		{
 			name:           "CEP não encontrado",
-			path:           "/weatherbycep/00000000",
+			path:           "/weatherbycep/99999999",
 			method:         "GET",
 			expectedStatus: http.StatusNotFound,
 			expectedMsg:    "can not find zipcode",
 		},
+		{
+			name:           "CEP fora da faixa atribuída",
+			path:           "/weatherbycep/00000000",
+			method:         "GET",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedMsg:    "invalid zipcode",
+		},
 		{
 			name:           "CEP não fornecido",
 			path:           "/weatherbycep/",
@@ -66,6 +140,8 @@ func TestWeatherByCEPHandler(t *testing.T) {
 		},
 	}
 
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req, err := http.NewRequest(tt.method, tt.path, nil)
@@ -74,8 +150,6 @@ func TestWeatherByCEPHandler(t *testing.T) {
 			}
 
 			rr := httptest.NewRecorder()
-			handler := http.HandlerFunc(weatherByCEPHandler)
-
 			handler.ServeHTTP(rr, req)
 
 			if status := rr.Code; status != tt.expectedStatus {
@@ -85,28 +159,28 @@ func TestWeatherByCEPHandler(t *testing.T) {
 
 			if tt.expectedStatus == http.StatusOK {
 				// Verifica se a resposta é um JSON válido com dados de temperatura
-				var weather WeatherData
-				if err := json.Unmarshal(rr.Body.Bytes(), &weather); err != nil {
+				var weatherData WeatherData
+				if err := json.Unmarshal(rr.Body.Bytes(), &weatherData); err != nil {
 					t.Errorf("Resposta não é um JSON válido: %v", err)
 				}
 
 				// Verifica se os campos de temperatura estão presentes
-				if weather.TempC == 0 && weather.TempF == 0 && weather.TempK == 0 {
+				if weatherData.TempC == 0 && weatherData.TempF == 0 && weatherData.TempK == 0 {
 					t.Errorf("Dados de temperatura não encontrados na resposta")
 				}
 
-				// Verifica conversões de temperatura
-				expectedTempF := (weather.TempC * 9 / 5) + 32
-				expectedTempK := weather.TempC + 273.15
+				// Verifica conversões de temperatura, arredondadas como na resposta
+				expectedTempF := round((weatherData.TempC*9/5)+32, temperaturePrecision)
+				expectedTempK := round(weatherData.TempC+273.15, temperaturePrecision)
 
-				if weather.TempF != expectedTempF {
+				if weatherData.TempF != expectedTempF {
 					t.Errorf("Conversão Fahrenheit incorreta: got %v want %v",
-						weather.TempF, expectedTempF)
+						weatherData.TempF, expectedTempF)
 				}
 
-				if weather.TempK != expectedTempK {
+				if weatherData.TempK != expectedTempK {
 					t.Errorf("Conversão Kelvin incorreta: got %v want %v",
-						weather.TempK, expectedTempK)
+						weatherData.TempK, expectedTempK)
 				}
 			} else if tt.expectedMsg != "" {
 				// Verifica mensagem de erro
@@ -124,6 +198,141 @@ func TestWeatherByCEPHandler(t *testing.T) {
 	}
 }
 
+func TestWeatherByCEPHandlerUpstreamErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		cepResolver     *fakeCEPResolver
+		weatherResolver *fakeWeatherResolver
+		expectedStatus  int
+		expectedMsg     string
+		weatherCalls    int
+	}{
+		{
+			name:            "Falha no ViaCEP",
+			cepResolver:     &fakeCEPResolver{err: &CustomError{Code: 500, Message: "internal server error"}},
+			weatherResolver: &fakeWeatherResolver{tempC: 23},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedMsg:     "internal server error",
+			weatherCalls:    0,
+		},
+		{
+			name:            "Falha no wttr.in",
+			cepResolver:     newFakeCEPResolver(),
+			weatherResolver: &fakeWeatherResolver{err: &CustomError{Code: 500, Message: "weather data not available"}},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedMsg:     "weather data not available",
+			weatherCalls:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWeatherHandler(tt.cepResolver, tt.weatherResolver)
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+			}
+			if errorResp.Message != tt.expectedMsg {
+				t.Errorf("Mensagem de erro incorreta: got %v want %v", errorResp.Message, tt.expectedMsg)
+			}
+			if tt.weatherResolver.calls != tt.weatherCalls {
+				t.Errorf("resolver de temperatura chamado %d vezes, want %d", tt.weatherResolver.calls, tt.weatherCalls)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerIncompleteAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		cepData *CEPData
+	}{
+		{"UF vazia", &CEPData{CEP: "01310-100", Localidade: "São Paulo", UF: ""}},
+		{"UF desconhecida", &CEPData{CEP: "01310-100", Localidade: "São Paulo", UF: "XX"}},
+		{"Cidade vazia", &CEPData{CEP: "01310-100", Localidade: " ", UF: "SP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherResolver := &fakeWeatherResolver{tempC: 20}
+			cepResolver := &fakeCEPResolver{data: map[string]*CEPData{"01310100": tt.cepData}}
+
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(cepResolver, weatherResolver)(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+			if rr.Code != http.StatusBadGateway {
+				t.Fatalf("status = %d, want 502 (%s)", rr.Code, rr.Body.String())
+			}
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatal(err)
+			}
+			if errorResp.Message != "incomplete address data" {
+				t.Errorf("message = %q, want %q", errorResp.Message, "incomplete address data")
+			}
+			if weatherResolver.calls != 0 {
+				t.Errorf("temperatura consultada %d vezes, want 0", weatherResolver.calls)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerInvalidCEPSkipsResolver(t *testing.T) {
+	cepResolver := newFakeCEPResolver()
+	handler := NewWeatherHandler(cepResolver, &fakeWeatherResolver{tempC: 23})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/123", nil))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if cepResolver.calls != 0 {
+		t.Errorf("CEP inválido não deveria chegar ao resolver: %d chamadas", cepResolver.calls)
+	}
+}
+
+func TestResolvePort(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     string
+		expected string
+		wantErr  bool
+	}{
+		{"Sem PORT", "", ":8080", false},
+		{"Porta definida", "3000", ":3000", false},
+		{"Com espaços", " 9090 ", ":9090", false},
+		{"Menor porta", "1", ":1", false},
+		{"Maior porta", "65535", ":65535", false},
+		{"Zero", "0", "", true},
+		{"Acima do limite", "65536", "", true},
+		{"Negativa", "-80", "", true},
+		{"Não numérica", "http", "", true},
+		{"Com dois pontos", ":8080", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", tt.port)
+
+			port, err := resolvePort()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolvePort() erro = %v, wantErr %v", err, tt.wantErr)
+			}
+			if port != tt.expected {
+				t.Errorf("resolvePort() = %q, want %q", port, tt.expected)
+			}
+		})
+	}
+}
+
 func TestIsValidCEP(t *testing.T) {
 	tests := []struct {
 		cep      string
@@ -137,7 +346,13 @@ func TestIsValidCEP(t *testing.T) {
 		{"abcd1234", false},
 		{"", false},
 		{"123-456", false},
-		{"12.345.678", false},
+		{"12.345.678", true},
+		{"123abc456", false},
+		{"00000000", false},
+		{"00000-000", false},
+		{"00999999", false},
+		{"01000000", true},
+		{"99999999", true},
 	}
 
 	for _, tt := range tests {
@@ -150,6 +365,71 @@ func TestIsValidCEP(t *testing.T) {
 	}
 }
 
+func TestCEPValidationDetail(t *testing.T) {
+	tests := []struct {
+		cep      string
+		expected string
+	}{
+		{"01310100", ""},
+		{"01310-100", ""},
+		{"123", `cep "123" must have 8 digits, got 3`},
+		{"1234567890", `cep "1234567890" must have 8 digits, got 10`},
+		{"123-456", `cep "123-456" must have 8 digits, got 6`},
+		{"abcd1234", `cep "abcd1234" must contain only digits`},
+		{"12.345.678", ""},
+		{"01310/100", ""},
+		{"123abc456", `cep "123abc456" must contain only digits`},
+		{"01310abc100", `cep "01310abc100" must contain only digits`},
+		{"123.456", `cep "123.456" must have 8 digits, got 6`},
+		{"00000000", `cep "00000000" is outside the assigned range`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cep, func(t *testing.T) {
+			if detail := cepValidationDetail(tt.cep); detail != tt.expected {
+				t.Errorf("cepValidationDetail(%s) = %q, want %q", tt.cep, detail, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerInvalidCEPDetail(t *testing.T) {
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedMsg    string
+		expectedDetail string
+	}{
+		{"/weatherbycep/123", http.StatusUnprocessableEntity, "invalid zipcode", `cep "123" must have 8 digits, got 3`},
+		{"/weatherbycep/0131a100", http.StatusUnprocessableEntity, "invalid zipcode", `cep "0131a100" must contain only digits`},
+		{"/weatherbycep/", http.StatusBadRequest, "cep parameter is required", ""},
+	}
+
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+			}
+			if errorResp.Message != tt.expectedMsg {
+				t.Errorf("message = %q, want %q", errorResp.Message, tt.expectedMsg)
+			}
+			if errorResp.Detail != tt.expectedDetail {
+				t.Errorf("detail = %q, want %q", errorResp.Detail, tt.expectedDetail)
+			}
+		})
+	}
+}
+
 func TestFormatCEP(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -160,25 +440,604 @@ func TestFormatCEP(t *testing.T) {
 		{"01310100", "01310100"},
 		{"123-45-678", "12345678"},
 		{"12 34 56 78", "12345678"},
+		{"01310.100", "01310100"},
+		{"01.310-100", "01310100"},
+		{"01310/100", "01310100"},
+		{"\t01310-100\n", "01310100"},
+		{"01310\u00a0100", "01310100"},
+		{"\u200301310100\u3000", "01310100"},
+		{"123abc456", "123abc456"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
 			result := formatCEP(tt.input)
 			if result != tt.expected {
-				t.Errorf("formatCEP(%s) = %s, want %s", tt.input, result, tt.expected)
+				t.Errorf("formatCEP(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		val      float64
+		places   int
+		expected float64
+	}{
+		{"Ruído de ponto flutuante", 73.39999999999999, 1, 73.4},
+		{"Kelvin com uma casa", 23 + 273.15, 1, 296.2},
+		{"Kelvin com duas casas", 23 + 273.15, 2, 296.15},
+		{"Sem casas decimais", 71.6, 0, 72},
+		{"Negativo", -1.25, 1, -1.3},
+		{"Negativo arredondando para cima", -40.04, 1, -40},
+		{"Zero", 0, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := round(tt.val, tt.places); got != tt.expected {
+				t.Errorf("round(%v, %d) = %v, want %v", tt.val, tt.places, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewTemperaturePrecisionFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", defaultTemperaturePrecision},
+		{"0", 0},
+		{"3", 3},
+		{"6", maxTemperaturePrecision},
+		{"400", maxTemperaturePrecision},
+		{"-1", defaultTemperaturePrecision},
+		{"duas", defaultTemperaturePrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TEMPERATURE_PRECISION", tt.value)
+			if got := newTemperaturePrecisionFromEnv(); got != tt.expected {
+				t.Errorf("newTemperaturePrecisionFromEnv() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatTemperaturesFollowsPrecision(t *testing.T) {
+	tests := []struct {
+		precision int
+		expected  string
+	}{
+		{0, "23C / 73F / 296K"},
+		{1, "23.0C / 73.4F / 296.2K"},
+		{2, "23.00C / 73.40F / 296.15K"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.precision), func(t *testing.T) {
+			previous := temperaturePrecision
+			temperaturePrecision = tt.precision
+			t.Cleanup(func() { temperaturePrecision = previous })
+
+			if got := formatTemperatures(weatherFromCelsius(23)); got != tt.expected {
+				t.Errorf("formatTemperatures() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// parseWttr decodifica uma resposta j1 do wttr.in como o servidor faz após a
+// consulta: pela biblioteca e convertida por weatherFromProvider
+func parseWttr(t *testing.T, body []byte) *WeatherData {
+	t.Helper()
+	data, err := weather.ParseWttrJSON(body)
+	if err != nil {
+		t.Fatalf("ParseWttrJSON retornou erro: %v", err)
+	}
+	return weatherFromProvider(data)
+}
+
+func TestParseWttrCurrentCondition(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "wttr", "01310100.json"))
+	if err != nil {
+		t.Fatalf("erro ao ler fixture: %v", err)
+	}
+
+	weatherData := parseWttr(t, body)
+	if weatherData.TempC != 22 || weatherData.TempF != 71.6 || weatherData.TempK != 295.2 {
+		t.Errorf("temperaturas incorretas: %+v", weatherData)
+	}
+	if weatherData.Humidity != 73 {
+		t.Errorf("humidity incorreta: got %v want 73", weatherData.Humidity)
+	}
+	if weatherData.FeelsLikeC != 24 {
+		t.Errorf("feels_like_C incorreta: got %v want 24", weatherData.FeelsLikeC)
+	}
+	if weatherData.Description != "Partly cloudy" {
+		t.Errorf("description incorreta: got %q want %q", weatherData.Description, "Partly cloudy")
+	}
+
+	// Campos complementares ausentes não invalidam a resposta
+	weatherData = parseWttr(t, []byte(`{"current_condition":[{"temp_C":"18"}]}`))
+	if weatherData.Humidity != 0 || weatherData.FeelsLikeC != 0 || weatherData.Description != "" {
+		t.Errorf("campos ausentes deveriam ficar vazios: %+v", weatherData)
+	}
+}
+
+func TestBuildAddress(t *testing.T) {
+	cepData := &CEPData{
+		CEP:        "01310-100",
+		Logradouro: "Avenida Paulista",
+		Bairro:     "Bela Vista",
+		Localidade: "São Paulo",
+		UF:         "SP",
+		IBGE:       "3550308",
+	}
+
+	tests := []struct {
+		mode           string
+		expectedFields []string
+	}{
+		{addressModeMin, []string{"cep", "localidade", "uf"}},
+		{addressModeFull, []string{"cep", "logradouro", "complemento", "bairro", "localidade", "uf", "ibge", "gia", "ddd", "siafi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			body, err := json.Marshal(buildAddress(tt.mode, cepData))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fields) != len(tt.expectedFields) {
+				t.Errorf("buildAddress(%s) retornou %d campos, want %d: %v",
+					tt.mode, len(fields), len(tt.expectedFields), fields)
+			}
+			for _, field := range tt.expectedFields {
+				if _, ok := fields[field]; !ok {
+					t.Errorf("buildAddress(%s) sem o campo %s", tt.mode, field)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAddressMode(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+		valid    bool
+	}{
+		{"", addressModeMin, true},
+		{"?address=min", addressModeMin, true},
+		{"?address=full", addressModeFull, true},
+		{"?address=compact", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil)
+			mode, ok := parseAddressMode(req)
+			if mode != tt.expected || ok != tt.valid {
+				t.Errorf("parseAddressMode(%s) = (%s, %v), want (%s, %v)",
+					tt.query, mode, ok, tt.expected, tt.valid)
+			}
+		})
+	}
+}
+
+// cancelAwareTransport registra as URLs requisitadas e falha quando o contexto
+// da requisição já foi cancelado, como faz o transporte real
+type cancelAwareTransport struct {
+	urls []string
+}
+
+func (ct *cancelAwareTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.urls = append(ct.urls, req.URL.String())
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("conexão recusada")
+}
+
+func TestSearchCEPKnownInvalidSkipsUpstream(t *testing.T) {
+	calls := 0
+	original := httpClient.Transport
+	httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("não deveria consultar o upstream")
+	})
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	for _, cep := range []string{"99999999", "99999-999"} {
+		_, cepErr := searchCEP(context.Background(), cep)
+		if cepErr == nil || cepErr.Code != http.StatusNotFound || cepErr.Message != "can not find zipcode" {
+			t.Errorf("searchCEP(%s) erro = %v, want 404 can not find zipcode", cep, cepErr)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("upstream consultado %d vezes, want 0", calls)
+	}
+}
+
+func TestSearchCEPInsecureFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		allowFallback bool
+		expectErr     bool
+		expectHTTP    bool
+	}{
+		{"Fallback desativado", false, true, false},
+		{"Fallback permitido", true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRetryBaseDelay(t, 0)
+			useConfig(t, Config{ViaCEPBaseURL: defaultViaCEPBaseURL, AllowInsecureFallback: tt.allowFallback})
+
+			var mu sync.Mutex
+			var urls []string
+			original := httpClient.Transport
+			httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				urls = append(urls, req.URL.String())
+				mu.Unlock()
+				if req.URL.Scheme == "https" {
+					return nil, errors.New("tls: handshake failure")
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"cep": "01310-100", "localidade": "São Paulo", "uf": "SP"}`)),
+					Header:     make(http.Header),
+				}, nil
+			})
+			t.Cleanup(func() { httpClient.Transport = original })
+
+			cepData, cepErr := searchCEP(context.Background(), "01310100")
+			if (cepErr != nil) != tt.expectErr {
+				t.Fatalf("searchCEP erro = %v, expectErr %v", cepErr, tt.expectErr)
+			}
+			if cepErr != nil && cepErr.Code < 500 {
+				t.Errorf("status = %d, want 5xx", cepErr.Code)
+			}
+			if cepErr == nil && cepData.Localidade != "São Paulo" {
+				t.Errorf("localidade = %q, want São Paulo", cepData.Localidade)
+			}
+
+			triedHTTP := false
+			for _, u := range urls {
+				if strings.HasPrefix(u, "http://") {
+					triedHTTP = true
+				}
+			}
+			if triedHTTP != tt.expectHTTP {
+				t.Errorf("fallback HTTP tentado = %v, want %v (%v)", triedHTTP, tt.expectHTTP, urls)
 			}
 		})
 	}
 }
 
-// Benchmark para testar performance
+func TestSearchCEPSkipsFallbackWhenContextDone(t *testing.T) {
+	transport := &cancelAwareTransport{}
+	useConfig(t, Config{ViaCEPBaseURL: defaultViaCEPBaseURL, AllowInsecureFallback: true})
+	original := httpClient.Transport
+	httpClient.Transport = transport
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, cepErr := searchCEP(ctx, "01310100")
+	if cepErr == nil {
+		t.Fatal("searchCEP com contexto cancelado deveria retornar erro")
+	}
+
+	for _, u := range transport.urls {
+		if strings.HasPrefix(u, "http://") {
+			t.Errorf("fallback HTTP não deveria ser tentado com contexto cancelado: %s", u)
+		}
+	}
+	if len(transport.urls) > 1 {
+		t.Errorf("esperada no máximo 1 tentativa, got %d: %v", len(transport.urls), transport.urls)
+	}
+}
+
+// blockingTransport simula um upstream lento: só retorna quando o contexto
+// da requisição termina
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestWeatherByCEPHandlerContextCancellation(t *testing.T) {
+	original := httpClient.Transport
+	httpClient.Transport = blockingTransport{}
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		ctx            func() context.Context
+		expectedStatus int
+	}{
+		{
+			name:           "Cliente já desconectado",
+			timeout:        time.Minute,
+			expectedStatus: http.StatusInternalServerError,
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+		},
+		{
+			name:           "Prazo total esgotado",
+			timeout:        50 * time.Millisecond,
+			ctx:            context.Background,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := handlerTimeout
+			handlerTimeout = tt.timeout
+			t.Cleanup(func() { handlerTimeout = previous })
+
+			// O CEP é resolvido localmente para exercitar também a consulta ao wttr.in
+			handler := NewWeatherHandler(newFakeCEPResolver(), wttrWeatherResolver{})
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil).WithContext(tt.ctx())
+			rr := httptest.NewRecorder()
+
+			start := time.Now()
+			handler(rr, req)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("handler demorou %v para retornar", elapsed)
+			}
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestSearchCEPWithCanceledContextReturnsQuickly(t *testing.T) {
+	original := httpClient.Transport
+	httpClient.Transport = blockingTransport{}
+	t.Cleanup(func() { httpClient.Transport = original })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, cepErr := searchCEP(ctx, "01310100")
+	if cepErr == nil || cepErr.Code != http.StatusInternalServerError {
+		t.Errorf("searchCEP = %v, want erro 500", cepErr)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("searchCEP demorou %v para retornar", elapsed)
+	}
+}
+
+// useDiscardLogger descarta os logs durante o benchmark, para não medir a escrita em stderr
+func useDiscardLogger(b *testing.B) {
+	b.Helper()
+	previous := logger
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	b.Cleanup(func() { logger = previous })
+}
+
+// Benchmark para testar performance do handler, sem acesso à rede
 func BenchmarkWeatherByCEPHandler(b *testing.B) {
-	req, _ := http.NewRequest("GET", "/weatherbycep/01310100", nil)
+	useDiscardLogger(b)
+	req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 22})
 
+	b.ReportAllocs()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(weatherByCEPHandler)
 		handler.ServeHTTP(rr, req)
 	}
 }
+
+// BenchmarkHandlerParallel mede o roteamento e a serialização sob
+// concorrência, passando pelos caches compartilhados de CEP e de temperatura
+// como em produção
+func BenchmarkHandlerParallel(b *testing.B) {
+	useDiscardLogger(b)
+	cepCache := NewCEPCache(time.Hour)
+	weatherCache := NewWeatherCache(time.Hour)
+	b.Cleanup(func() {
+		cepCache.Stop()
+		weatherCache.Stop()
+	})
+
+	handler := NewWeatherHandler(
+		cachingCEPResolver{cache: cepCache, next: newFakeCEPResolver()},
+		cachingWeatherResolver{cache: weatherCache, next: &fakeWeatherResolver{tempC: 22}},
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+			if rr.Code != http.StatusOK {
+				b.Errorf("status = %d, want 200", rr.Code)
+				return
+			}
+		}
+	})
+}
+
+func TestGetWeatherDataTextFallback(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		text           string
+		expectedTempC  float64
+		expectedCode   int
+		expectFallback bool
+	}{
+		{"j1 retorna HTML", "text/html; charset=utf-8", "<html>rate limited</html>", "+23°C", 23, 0, true},
+		{"HTML sem Content-Type", "", "  <!DOCTYPE html><p>limite</p>", "-1.5°C", -1.5, 0, true},
+		{"Texto também inválido", "text/html", "<html></html>", "Unknown location", 0, 500, true},
+		{"j1 retorna JSON", "application/json", `{"current_condition": [{"temp_C": "18"}]}`, "+99°C", 18, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var textRequests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("format") == "%t" {
+					textRequests++
+					w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+					w.Write([]byte(tt.text))
+					return
+				}
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			useConfig(t, Config{WeatherBaseURL: server.URL})
+
+			weatherData, err := getWeatherData(context.Background(), "São Paulo", "SP")
+
+			if tt.expectFallback != (textRequests == 1) {
+				t.Errorf("requisições ao formato texto = %d, fallback esperado = %v", textRequests, tt.expectFallback)
+			}
+			if tt.expectedCode != 0 {
+				if err == nil || err.Code != tt.expectedCode {
+					t.Fatalf("erro = %v, want código %d", err, tt.expectedCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getWeatherData retornou erro: %v", err)
+			}
+			if weatherData.TempC != tt.expectedTempC || weatherData.TempK != round(tt.expectedTempC+273.15, temperaturePrecision) {
+				t.Errorf("temperatura = %+v, want %v°C", weatherData, tt.expectedTempC)
+			}
+		})
+	}
+}
+
+func TestCEPFromPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"CEP simples", "/weatherbycep/01310100", "01310100"},
+		{"Espaço codificado no fim", "/weatherbycep/01310100%20", "01310100"},
+		{"Barra final", "/weatherbycep/01310100/", "01310100"},
+		{"Espaço codificado e barra final", "/weatherbycep/%2001310-100%20/", "01310-100"},
+		{"Espaço interno", "/weatherbycep/01310%20100", "01310 100"},
+		{"Barra codificada", "/weatherbycep/01310%2F100", "01310/100"},
+		{"Apenas espaços", "/weatherbycep/%20%20", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cepFromPath(httptest.NewRequest("GET", tt.path, nil), "/weatherbycep/"); got != tt.expected {
+				t.Errorf("cepFromPath(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerDecodesPath(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"Espaço codificado", "/weatherbycep/01310100%20", http.StatusOK},
+		{"Barra final", "/weatherbycep/01310100/", http.StatusOK},
+		{"Espaço interno", "/weatherbycep/01310%20100", http.StatusOK},
+		{"Apenas espaços", "/weatherbycep/%20", http.StatusBadRequest},
+		{"Letras codificadas", "/weatherbycep/013101%41%42", http.StatusUnprocessableEntity},
+		{"Segmento extra", "/weatherbycep/01310100/extra", http.StatusUnprocessableEntity},
+		{"Letras no meio", "/weatherbycep/123abc456%20", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20})(rr, httptest.NewRequest("GET", tt.path, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerQueryParam(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedMsg    string
+		expectedCEP    string
+	}{
+		{"CEP na query", "/weatherbycep?cep=01310100", http.StatusOK, "", "01310-100"},
+		{"CEP na query com barra", "/weatherbycep/?cep=01310-100", http.StatusOK, "", "01310-100"},
+		{"Path prevalece sobre a query", "/weatherbycep/01310100?cep=123", http.StatusOK, "", "01310-100"},
+		{"CEP inválido na query", "/weatherbycep?cep=123", http.StatusUnprocessableEntity, "invalid zipcode", ""},
+		{"CEP ausente", "/weatherbycep", http.StatusBadRequest, "cep parameter is required", ""},
+		{"CEP vazio na query", "/weatherbycep?cep=", http.StatusBadRequest, "cep parameter is required", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cepResolver := newFakeCEPResolver()
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(cepResolver, &fakeWeatherResolver{tempC: 23})(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				var errResp ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil {
+					t.Fatalf("resposta de erro não é um JSON válido: %v", err)
+				}
+				if errResp.Message != tt.expectedMsg {
+					t.Errorf("mensagem = %q, want %q", errResp.Message, tt.expectedMsg)
+				}
+				if cepResolver.calls != 0 {
+					t.Errorf("resolver de CEP chamado %d vezes, want 0", cepResolver.calls)
+				}
+				return
+			}
+
+			var body struct {
+				Address MinAddress `json:"address"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if body.Address.CEP != tt.expectedCEP {
+				t.Errorf("CEP = %q, want %q", body.Address.CEP, tt.expectedCEP)
+			}
+		})
+	}
+}
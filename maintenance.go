@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// defaultMaintenanceRetryAfter é o tempo sugerido, em segundos, para o cliente tentar novamente
+const defaultMaintenanceRetryAfter = 300
+
+// maintenanceMode faz os endpoints de dados responderem 503 durante janelas
+// de manutenção planejada, sem derrubar o processo
+type maintenanceMode struct {
+	enabled    bool
+	retryAfter int
+}
+
+// newMaintenanceModeFromEnv lê MAINTENANCE_MODE e MAINTENANCE_RETRY_AFTER
+func newMaintenanceModeFromEnv() *maintenanceMode {
+	return &maintenanceMode{
+		enabled:    envBool("MAINTENANCE_MODE"),
+		retryAfter: envIntWhere("MAINTENANCE_RETRY_AFTER", defaultMaintenanceRetryAfter, positive[int]),
+	}
+}
+
+// Wrap aplica o modo de manutenção ao handler informado
+func (m *maintenanceMode) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if m.enabled {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(m.retryAfter))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "service under maintenance"})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	maintenance := &maintenanceMode{enabled: true, retryAfter: 120}
+
+	dataEndpoints := []struct {
+		path    string
+		method  string
+		handler http.HandlerFunc
+	}{
+		{"/weatherbycep/01310100", "GET", NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})},
+		{"/weatherbyaddress?q=Av+Paulista", "GET", NewWeatherByAddressHandler(addressGeocoder, wttrWeatherResolver{})},
+		{"/rpc", "POST", NewRPCHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})},
+	}
+
+	for _, ep := range dataEndpoints {
+		t.Run(ep.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			maintenance.Wrap(ep.handler)(rr, httptest.NewRequest(ep.method, ep.path, nil))
+
+			if rr.Code != http.StatusServiceUnavailable {
+				t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+			}
+			if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "120" {
+				t.Errorf("Retry-After incorreto: got %q want %q", retryAfter, "120")
+			}
+
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+			}
+			if errorResp.Message != "service under maintenance" {
+				t.Errorf("Mensagem de erro incorreta: got %v", errorResp.Message)
+			}
+		})
+	}
+
+	// Endpoints que não dependem dos upstreams não são envolvidos pelo modo de manutenção
+	rr := httptest.NewRecorder()
+	ufsHandler(rr, httptest.NewRequest("GET", "/ufs", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("/ufs deveria continuar respondendo 200: got %v", rr.Code)
+	}
+}
+
+func TestMaintenanceModeDisabled(t *testing.T) {
+	maintenance := &maintenanceMode{retryAfter: defaultMaintenanceRetryAfter}
+
+	rr := httptest.NewRecorder()
+	maintenance.Wrap(ufsHandler)(rr, httptest.NewRequest("GET", "/ufs", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestNewMaintenanceModeFromEnv(t *testing.T) {
+	t.Setenv("MAINTENANCE_MODE", "true")
+	t.Setenv("MAINTENANCE_RETRY_AFTER", "60")
+
+	mode := newMaintenanceModeFromEnv()
+	if !mode.enabled || mode.retryAfter != 60 {
+		t.Errorf("newMaintenanceModeFromEnv() = %+v, want enabled com retryAfter 60", mode)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// httpRequestsTotal conta as requisições atendidas pelos endpoints de dados, por status HTTP
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total de requisições HTTP atendidas, por status.",
+	}, []string{"code"})
+
+	// upstreamRequestDuration mede a latência de cada chamada aos upstreams
+	upstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Latência das chamadas aos upstreams, em segundos.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider"})
+)
+
+// upstreamProviders mapeia os hosts dos upstreams configurados para o rótulo usado nas métricas
+var upstreamProviders = map[string]string{
+	hostOf(config.ViaCEPBaseURL):    providerViaCEP,
+	hostOf(config.WeatherBaseURL):   providerWttr,
+	hostOf(config.OpenMeteoBaseURL): providerOpenMeteo,
+	hostOf(config.BrasilAPIBaseURL): providerBrasilAPI,
+}
+
+// registerMetrics registra os coletores da aplicação no registry informado
+func registerMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(httpRequestsTotal, upstreamRequestDuration)
+}
+
+// cacheStatsSource é um cache que contabiliza os seus hits e misses
+type cacheStatsSource interface {
+	Stats() (hits, misses uint64)
+}
+
+// registerCacheMetrics expõe os hits e misses do cache como os contadores
+// {name}_cache_hits_total e {name}_cache_misses_total
+func registerCacheMetrics(reg prometheus.Registerer, name string, cache cacheStatsSource) {
+	reg.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: name + "_cache_hits_total",
+			Help: "Consultas atendidas pelo cache de " + name + ".",
+		}, func() float64 {
+			hits, _ := cache.Stats()
+			return float64(hits)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: name + "_cache_misses_total",
+			Help: "Consultas não encontradas ou expiradas no cache de " + name + ".",
+		}, func() float64 {
+			_, misses := cache.Stats()
+			return float64(misses)
+		}),
+	)
+}
+
+// providerForHost retorna o rótulo do upstream, agrupando hosts desconhecidos
+// em "other" para limitar a cardinalidade
+func providerForHost(host string) string {
+	if provider, ok := upstreamProviders[host]; ok {
+		return provider
+	}
+	return "other"
+}
+
+// metricsTransport registra a latência de cada chamada aos upstreams
+type metricsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	upstreamRequestDuration.WithLabelValues(providerForHost(req.URL.Hostname())).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// instrumentRequests conta as requisições atendidas pelo handler, por status
+func instrumentRequests(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := newStatusCapturingResponseWriter(w)
+		next(recorder, r)
+		httpRequestsTotal.WithLabelValues(strconv.Itoa(recorder.status)).Inc()
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestRegistry cria um registry isolado com os coletores da aplicação
+func newTestRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	registerMetrics(reg)
+	return reg
+}
+
+// gatherValue raspa o registry e retorna o valor do contador ou a quantidade
+// de amostras do histograma com o rótulo informado
+func gatherValue(t *testing.T, reg *prometheus.Registry, name, label, value string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("erro ao raspar as métricas: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if pair.GetName() != label || pair.GetValue() != value {
+					continue
+				}
+				if metric.Histogram != nil {
+					return float64(metric.GetHistogram().GetSampleCount())
+				}
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestInstrumentRequests(t *testing.T) {
+	reg := newTestRegistry(t)
+	handler := instrumentRequests(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23}))
+
+	before200 := gatherValue(t, reg, "http_requests_total", "code", "200")
+	before404 := gatherValue(t, reg, "http_requests_total", "code", "404")
+	before422 := gatherValue(t, reg, "http_requests_total", "code", "422")
+
+	for _, path := range []string{
+		"/weatherbycep/01310100",
+		"/weatherbycep/01310-100",
+		"/weatherbycep/01310100",
+		"/weatherbycep/99999999",
+		"/weatherbycep/123",
+	} {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	tests := []struct {
+		code     string
+		before   float64
+		expected float64
+	}{
+		{"200", before200, 3},
+		{"404", before404, 1},
+		{"422", before422, 1},
+	}
+	for _, tt := range tests {
+		if got := gatherValue(t, reg, "http_requests_total", "code", tt.code) - tt.before; got != tt.expected {
+			t.Errorf("http_requests_total{code=%q} incrementou %v, want %v", tt.code, got, tt.expected)
+		}
+	}
+}
+
+func TestMetricsTransport(t *testing.T) {
+	reg := newTestRegistry(t)
+	transport := &metricsTransport{
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}),
+	}
+
+	tests := []struct {
+		url      string
+		provider string
+	}{
+		{"https://viacep.com.br/ws/01310100/json/", "viacep"},
+		{"https://wttr.in/Sao+Paulo?format=j1", "wttr"},
+		{"https://nominatim.openstreetmap.org/search", "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			before := gatherValue(t, reg, "upstream_request_duration_seconds", "provider", tt.provider)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+
+			after := gatherValue(t, reg, "upstream_request_duration_seconds", "provider", tt.provider)
+			if after-before != 1 {
+				t.Errorf("upstream_request_duration_seconds{provider=%q} registrou %v amostras, want 1", tt.provider, after-before)
+			}
+		})
+	}
+}
+
+// gatherCounter raspa o registry e retorna o valor do contador sem rótulos
+func gatherCounter(t *testing.T, reg *prometheus.Registry, name string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("erro ao raspar as métricas: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) > 0 {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	t.Fatalf("métrica %s não registrada", name)
+	return 0
+}
+
+func TestCacheMetrics(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cepCache := newTestCEPCache(t, time.Hour, &now)
+	weatherCache := newTestWeatherCache(t, time.Hour, &now)
+
+	reg := prometheus.NewRegistry()
+	registerCacheMetrics(reg, "cep", cepCache)
+	registerCacheMetrics(reg, "weather", weatherCache)
+
+	// Uma consulta não encontrada seguida de uma encontrada
+	if _, ok := cepCache.Get(context.Background(), "01310100"); ok {
+		t.Fatal("cache de CEP deveria estar vazio")
+	}
+	cepCache.Set(context.Background(), "01310100", &CEPData{CEP: "01310-100"})
+	if _, ok := cepCache.Get(context.Background(), "01310-100"); !ok {
+		t.Fatal("CEP deveria estar no cache")
+	}
+
+	if _, ok := weatherCache.Get(context.Background(), "São Paulo", "SP", ""); ok {
+		t.Fatal("cache de temperatura deveria estar vazio")
+	}
+	weatherCache.Set(context.Background(), "São Paulo", "SP", "", &WeatherData{TempC: 22})
+	weatherCache.Get(context.Background(), "São Paulo", "SP", "")
+	weatherCache.Get(context.Background(), "São Paulo", "SP", "")
+
+	if hits, misses := cepCache.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("cepCache.Stats() = (%d, %d), want (1, 1)", hits, misses)
+	}
+	expected := map[string]float64{
+		"cep_cache_hits_total":       1,
+		"cep_cache_misses_total":     1,
+		"weather_cache_hits_total":   2,
+		"weather_cache_misses_total": 1,
+	}
+	for name, want := range expected {
+		if got := gatherCounter(t, reg, name); got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	// Entradas expiradas contam como miss
+	now = now.Add(2 * time.Hour)
+	cepCache.Get(context.Background(), "01310100")
+	if got := gatherCounter(t, reg, "cep_cache_misses_total"); got != 2 {
+		t.Errorf("cep_cache_misses_total após expirar = %v, want 2", got)
+	}
+}
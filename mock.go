@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+// mockTempC é a temperatura devolvida pelo modo offline
+const mockTempC = 23
+
+// mockCEPResolver implementa CEPResolver no desenvolvimento local
+// (MOCK_MODE): todo CEP válido resolve para um endereço em São Paulo, sem
+// rede. Os CEPs inválidos ou sabidamente inexistentes continuam recusados
+// como na consulta real.
+type mockCEPResolver struct{}
+
+func (mockCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	if detail := cepValidationDetail(cep); detail != "" {
+		return nil, &CustomError{Code: 422, Message: "invalid zipcode", Detail: detail, Err: weather.ErrInvalidCEP}
+	}
+	if knownInvalidCEPs[formatCEP(cep)] {
+		return nil, &CustomError{Code: 404, Message: "can not find zipcode", Err: weather.ErrCEPNotFound}
+	}
+	address := mockAddress(cep)
+	return &address, nil
+}
+
+// mockWeatherResolver implementa WeatherResolver no desenvolvimento local
+// (MOCK_MODE): toda cidade tem 23°C, com a observação e a previsão completas
+// da resposta simulada do wttr.in, de modo que ?verbose= e ?forecast= também
+// funcionam sem rede
+type mockWeatherResolver struct{}
+
+func (mockWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	data, err := weather.ParseWttrJSON([]byte(mockWttrJSON(time.Now())))
+	if err != nil {
+		return nil, upstreamError(ctx, providerWttr, err, "city", city, "state", state)
+	}
+	weatherData := weatherFromProvider(data)
+	weatherData.FetchedAt = time.Now()
+	return weatherData, nil
+}
+
+// mockTransport é o transporte base do httpClient no modo offline, dentro
+// da mesma cadeia de limite, taxa de erro e métricas do transporte real. Ele
+// atende os endpoints que consultam os upstreams sem passar pelos resolvers
+// (/rpc, /weatherbyaddress, /weather/bbox, /cepsearch e as coordenadas do
+// CEP) com as mesmas respostas fixas dos resolvers simulados. Chamadas a
+// upstreams sem resposta simulada falham em vez de sair para a rede.
+type mockTransport struct{}
+
+func (mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	switch {
+	case hasBaseURL(target, config.ViaCEPBaseURL):
+		return mockViaCEP(strings.TrimPrefix(target, strings.TrimSuffix(config.ViaCEPBaseURL, "/")))
+	case hasBaseURL(target, config.WeatherBaseURL):
+		if req.URL.Query().Get("format") == "%t" {
+			return mockResponse("text/plain; charset=utf-8", fmt.Sprintf("+%d°C", mockTempC)), nil
+		}
+		return mockResponse("application/json", mockWttrJSON(time.Now())), nil
+	case hasBaseURL(target, config.GeocoderBaseURL):
+		return mockResponse("application/json", `[{"lat": "-23.5614", "lon": "-46.6559", "display_name": "Avenida Paulista, Bela Vista, São Paulo, SP, Brasil", "address": {"city": "São Paulo", "ISO3166-2-lvl4": "BR-SP"}}]`), nil
+	}
+	return nil, fmt.Errorf("mock mode: no canned response for %s", req.URL.Redacted())
+}
+
+// hasBaseURL indica se target está sob a URL base de um upstream configurado
+func hasBaseURL(target, base string) bool {
+	base = strings.TrimSuffix(base, "/")
+	return base != "" && (target == base || strings.HasPrefix(target, base+"/"))
+}
+
+// mockViaCEP responde a consulta (/{cep}/json/) e a busca reversa
+// (/{uf}/{cidade}/{logradouro}/json/) do ViaCEP
+func mockViaCEP(path string) (*http.Response, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch len(segments) {
+	case 2:
+		return mockResponse("application/json", mockAddressJSON(segments[0])), nil
+	case 4:
+		return mockResponse("application/json", "["+mockAddressJSON("01310100")+"]"), nil
+	}
+	return nil, fmt.Errorf("mock mode: no canned response for ViaCEP path %s", path)
+}
+
+// mockAddress é o endereço de exemplo do CEP, com o CEP no formato do ViaCEP
+func mockAddress(cep string) CEPData {
+	formatted := formatCEP(cep)
+	if len(formatted) == 8 {
+		formatted = formatted[:5] + "-" + formatted[5:]
+	}
+	return CEPData{
+		CEP:        formatted,
+		Logradouro: "Avenida Paulista",
+		Bairro:     "Bela Vista",
+		Localidade: "São Paulo",
+		UF:         "SP",
+		IBGE:       "3550308",
+		DDD:        "11",
+	}
+}
+
+// mockAddressJSON é o endereço de exemplo do CEP, no formato do ViaCEP
+func mockAddressJSON(cep string) string {
+	body, _ := json.Marshal(mockAddress(cep))
+	return string(body)
+}
+
+// mockWttrHours são os horários das leituras horárias simuladas, no formato
+// HHMM sem zeros à esquerda do wttr.in
+var mockWttrHours = []string{"0", "300", "600", "900", "1200", "1500", "1800", "2100"}
+
+// mockWttrJSON monta a resposta format=j1 do wttr.in com os mesmos campos da
+// resposta real: a condição atual observada na última hora cheia antes de
+// now, e três dias de previsão com leituras horárias a partir da data de now
+// em São Paulo
+func mockWttrJSON(now time.Time) string {
+	loc, ok := timezoneForUF("SP")
+	if !ok {
+		loc = time.UTC
+	}
+	observed := now.UTC().Truncate(time.Hour)
+
+	type hourly struct {
+		Time  string `json:"time"`
+		TempC string `json:"tempC"`
+	}
+	type day struct {
+		Date     string   `json:"date"`
+		MaxTempC string   `json:"maxtempC"`
+		MinTempC string   `json:"mintempC"`
+		Hourly   []hourly `json:"hourly"`
+	}
+	days := make([]day, 0, 3)
+	for i := 0; i < 3; i++ {
+		d := day{
+			Date:     now.In(loc).AddDate(0, 0, i).Format(wttrDateLayout),
+			MaxTempC: fmt.Sprint(mockTempC + 4),
+			MinTempC: fmt.Sprint(mockTempC - 6),
+		}
+		for j, h := range mockWttrHours {
+			d.Hourly = append(d.Hourly, hourly{Time: h, TempC: fmt.Sprint(mockTempC - 6 + j)})
+		}
+		days = append(days, d)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"current_condition": []map[string]interface{}{{
+			"temp_C":           fmt.Sprint(mockTempC),
+			"FeelsLikeC":       fmt.Sprint(mockTempC + 1),
+			"humidity":         "60",
+			"observation_time": observed.Format(wttrObservationTimeLayout),
+			"localObsDateTime": observed.In(loc).Format(wttrLocalObsLayout),
+			"weatherDesc":      []map[string]string{{"value": "Sunny"}},
+		}},
+		"nearest_area": []map[string]string{{"latitude": "-23.533", "longitude": "-46.617"}},
+		"weather":      days,
+	})
+	return string(body)
+}
+
+// mockResponse monta uma resposta 200 com o corpo informado
+func mockResponse(contentType, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
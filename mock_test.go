@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+// useMockMode ativa o modo offline durante o teste, com o mockTransport na
+// mesma cadeia de transportes usada por main. Os upstreams apontam para um
+// servidor que reprova o teste se for acessado, de modo que qualquer chamada
+// que escape do mockTransport é detectada.
+func useMockMode(t *testing.T) {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("modo offline acessou a rede: %s", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(upstream.Close)
+
+	useConfig(t, Config{
+		ViaCEPBaseURL:    upstream.URL + "/ws",
+		WeatherBaseURL:   upstream.URL + "/wttr",
+		GeocoderBaseURL:  upstream.URL + "/search",
+		OpenMeteoBaseURL: upstream.URL + "/openmeteo",
+		BrasilAPIBaseURL: upstream.URL + "/brasilapi",
+	})
+	useTransport(t, newUpstreamTransport(mockTransport{}))
+	useGeocoder(t, &nominatimGeocoder{baseURL: config.GeocoderBaseURL})
+	previous := localityGeocoder
+	localityGeocoder = addressLocalityGeocoder{geocoder: addressGeocoder}
+	t.Cleanup(func() { localityGeocoder = previous })
+}
+
+func TestWeatherByCEPHandlerMockMode(t *testing.T) {
+	useMockMode(t)
+	handler := NewWeatherHandler(mockCEPResolver{}, mockWeatherResolver{})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"CEP válido devolve dados de exemplo", "/weatherbycep/01310100?verbose=true&forecast=3", http.StatusOK},
+		{"CEP inexistente", "/weatherbycep/99999999", http.StatusNotFound},
+		{"CEP inválido", "/weatherbycep/123", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				TempC   float64 `json:"temp_C"`
+				Address struct {
+					Localidade string `json:"localidade"`
+				} `json:"address"`
+				Lat           *float64      `json:"lat"`
+				ObservedLocal string        `json:"observed_local"`
+				PartialFields []string      `json:"partial_fields"`
+				Forecast      []ForecastDay `json:"forecast"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if resp.TempC != mockTempC {
+				t.Errorf("temp_C = %v, want %v", resp.TempC, mockTempC)
+			}
+			if resp.Address.Localidade != "São Paulo" {
+				t.Errorf("localidade = %q, want São Paulo", resp.Address.Localidade)
+			}
+			if resp.Lat == nil {
+				t.Error("coordenadas ausentes: o geocodificador também deveria ser simulado")
+			}
+			if resp.ObservedLocal == "" || len(resp.PartialFields) > 0 {
+				t.Errorf("observação incompleta: observed_local = %q, partial_fields = %v", resp.ObservedLocal, resp.PartialFields)
+			}
+			if len(resp.Forecast) != 3 {
+				t.Errorf("previsão com %d dias, want 3", len(resp.Forecast))
+			}
+		})
+	}
+}
+
+func TestMockWttrJSONIsComplete(t *testing.T) {
+	now := time.Date(2025, 1, 15, 15, 40, 0, 0, time.UTC)
+	data, err := weather.ParseWttrJSON([]byte(mockWttrJSON(now)))
+	if err != nil {
+		t.Fatalf("resposta simulada inválida: %v", err)
+	}
+
+	if data.TempC != mockTempC || data.Humidity == 0 || data.Description == "" {
+		t.Errorf("condição atual incompleta: %+v", data)
+	}
+	if data.Observation.NearestAreaLatitude == "" || data.Observation.NearestAreaLongitude == "" {
+		t.Errorf("nearest_area ausente: %+v", data.Observation)
+	}
+	if len(data.Forecast) != 3 || data.Forecast[0].Date != "2025-01-15" {
+		t.Errorf("previsão = %+v, want 3 dias a partir de 2025-01-15", data.Forecast)
+	}
+
+	details := weatherFromProvider(data).Details.localize("SP", nil, now)
+	if details.ObservedLocal != "2025-01-15T12:00:00-03:00" {
+		t.Errorf("observed_local = %q, want 2025-01-15T12:00:00-03:00", details.ObservedLocal)
+	}
+	if len(details.PartialFields) > 0 || len(details.RecentTemps) == 0 {
+		t.Errorf("modo verbose incompleto: partial_fields = %v, recent_temps = %v", details.PartialFields, details.RecentTemps)
+	}
+}
+
+func TestMockModeRoutesStayOffline(t *testing.T) {
+	useMockMode(t)
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		target   string
+		body     string
+		expected string
+	}{
+		{
+			name:     "rpc",
+			handler:  NewRPCHandler(viaCEPResolver{}, wttrWeatherResolver{}),
+			method:   "POST",
+			target:   "/rpc",
+			body:     `{"jsonrpc":"2.0","method":"weather.byCep","params":{"cep":"01310-100"},"id":1}`,
+			expected: `"temp_C":23`,
+		},
+		{
+			name:     "weatherbyaddress",
+			handler:  NewWeatherByAddressHandler(addressGeocoder, wttrWeatherResolver{}),
+			method:   "GET",
+			target:   "/weatherbyaddress?q=Av+Paulista,+Sao+Paulo",
+			expected: `"temp_C":23`,
+		},
+		{
+			name:     "weather/bbox",
+			handler:  NewBBoxWeatherHandler(nil),
+			method:   "GET",
+			target:   "/weather/bbox?minlat=-23.6&minlon=-46.7&maxlat=-23.5&maxlon=-46.6&step=0.1",
+			expected: `"temp_C":23`,
+		},
+		{
+			name:     "cepsearch",
+			handler:  cepSearchHandler,
+			method:   "GET",
+			target:   "/cepsearch?uf=SP&city=S%C3%A3o+Paulo&street=Paulista",
+			expected: `"01310-100"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler(rr, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (corpo: %s)", rr.Code, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), tt.expected) {
+				t.Errorf("corpo = %s, want %s", rr.Body.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestMockTransportRejectsUnknownUpstreams(t *testing.T) {
+	useConfig(t, Config{ViaCEPBaseURL: "http://viacep.test/ws"})
+
+	req := httptest.NewRequest("GET", "http://desconhecido.test/api", nil)
+	if _, err := (mockTransport{}).RoundTrip(req); err == nil {
+		t.Error("upstream sem resposta simulada deveria falhar em vez de acessar a rede")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// validCacheNamespace limita os namespaces e inquilinos aceitos, evitando
+// chaves enormes ou com o separador usado entre o namespace e a chave
+var validCacheNamespace = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// cacheNamespace isola as entradas dos caches entre inquilinos que
+// compartilham o servidor: todas as chaves recebem o prefixo de
+// CACHE_NAMESPACE e, quando CACHE_TENANT_HEADER está definida, o valor desse
+// header na requisição
+type cacheNamespace struct {
+	prefix       string
+	tenantHeader string
+}
+
+// newCacheNamespaceFromEnv lê CACHE_NAMESPACE e CACHE_TENANT_HEADER, ambos
+// vazios por padrão; um namespace inválido é ignorado com um aviso
+func newCacheNamespaceFromEnv() *cacheNamespace {
+	prefix := strings.TrimSpace(os.Getenv("CACHE_NAMESPACE"))
+	if prefix != "" && !validCacheNamespace.MatchString(prefix) {
+		logger.Warn("CACHE_NAMESPACE inválido, ignorando", "value", prefix)
+		prefix = ""
+	}
+	return &cacheNamespace{
+		prefix:       prefix,
+		tenantHeader: http.CanonicalHeaderKey(strings.TrimSpace(os.Getenv("CACHE_TENANT_HEADER"))),
+	}
+}
+
+// cacheNamespaceKey é a chave do namespace dos caches no contexto
+type cacheNamespaceKey struct{}
+
+// withCacheNamespace guarda no contexto o namespace dos caches
+func withCacheNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, cacheNamespaceKey{}, namespace)
+}
+
+// namespacedKey acrescenta à chave o namespace guardado no contexto, no
+// formato "namespace:chave"; sem namespace a chave não muda
+func namespacedKey(ctx context.Context, key string) string {
+	namespace, _ := ctx.Value(cacheNamespaceKey{}).(string)
+	if namespace == "" {
+		return key
+	}
+	return namespace + ":" + key
+}
+
+// Wrap guarda no contexto da requisição o namespace dos caches. Com
+// CACHE_TENANT_HEADER, um inquilino fora do formato aceito é recusado com
+// 400, para que não caia no namespace compartilhado.
+func (n *cacheNamespace) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		namespace := n.prefix
+		if n.tenantHeader != "" {
+			if tenant := r.Header.Get(n.tenantHeader); tenant != "" {
+				if !validCacheNamespace.MatchString(tenant) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid tenant header"})
+					return
+				}
+				if namespace != "" {
+					namespace += "/"
+				}
+				namespace += tenant
+			}
+		}
+		if namespace == "" {
+			next(w, r)
+			return
+		}
+		next(w, r.WithContext(withCacheNamespace(r.Context(), namespace)))
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCacheNamespaceIsolatesEntries(t *testing.T) {
+	cache := NewCEPCache(time.Hour)
+	t.Cleanup(cache.Stop)
+	upstream := newFakeCEPResolver()
+	resolver := cachingCEPResolver{cache: cache, next: upstream}
+
+	namespace := &cacheNamespace{prefix: "prod", tenantHeader: "X-Tenant-Id"}
+	handler := namespace.Wrap(NewCEPHandler(resolver))
+
+	tests := []struct {
+		name          string
+		tenant        string
+		expectedCalls int
+	}{
+		{"Primeiro inquilino consulta o upstream", "acme", 1},
+		{"Mesmo inquilino usa o cache", "acme", 1},
+		{"Outro inquilino não vê a entrada", "globex", 2},
+		{"Sem inquilino usa apenas o prefixo", "", 3},
+		{"Sem inquilino de novo usa o cache", "", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/cep/01310-100", nil)
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant-Id", tt.tenant)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (corpo: %s)", rr.Code, rr.Body.String())
+			}
+			if upstream.calls != tt.expectedCalls {
+				t.Errorf("upstream consultado %d vezes, want %d", upstream.calls, tt.expectedCalls)
+			}
+		})
+	}
+
+	if cache.Len() != 3 {
+		t.Errorf("cache com %d entradas, want uma por namespace (3)", cache.Len())
+	}
+}
+
+func TestNamespacedKey(t *testing.T) {
+	ctx := context.Background()
+	if got := namespacedKey(ctx, "01310100"); got != "01310100" {
+		t.Errorf("sem namespace = %q, want a chave original", got)
+	}
+	if got := namespacedKey(withCacheNamespace(ctx, "prod/acme"), "01310100"); got != "prod/acme:01310100" {
+		t.Errorf("com namespace = %q, want prod/acme:01310100", got)
+	}
+
+	// As mesmas entradas do cache de temperatura também ficam separadas
+	cache := NewWeatherCache(time.Hour)
+	t.Cleanup(cache.Stop)
+	cache.Set(withCacheNamespace(ctx, "acme"), "São Paulo", "SP", "", &WeatherData{TempC: 22})
+	if _, ok := cache.Get(withCacheNamespace(ctx, "globex"), "São Paulo", "SP", ""); ok {
+		t.Error("entrada de outro namespace não deveria ser encontrada")
+	}
+	if _, ok := cache.Get(withCacheNamespace(ctx, "acme"), "São Paulo", "SP", ""); !ok {
+		t.Error("entrada do mesmo namespace deveria ser encontrada")
+	}
+}
+
+func TestCacheNamespaceRejectsInvalidTenant(t *testing.T) {
+	namespace := &cacheNamespace{tenantHeader: "X-Tenant-Id"}
+	called := false
+	handler := namespace.Wrap(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	req := httptest.NewRequest("GET", "/cep/01310100", nil)
+	req.Header.Set("X-Tenant-Id", "acme:globex")
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if called || rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, handler chamado = %v, want 400 sem chamar o handler", rr.Code, called)
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errResp); err != nil || errResp.Message != "invalid tenant header" {
+		t.Errorf("resposta = %s, want invalid tenant header", rr.Body.String())
+	}
+}
+
+func TestNewCacheNamespaceFromEnv(t *testing.T) {
+	tests := []struct {
+		prefix         string
+		header         string
+		expectedPrefix string
+		expectedHeader string
+	}{
+		{"", "", "", ""},
+		{"prod", "", "prod", ""},
+		{" prod ", "x-tenant-id", "prod", "X-Tenant-Id"},
+		{"prod:eu", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix+"|"+tt.header, func(t *testing.T) {
+			t.Setenv("CACHE_NAMESPACE", tt.prefix)
+			t.Setenv("CACHE_TENANT_HEADER", tt.header)
+			namespace := newCacheNamespaceFromEnv()
+			if namespace.prefix != tt.expectedPrefix || namespace.tenantHeader != tt.expectedHeader {
+				t.Errorf("namespace = %+v, want prefix %q e header %q", namespace, tt.expectedPrefix, tt.expectedHeader)
+			}
+		})
+	}
+}
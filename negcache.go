@@ -0,0 +1,124 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultNotFoundCacheTTL é o tempo padrão em que um CEP inexistente é lembrado
+	defaultNotFoundCacheTTL = 5 * time.Minute
+	// defaultNotFoundCacheSize limita a quantidade de CEPs inexistentes guardados
+	defaultNotFoundCacheSize = 10000
+	// notFoundCacheJitter varia o TTL de cada entrada em até ±10% para que
+	// CEPs consultados juntos não expirem todos ao mesmo tempo
+	notFoundCacheJitter = 0.1
+)
+
+// notFoundEntry guarda um CEP inexistente e o momento em que expira
+type notFoundEntry struct {
+	cep       string
+	expiresAt time.Time
+}
+
+// NotFoundCache lembra por pouco tempo os CEPs que o upstream informou não
+// existirem, separado do cache de endereços. O tamanho é limitado: quando
+// cheio, o CEP consultado há mais tempo é descartado.
+type NotFoundCache struct {
+	cacheStats
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+	random     func() float64
+}
+
+// NewNotFoundCache cria um cache de CEPs inexistentes com o TTL e o tamanho informados
+func NewNotFoundCache(ttl time.Duration, maxEntries int) *NotFoundCache {
+	return &NotFoundCache{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		random:     rand.Float64,
+	}
+}
+
+// newNotFoundCacheFromEnv cria o cache a partir de NOT_FOUND_CACHE_TTL e
+// NOT_FOUND_CACHE_MAX_ENTRIES. Um TTL 0 desativa o cache.
+func newNotFoundCacheFromEnv() *NotFoundCache {
+	ttl := envDurationWhere("NOT_FOUND_CACHE_TTL", defaultNotFoundCacheTTL, nonNegative[time.Duration])
+	if ttl == 0 {
+		return nil
+	}
+	maxEntries := envIntWhere("NOT_FOUND_CACHE_MAX_ENTRIES", defaultNotFoundCacheSize, positive[int])
+
+	return NewNotFoundCache(ttl, maxEntries)
+}
+
+// Contains indica se o CEP foi marcado como inexistente, no namespace do
+// contexto, e ainda não expirou. Um cache nil nunca contém CEPs.
+func (c *NotFoundCache) Contains(ctx context.Context, cep string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[namespacedKey(ctx, cepCacheKey(cep))]
+	if !ok {
+		c.record(false)
+		return false
+	}
+	entry := elem.Value.(*notFoundEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, entry.cep)
+		c.record(false)
+		return false
+	}
+	c.order.MoveToFront(elem)
+	c.record(true)
+	return true
+}
+
+// Add marca o CEP como inexistente no namespace do contexto, descartando o
+// menos consultado se o cache estiver cheio
+func (c *NotFoundCache) Add(ctx context.Context, cep string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cep = namespacedKey(ctx, cepCacheKey(cep))
+	jitter := 1 + notFoundCacheJitter*(2*c.random()-1)
+	expiresAt := c.now().Add(time.Duration(float64(c.ttl) * jitter))
+
+	if elem, ok := c.entries[cep]; ok {
+		elem.Value.(*notFoundEntry).expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*notFoundEntry).cep)
+	}
+	c.entries[cep] = c.order.PushFront(&notFoundEntry{cep: cep, expiresAt: expiresAt})
+}
+
+// Len retorna a quantidade de CEPs guardados, inclusive os já expirados
+func (c *NotFoundCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
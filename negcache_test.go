@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestNotFoundCache cria um cache de CEPs inexistentes com relógio
+// controlado pelo teste e sem variação no TTL
+func newTestNotFoundCache(ttl time.Duration, maxEntries int, now *time.Time) *NotFoundCache {
+	cache := NewNotFoundCache(ttl, maxEntries)
+	cache.now = func() time.Time { return *now }
+	cache.random = func() float64 { return 0.5 }
+	return cache
+}
+
+func TestCachingCEPResolverRemembersNotFound(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"erro": true}`))
+	}))
+	defer server.Close()
+	useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws"})
+
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	resolver := cachingCEPResolver{
+		cache:    newTestCEPCache(t, time.Hour, &now),
+		notFound: newTestNotFoundCache(5*time.Minute, 10, &now),
+		next:     viaCEPResolver{},
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := resolver.ResolveCEP(context.Background(), "01310-100")
+		if err == nil || err.Code != 404 {
+			t.Fatalf("consulta %d: erro = %v, want 404", i+1, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("ViaCEP consultado %d vezes dentro do TTL, want 1", n)
+	}
+
+	// Após o TTL o CEP volta a ser consultado
+	now = now.Add(5 * time.Minute)
+	resolver.ResolveCEP(context.Background(), "01310100")
+	if n := calls.Load(); n != 2 {
+		t.Errorf("ViaCEP consultado %d vezes após o TTL, want 2", n)
+	}
+}
+
+func TestCachingCEPResolverOnlyRemembersNotFound(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	next := newFakeCEPResolver()
+	next.err = &CustomError{Code: 502, Message: "bad gateway"}
+	notFound := newTestNotFoundCache(5*time.Minute, 10, &now)
+	resolver := cachingCEPResolver{cache: newTestCEPCache(t, time.Hour, &now), notFound: notFound, next: next}
+
+	resolver.ResolveCEP(context.Background(), "01310100")
+	resolver.ResolveCEP(context.Background(), "01310100")
+	if next.calls != 2 {
+		t.Errorf("resolver chamado %d vezes, want 2: falhas do upstream não devem ser lembradas", next.calls)
+	}
+	if notFound.Len() != 0 {
+		t.Errorf("cache de inexistentes com %d entradas, want 0", notFound.Len())
+	}
+}
+
+func TestNotFoundCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestNotFoundCache(time.Minute, 2, &now)
+
+	cache.Add(context.Background(), "01000001")
+	cache.Add(context.Background(), "01000002")
+	cache.Contains(context.Background(), "01000001") // 01000002 passa a ser o menos usado
+	cache.Add(context.Background(), "01000003")
+
+	if cache.Len() != 2 {
+		t.Errorf("tamanho = %d, want 2", cache.Len())
+	}
+	if cache.Contains(context.Background(), "01000002") {
+		t.Error("o CEP menos usado deveria ter sido descartado")
+	}
+	if !cache.Contains(context.Background(), "01000001") || !cache.Contains(context.Background(), "01000003") {
+		t.Error("os CEPs mais recentes deveriam continuar no cache")
+	}
+}
+
+func TestNotFoundCacheJitter(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestNotFoundCache(10*time.Minute, 10, &now)
+
+	// random no máximo estende o TTL em 10%
+	cache.random = func() float64 { return 1 }
+	cache.Add(context.Background(), "01310100")
+	now = now.Add(10*time.Minute + 30*time.Second)
+	if !cache.Contains(context.Background(), "01310100") {
+		t.Error("a entrada deveria durar até 11 minutos com o jitter máximo")
+	}
+	now = now.Add(30 * time.Second)
+	if cache.Contains(context.Background(), "01310100") {
+		t.Error("a entrada deveria expirar após 11 minutos")
+	}
+}
+
+func TestNewNotFoundCacheFromEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        string
+		maxEntries string
+		disabled   bool
+		wantTTL    time.Duration
+		wantMax    int
+	}{
+		{"Padrão", "", "", false, defaultNotFoundCacheTTL, defaultNotFoundCacheSize},
+		{"Configurado", "1m", "500", false, time.Minute, 500},
+		{"Valores inválidos usam o padrão", "breve", "-1", false, defaultNotFoundCacheTTL, defaultNotFoundCacheSize},
+		{"Desativado", "0s", "", true, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOT_FOUND_CACHE_TTL", tt.ttl)
+			t.Setenv("NOT_FOUND_CACHE_MAX_ENTRIES", tt.maxEntries)
+
+			cache := newNotFoundCacheFromEnv()
+			if tt.disabled {
+				if cache != nil {
+					t.Error("o cache deveria estar desativado")
+				}
+				return
+			}
+			if cache == nil || cache.ttl != tt.wantTTL || cache.maxEntries != tt.wantMax {
+				t.Errorf("cache = %+v, want TTL %v e tamanho %d", cache, tt.wantTTL, tt.wantMax)
+			}
+		})
+	}
+}
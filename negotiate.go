@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formatos de resposta aceitos em /weatherbycep/{cep} via header Accept
+const (
+	formatJSON = "json"
+	formatXML  = "xml"
+	formatText = "text"
+)
+
+// acceptedRange é um tipo do header Accept com o seu peso (q)
+type acceptedRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept lê os tipos do header Accept com os respectivos pesos,
+// ordenados do maior para o menor peso. Tipos com o mesmo peso mantêm a ordem
+// do header; tipos com q=0 (recusados) ou malformados são descartados.
+func parseAccept(header string) []acceptedRange {
+	var ranges []acceptedRange
+	for _, accepted := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		if q == 0 {
+			continue
+		}
+		ranges = append(ranges, acceptedRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	return ranges
+}
+
+// negotiateFormat escolhe o formato da resposta a partir do header Accept,
+// usando o tipo suportado de maior peso (q) e, no empate, o primeiro na ordem
+// em que aparece. Accept ausente, genérico (*/*) ou sem tipos suportados
+// resulta em JSON.
+func negotiateFormat(r *http.Request) string {
+	for _, accepted := range parseAccept(r.Header.Get("Accept")) {
+		switch accepted.mediaType {
+		case "application/json", "*/*":
+			return formatJSON
+		case "application/xml", "text/xml":
+			return formatXML
+		case "text/plain":
+			return formatText
+		}
+	}
+	return formatJSON
+}
+
+// ndjsonContentType é o tipo das respostas em streaming, um objeto JSON por linha
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON indica se o cliente prefere a resposta em NDJSON: o tipo
+// aparece em Accept antes de JSON ou de */*, pelas mesmas regras de peso de
+// negotiateFormat
+func wantsNDJSON(r *http.Request) bool {
+	for _, accepted := range parseAccept(r.Header.Get("Accept")) {
+		switch accepted.mediaType {
+		case ndjsonContentType:
+			return true
+		case "application/json", "*/*":
+			return false
+		}
+	}
+	return false
+}
+
+// writeError escreve a resposta de erro no formato negociado
+func writeError(w http.ResponseWriter, format string, status int, resp ErrorResponse) {
+	switch format {
+	case formatXML:
+		writeXML(w, status, "error", resp)
+	case formatText:
+		line := resp.Message
+		if resp.Detail != "" {
+			line += ": " + resp.Detail
+		}
+		writeText(w, status, line)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// writeWeather escreve a resposta de sucesso no formato negociado. O corpo
+// JSON já vem renderizado conforme os parâmetros da requisição; XML e texto
+// trazem apenas os dados de temperatura.
+func writeWeather(w http.ResponseWriter, format string, jsonBody interface{}, weather WeatherData) {
+	switch format {
+	case formatXML:
+		writeXML(w, http.StatusOK, "weather", weather)
+	case formatText:
+		writeText(w, http.StatusOK, formatTemperatures(weather))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		encodeJSON(w, jsonBody)
+	}
+}
+
+// formatTemperatures monta a linha de texto com as três escalas, com as casas
+// decimais de TEMPERATURE_PRECISION, ex.: "23.0C / 73.4F / 296.2K"
+func formatTemperatures(weather WeatherData) string {
+	p := temperaturePrecision
+	return fmt.Sprintf("%.*fC / %.*fF / %.*fK", p, weather.TempC, p, weather.TempF, p, weather.TempK)
+}
+
+// writeXML codifica o valor em XML usando o elemento raiz informado
+func writeXML(w http.ResponseWriter, status int, root string, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: root}})
+}
+
+// writeText escreve uma única linha de texto
+func writeText(w http.ResponseWriter, status int, line string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintln(w, line)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"", formatJSON},
+		{"*/*", formatJSON},
+		{"application/json", formatJSON},
+		{"application/xml", formatXML},
+		{"text/xml; charset=utf-8", formatXML},
+		{"text/plain", formatText},
+		{"text/html, text/plain;q=0.9", formatText},
+		{"application/xml, application/json", formatXML},
+		{"image/png", formatJSON},
+		// O peso (q) tem prioridade sobre a ordem do header
+		{"application/xml;q=0.1, application/json", formatJSON},
+		{"text/plain;q=0.5, application/xml;q=0.8", formatXML},
+		{"*/*;q=0.1, text/plain", formatText},
+		// Empate de peso mantém a ordem do header
+		{"text/plain;q=0.7, application/xml;q=0.7", formatText},
+		// q=0 recusa o tipo
+		{"application/json;q=0, application/xml", formatXML},
+		{"text/plain;q=0", formatJSON},
+		// Peso malformado descarta o tipo
+		{"text/plain;q=alto, application/xml", formatXML},
+		{"text/plain;q=2", formatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+			req.Header.Set("Accept", tt.accept)
+			if format := negotiateFormat(req); format != tt.expected {
+				t.Errorf("negotiateFormat(%q) = %s, want %s", tt.accept, format, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerFormats(t *testing.T) {
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		rr := serve("/weatherbycep/01310100", "application/json")
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		var resp WeatherResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.TempC != 23 {
+			t.Errorf("corpo JSON inválido: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		rr := serve("/weatherbycep/01310100", "application/xml")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+			t.Errorf("Content-Type = %q, want application/xml", ct)
+		}
+		var resp struct {
+			XMLName xml.Name `xml:"weather"`
+			WeatherData
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("corpo XML inválido: %v (%s)", err, rr.Body.String())
+		}
+		if resp.TempC != 23 || resp.TempF != 73.4 || resp.TempK != 296.2 {
+			t.Errorf("temperaturas incorretas: %+v", resp.WeatherData)
+		}
+	})
+
+	t.Run("Texto", func(t *testing.T) {
+		rr := serve("/weatherbycep/01310100", "text/plain")
+		if ct := rr.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("Content-Type = %q, want text/plain", ct)
+		}
+		if body := rr.Body.String(); body != "23.0C / 73.4F / 296.2K\n" {
+			t.Errorf("corpo = %q, want %q", body, "23.0C / 73.4F / 296.2K\n")
+		}
+	})
+
+	t.Run("Erro em XML", func(t *testing.T) {
+		rr := serve("/weatherbycep/99999999", "application/xml")
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want 404", rr.Code)
+		}
+		var resp struct {
+			XMLName xml.Name `xml:"error"`
+			ErrorResponse
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("corpo XML inválido: %v (%s)", err, rr.Body.String())
+		}
+		if resp.Message != "can not find zipcode" {
+			t.Errorf("message = %q, want %q", resp.Message, "can not find zipcode")
+		}
+	})
+
+	t.Run("Erro em texto", func(t *testing.T) {
+		rr := serve("/weatherbycep/123", "text/plain")
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want 422", rr.Code)
+		}
+		if body := rr.Body.String(); !strings.HasPrefix(body, "invalid zipcode: ") {
+			t.Errorf("corpo = %q, want prefixo %q", body, "invalid zipcode: ")
+		}
+	})
+}
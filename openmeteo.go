@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// openMeteoWeatherResolver implementa WeatherResolver consultando o Open-Meteo.
+// Como o Open-Meteo trabalha com coordenadas, a cidade é geocodificada antes.
+type openMeteoWeatherResolver struct {
+	geocoder AddressGeocoder
+}
+
+func (o openMeteoWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	results, geoErr := o.geocoder.Geocode(ctx, fmt.Sprintf("%s, %s, Brasil", city, state))
+	if geoErr != nil {
+		return nil, geoErr
+	}
+	if len(results) == 0 {
+		logger.WarnContext(ctx, "cidade não geocodificada", "provider", providerOpenMeteo, "city", city, "state", state)
+		return nil, &CustomError{Code: 500, Message: "weather data not available"}
+	}
+
+	return fetchOpenMeteo(ctx, results[0].Lat, results[0].Lon)
+}
+
+// fetchOpenMeteo consulta a temperatura atual de uma coordenada no Open-Meteo
+func fetchOpenMeteo(ctx context.Context, lat, lon float64) (*WeatherData, *CustomError) {
+	params := url.Values{}
+	params.Set("latitude", fmt.Sprintf("%.4f", lat))
+	params.Set("longitude", fmt.Sprintf("%.4f", lon))
+	params.Set("current", "temperature_2m")
+
+	ctx, cancel := withUpstreamTimeout(ctx, config.timeoutFor(providerOpenMeteo, config.WeatherTimeout))
+	defer cancel()
+
+	var forecast struct {
+		Current struct {
+			Temperature *float64 `json:"temperature_2m"`
+		} `json:"current"`
+	}
+	if err := newUpstreamClient(ctx).GetJSON(ctx, config.OpenMeteoBaseURL+"/v1/forecast?"+params.Encode(), &forecast); err != nil {
+		return nil, upstreamError(ctx, providerOpenMeteo, err)
+	}
+	if forecast.Current.Temperature == nil {
+		logger.WarnContext(ctx, "dados climáticos não disponíveis para a localização fornecida", "provider", providerOpenMeteo)
+		return nil, &CustomError{Code: 500, Message: "weather data not available"}
+	}
+
+	weatherData := weatherFromCelsius(*forecast.Current.Temperature)
+	weatherData.FetchedAt = time.Now()
+	return &weatherData, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFailoverWeatherResolver(t *testing.T) {
+	upstreamErr := &CustomError{Code: 500, Message: "internal server error"}
+
+	tests := []struct {
+		name            string
+		primary         *fakeWeatherResolver
+		secondary       *fakeWeatherResolver
+		expectedTempC   float64
+		expectedErr     *CustomError
+		secondaryCalled bool
+	}{
+		{
+			name:            "Primário responde",
+			primary:         &fakeWeatherResolver{tempC: 21},
+			secondary:       &fakeWeatherResolver{tempC: 30},
+			expectedTempC:   21,
+			secondaryCalled: false,
+		},
+		{
+			name:            "Primário falha e secundário responde",
+			primary:         &fakeWeatherResolver{err: upstreamErr},
+			secondary:       &fakeWeatherResolver{tempC: 30},
+			expectedTempC:   30,
+			secondaryCalled: true,
+		},
+		{
+			name:            "Ambos falham",
+			primary:         &fakeWeatherResolver{err: upstreamErr},
+			secondary:       &fakeWeatherResolver{err: &CustomError{Code: 500, Message: "weather data not available"}},
+			expectedErr:     upstreamErr,
+			secondaryCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := FailoverWeatherResolver{Primary: tt.primary, Secondary: tt.secondary}
+			weather, err := resolver.ResolveWeather(context.Background(), "São Paulo", "SP")
+
+			if err != tt.expectedErr {
+				t.Fatalf("erro = %v, want %v", err, tt.expectedErr)
+			}
+			if err == nil && weather.TempC != tt.expectedTempC {
+				t.Errorf("temp_C = %v, want %v", weather.TempC, tt.expectedTempC)
+			}
+			if called := tt.secondary.calls > 0; called != tt.secondaryCalled {
+				t.Errorf("secundário chamado = %v, want %v", called, tt.secondaryCalled)
+			}
+		})
+	}
+}
+
+func TestFailoverWeatherResolverSkipsSecondaryWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	secondary := &fakeWeatherResolver{tempC: 30}
+	resolver := FailoverWeatherResolver{
+		Primary:   &fakeWeatherResolver{err: &CustomError{Code: 500, Message: "request canceled"}},
+		Secondary: secondary,
+	}
+	if _, err := resolver.ResolveWeather(ctx, "São Paulo", "SP"); err == nil {
+		t.Fatal("ResolveWeather deveria retornar erro")
+	}
+	if secondary.calls != 0 {
+		t.Errorf("secundário não deveria ser chamado com o contexto cancelado")
+	}
+}
+
+func TestWeatherByCEPHandlerFailsOverToOpenMeteo(t *testing.T) {
+	var forecastQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/forecast":
+			forecastQuery = r.URL.RawQuery
+			w.Write([]byte(`{"current": {"time": "2025-01-15T12:00", "temperature_2m": 26.5}}`))
+		default:
+			// O wttr.in está fora do ar
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	useRetryBaseDelay(t, 0)
+	useConfig(t, Config{WeatherBaseURL: server.URL, OpenMeteoBaseURL: server.URL})
+
+	geocoder := &stubGeocoder{results: []GeocodeResult{{City: "São Paulo", State: "SP", Lat: -23.5505, Lon: -46.6333}}}
+	handler := NewWeatherHandler(newFakeCEPResolver(), FailoverWeatherResolver{
+		Primary:   wttrWeatherResolver{},
+		Secondary: openMeteoWeatherResolver{geocoder: geocoder},
+	})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rr.Code, rr.Body.String())
+	}
+	if want := "current=temperature_2m&latitude=-23.5505&longitude=-46.6333"; forecastQuery != want {
+		t.Errorf("query do Open-Meteo = %q, want %q", forecastQuery, want)
+	}
+
+	var resp WeatherResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.TempC != 26.5 {
+		t.Errorf("temp_C = %v, want 26.5", resp.TempC)
+	}
+}
+
+func TestOpenMeteoWeatherResolverErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"current": {}}`))
+	}))
+	defer server.Close()
+	useConfig(t, Config{OpenMeteoBaseURL: server.URL})
+
+	tests := []struct {
+		name        string
+		geocoder    *stubGeocoder
+		expectedMsg string
+	}{
+		{"Cidade não geocodificada", &stubGeocoder{}, "weather data not available"},
+		{"Falha no geocodificador", &stubGeocoder{err: &CustomError{Code: 500, Message: "internal server error"}}, "internal server error"},
+		{"Sem temperatura", &stubGeocoder{results: []GeocodeResult{{Lat: -23.55, Lon: -46.63}}}, "weather data not available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openMeteoWeatherResolver{geocoder: tt.geocoder}.ResolveWeather(context.Background(), "São Paulo", "SP")
+			if err == nil || err.Message != tt.expectedMsg {
+				t.Errorf("erro = %v, want %q", err, tt.expectedMsg)
+			}
+		})
+	}
+}
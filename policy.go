@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxPolicyAttempts limita as tentativas por chamada aceitas na política
+const maxPolicyAttempts = 10
+
+// Ordens padrão dos provedores, usadas quando a política não informa outra
+var (
+	defaultCEPProviders     = []string{providerViaCEP, providerBrasilAPI}
+	defaultWeatherProviders = []string{providerWttr, providerOpenMeteo}
+)
+
+// ResolutionPolicy descreve em um único documento JSON a estratégia de
+// resolução: a ordem dos provedores de CEP e de temperatura, o prazo e as
+// tentativas de cada provedor, os TTLs dos caches e o circuit breaker. Os
+// campos ausentes mantêm a configuração das variáveis de ambiente.
+type ResolutionPolicy struct {
+	CEPProviders     []string                  `json:"cep_providers"`
+	WeatherProviders []string                  `json:"weather_providers"`
+	Providers        map[string]ProviderPolicy `json:"providers"`
+	Cache            CachePolicy               `json:"cache"`
+	Breaker          BreakerPolicy             `json:"breaker"`
+}
+
+// ProviderPolicy é o prazo e a quantidade máxima de tentativas das chamadas a um provedor
+type ProviderPolicy struct {
+	Timeout     *policyDuration `json:"timeout"`
+	MaxAttempts int             `json:"max_attempts"`
+}
+
+// CachePolicy reúne os TTLs dos caches e o limite para servir dados expirados
+type CachePolicy struct {
+	CEPTTL     *policyDuration `json:"cep_ttl"`
+	WeatherTTL *policyDuration `json:"weather_ttl"`
+	MaxStale   *policyDuration `json:"max_stale"`
+}
+
+// BreakerPolicy configura os circuit breakers do ViaCEP, do wttr.in e do
+// geocodificador; threshold zero os desativa
+type BreakerPolicy struct {
+	Threshold *int            `json:"threshold"`
+	Cooldown  *policyDuration `json:"cooldown"`
+}
+
+// policyDuration é uma duração escrita como texto no JSON, ex.: "5s"
+type policyDuration time.Duration
+
+func (d *policyDuration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string like \"5s\"")
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", text)
+	}
+	*d = policyDuration(parsed)
+	return nil
+}
+
+// loadResolutionPolicyFromEnv lê a política de RESOLUTION_POLICY (o JSON em
+// si) ou de RESOLUTION_POLICY_FILE (o caminho do arquivo). Sem nenhuma das
+// duas, retorna nil e a configuração continua vindo das variáveis de
+// ambiente; uma política inválida é um erro, para que o servidor não
+// inicie com uma estratégia de resiliência diferente da pedida.
+func loadResolutionPolicyFromEnv() (*ResolutionPolicy, error) {
+	inline := strings.TrimSpace(os.Getenv("RESOLUTION_POLICY"))
+	path := strings.TrimSpace(os.Getenv("RESOLUTION_POLICY_FILE"))
+	switch {
+	case inline != "" && path != "":
+		return nil, errors.New("RESOLUTION_POLICY and RESOLUTION_POLICY_FILE are mutually exclusive")
+	case inline != "":
+		return parseResolutionPolicy([]byte(inline))
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading RESOLUTION_POLICY_FILE: %w", err)
+		}
+		return parseResolutionPolicy(data)
+	}
+	return nil, nil
+}
+
+// parseResolutionPolicy decodifica e valida a política. Campos desconhecidos
+// são recusados, para que um erro de digitação não passe despercebido.
+func parseResolutionPolicy(data []byte) (*ResolutionPolicy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var policy ResolutionPolicy
+	if err := dec.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid resolution policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid resolution policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// validate verifica os provedores, os prazos, as tentativas e os TTLs da política
+func (p *ResolutionPolicy) validate() error {
+	if err := validateProviderOrder("cep_providers", p.CEPProviders, defaultCEPProviders); err != nil {
+		return err
+	}
+	if err := validateProviderOrder("weather_providers", p.WeatherProviders, defaultWeatherProviders); err != nil {
+		return err
+	}
+
+	for name, provider := range p.Providers {
+		switch name {
+		case providerViaCEP, providerBrasilAPI, providerWttr, providerOpenMeteo:
+		case providerGeocoder:
+			// As chamadas ao geocodificador não são identificadas pelo host
+			if provider.MaxAttempts != 0 {
+				return fmt.Errorf("providers.%s: max_attempts is not supported", name)
+			}
+		default:
+			return fmt.Errorf("providers: unknown provider %q", name)
+		}
+		if provider.Timeout != nil && *provider.Timeout < 0 {
+			return fmt.Errorf("providers.%s: timeout must not be negative", name)
+		}
+		if provider.MaxAttempts < 0 || provider.MaxAttempts > maxPolicyAttempts {
+			return fmt.Errorf("providers.%s: max_attempts must be between 1 and %d", name, maxPolicyAttempts)
+		}
+	}
+
+	for field, ttl := range map[string]*policyDuration{"cache.cep_ttl": p.Cache.CEPTTL, "cache.weather_ttl": p.Cache.WeatherTTL} {
+		if ttl != nil && *ttl <= 0 {
+			return fmt.Errorf("%s must be positive", field)
+		}
+	}
+	if p.Cache.MaxStale != nil && *p.Cache.MaxStale < 0 {
+		return errors.New("cache.max_stale must not be negative")
+	}
+	if p.Breaker.Threshold != nil && *p.Breaker.Threshold < 0 {
+		return errors.New("breaker.threshold must not be negative")
+	}
+	if p.Breaker.Cooldown != nil && *p.Breaker.Cooldown <= 0 {
+		return errors.New("breaker.cooldown must be positive")
+	}
+	return nil
+}
+
+// validateProviderOrder aceita uma lista vazia (ordem padrão) ou os
+// provedores conhecidos, sem repetição
+func validateProviderOrder(field string, order, known []string) error {
+	seen := make(map[string]bool, len(order))
+	for _, name := range order {
+		if !containsString(known, name) {
+			return fmt.Errorf("%s: unknown provider %q", field, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s: provider %q listed twice", field, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// containsString indica se value está em values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// cepResolver monta a cadeia de provedores de CEP na ordem da política: cada
+// provedor é consultado quando o anterior está indisponível. Uma política
+// nil ou sem ordem usa ViaCEP e depois BrasilAPI.
+func (p *ResolutionPolicy) cepResolver() CEPResolver {
+	order := defaultCEPProviders
+	if p != nil && len(p.CEPProviders) > 0 {
+		order = p.CEPProviders
+	}
+
+	var resolver CEPResolver
+	for i := len(order) - 1; i >= 0; i-- {
+		var provider CEPResolver = viaCEPResolver{}
+		if order[i] == providerBrasilAPI {
+			provider = brasilAPICEPResolver{}
+		}
+		if resolver == nil {
+			resolver = provider
+			continue
+		}
+		resolver = ChainedCEPResolver{Primary: provider, Secondary: resolver}
+	}
+	return resolver
+}
+
+// weatherResolver monta a cadeia de provedores de temperatura na ordem da
+// política, com failover para o seguinte em qualquer falha. Uma política nil
+// ou sem ordem usa o wttr.in e depois o Open-Meteo.
+func (p *ResolutionPolicy) weatherResolver(geocoder AddressGeocoder) WeatherResolver {
+	order := defaultWeatherProviders
+	if p != nil && len(p.WeatherProviders) > 0 {
+		order = p.WeatherProviders
+	}
+
+	var resolver WeatherResolver
+	for i := len(order) - 1; i >= 0; i-- {
+		var provider WeatherResolver = wttrWeatherResolver{}
+		if order[i] == providerOpenMeteo {
+			provider = openMeteoWeatherResolver{geocoder: geocoder}
+		}
+		if resolver == nil {
+			resolver = provider
+			continue
+		}
+		resolver = FailoverWeatherResolver{Primary: provider, Secondary: resolver}
+	}
+	return resolver
+}
+
+// applyConfig copia para a configuração os prazos e as tentativas por provedor
+func (p *ResolutionPolicy) applyConfig(cfg *Config) {
+	if p == nil {
+		return
+	}
+	for name, provider := range p.Providers {
+		if provider.Timeout != nil {
+			if cfg.ProviderTimeouts == nil {
+				cfg.ProviderTimeouts = make(map[string]time.Duration)
+			}
+			cfg.ProviderTimeouts[name] = time.Duration(*provider.Timeout)
+		}
+		if provider.MaxAttempts > 0 {
+			if cfg.ProviderMaxAttempts == nil {
+				cfg.ProviderMaxAttempts = make(map[string]int)
+			}
+			cfg.ProviderMaxAttempts[name] = provider.MaxAttempts
+		}
+	}
+}
+
+// applyCaches substitui os TTLs dos caches e o limite de dados expirados.
+// A remoção periódica mantém o intervalo calculado na criação, o que só
+// atrasa a liberação das entradas; get e getStale usam sempre o TTL novo.
+func (p *ResolutionPolicy) applyCaches(cepCache *CEPCache, weatherCache *WeatherCache) {
+	if p == nil {
+		return
+	}
+	if p.Cache.CEPTTL != nil {
+		cepCache.ttl = time.Duration(*p.Cache.CEPTTL)
+	}
+	if p.Cache.WeatherTTL != nil {
+		weatherCache.ttl = time.Duration(*p.Cache.WeatherTTL)
+	}
+	if p.Cache.MaxStale != nil {
+		weatherCache.maxStale = time.Duration(*p.Cache.MaxStale)
+	}
+}
+
+// circuitBreaker cria o breaker do provedor com o limite e o cooldown da
+// política, completando com CIRCUIT_BREAKER_THRESHOLD e
+// CIRCUIT_BREAKER_COOLDOWN o que ela não informa
+func (p *ResolutionPolicy) circuitBreaker(provider string) *circuitBreaker {
+	if p == nil || (p.Breaker.Threshold == nil && p.Breaker.Cooldown == nil) {
+		return newCircuitBreakerFromEnv(provider)
+	}
+	threshold := envInt("CIRCUIT_BREAKER_THRESHOLD", defaultBreakerThreshold)
+	if p.Breaker.Threshold != nil {
+		threshold = *p.Breaker.Threshold
+	}
+	if threshold == 0 {
+		return nil
+	}
+	cooldown := envDuration("CIRCUIT_BREAKER_COOLDOWN", defaultBreakerCooldown)
+	if p.Breaker.Cooldown != nil {
+		cooldown = time.Duration(*p.Breaker.Cooldown)
+	}
+	return newCircuitBreaker(provider, threshold, cooldown)
+}
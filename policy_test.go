@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// representativePolicy inverte as ordens padrão e ajusta prazos, tentativas,
+// TTLs e o circuit breaker
+const representativePolicy = `{
+	"cep_providers": ["brasilapi", "viacep"],
+	"weather_providers": ["openmeteo", "wttr"],
+	"providers": {
+		"viacep": {"timeout": "2s", "max_attempts": 1},
+		"brasilapi": {"timeout": "4s"},
+		"wttr": {"max_attempts": 2},
+		"geocoder": {"timeout": "1500ms"}
+	},
+	"cache": {"cep_ttl": "12h", "weather_ttl": "5m", "max_stale": "30m"},
+	"breaker": {"threshold": 3, "cooldown": "10s"}
+}`
+
+func TestResolutionPolicyApplied(t *testing.T) {
+	policy, err := parseResolutionPolicy([]byte(representativePolicy))
+	if err != nil {
+		t.Fatalf("parseResolutionPolicy retornou erro: %v", err)
+	}
+
+	t.Run("Ordem dos provedores de CEP", func(t *testing.T) {
+		expected := ChainedCEPResolver{Primary: brasilAPICEPResolver{}, Secondary: viaCEPResolver{}}
+		if got := policy.cepResolver(); !reflect.DeepEqual(got, expected) {
+			t.Errorf("cepResolver() = %#v, want %#v", got, expected)
+		}
+	})
+
+	t.Run("Ordem dos provedores de temperatura", func(t *testing.T) {
+		geocoder := &stubGeocoder{}
+		expected := FailoverWeatherResolver{Primary: openMeteoWeatherResolver{geocoder: geocoder}, Secondary: wttrWeatherResolver{}}
+		if got := policy.weatherResolver(geocoder); !reflect.DeepEqual(got, expected) {
+			t.Errorf("weatherResolver() = %#v, want %#v", got, expected)
+		}
+	})
+
+	t.Run("Prazos por provedor", func(t *testing.T) {
+		cfg := Config{ViaCEPTimeout: 5 * time.Second, WeatherTimeout: 8 * time.Second, GeocoderTimeout: 5 * time.Second}
+		policy.applyConfig(&cfg)
+
+		tests := []struct {
+			provider string
+			fallback time.Duration
+			expected time.Duration
+		}{
+			{providerViaCEP, cfg.ViaCEPTimeout, 2 * time.Second},
+			{providerBrasilAPI, cfg.ViaCEPTimeout, 4 * time.Second},
+			{providerWttr, cfg.WeatherTimeout, 8 * time.Second},
+			{providerOpenMeteo, cfg.WeatherTimeout, 8 * time.Second},
+			{providerGeocoder, cfg.GeocoderTimeout, 1500 * time.Millisecond},
+		}
+		for _, tt := range tests {
+			if got := cfg.timeoutFor(tt.provider, tt.fallback); got != tt.expected {
+				t.Errorf("timeoutFor(%s) = %v, want %v", tt.provider, got, tt.expected)
+			}
+		}
+	})
+
+	t.Run("Tentativas por provedor", func(t *testing.T) {
+		useRetryBaseDelay(t, time.Millisecond)
+		cfg := config
+		cfg.ProviderTimeouts, cfg.ProviderMaxAttempts = nil, nil
+		policy.applyConfig(&cfg)
+		useConfig(t, cfg)
+
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		// O servidor de teste responde como o wttr.in
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		upstreamProviders[req.URL.Hostname()] = providerWttr
+		t.Cleanup(func() { delete(upstreamProviders, req.URL.Hostname()) })
+
+		resp, err := upstreamDoer{}.Do(req)
+		if err != nil {
+			t.Fatalf("Do retornou erro: %v", err)
+		}
+		resp.Body.Close()
+		if got := atomic.LoadInt32(&attempts); got != 2 {
+			t.Errorf("tentativas = %d, want 2", got)
+		}
+	})
+
+	t.Run("TTLs dos caches", func(t *testing.T) {
+		cepCache := NewCEPCache(time.Hour)
+		t.Cleanup(cepCache.Stop)
+		weatherCache := NewWeatherCache(time.Minute)
+		t.Cleanup(weatherCache.Stop)
+
+		policy.applyCaches(cepCache, weatherCache)
+		if cepCache.ttl != 12*time.Hour || weatherCache.ttl != 5*time.Minute || weatherCache.maxStale != 30*time.Minute {
+			t.Errorf("ttl do CEP = %v, ttl da temperatura = %v, maxStale = %v, want 12h, 5m e 30m", cepCache.ttl, weatherCache.ttl, weatherCache.maxStale)
+		}
+	})
+
+	t.Run("Circuit breaker", func(t *testing.T) {
+		t.Setenv("CIRCUIT_BREAKER_THRESHOLD", "7")
+		b := policy.circuitBreaker(providerViaCEP)
+		if b == nil || b.threshold != 3 || b.cooldown != 10*time.Second {
+			t.Errorf("breaker = %+v, want limite 3 e cooldown 10s", b)
+		}
+	})
+}
+
+func TestResolutionPolicyDefaults(t *testing.T) {
+	var policy *ResolutionPolicy
+
+	expectedCEP := ChainedCEPResolver{Primary: viaCEPResolver{}, Secondary: brasilAPICEPResolver{}}
+	if got := policy.cepResolver(); !reflect.DeepEqual(got, expectedCEP) {
+		t.Errorf("cepResolver() = %#v, want %#v", got, expectedCEP)
+	}
+	expectedWeather := FailoverWeatherResolver{Primary: wttrWeatherResolver{}, Secondary: openMeteoWeatherResolver{}}
+	if got := policy.weatherResolver(nil); !reflect.DeepEqual(got, expectedWeather) {
+		t.Errorf("weatherResolver() = %#v, want %#v", got, expectedWeather)
+	}
+
+	// Um único provedor dispensa a cadeia
+	single := &ResolutionPolicy{CEPProviders: []string{providerBrasilAPI}}
+	if got := single.cepResolver(); got != (brasilAPICEPResolver{}) {
+		t.Errorf("cepResolver() = %#v, want brasilAPICEPResolver", got)
+	}
+
+	// Sem breaker na política, valem as variáveis de ambiente
+	t.Setenv("CIRCUIT_BREAKER_THRESHOLD", "0")
+	if b := single.circuitBreaker(providerViaCEP); b != nil {
+		t.Errorf("breaker = %+v, want nil", b)
+	}
+
+	cfg := Config{ViaCEPTimeout: 5 * time.Second}
+	policy.applyConfig(&cfg)
+	if got := cfg.maxAttemptsFor(providerViaCEP); got != upstreamMaxAttempts {
+		t.Errorf("maxAttemptsFor = %d, want %d", got, upstreamMaxAttempts)
+	}
+}
+
+func TestParseResolutionPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		expected string
+	}{
+		{"JSON malformado", `{"cep_providers": [`, "unexpected EOF"},
+		{"Campo desconhecido", `{"cep_provider": ["viacep"]}`, `unknown field "cep_provider"`},
+		{"Provedor de CEP desconhecido", `{"cep_providers": ["correios"]}`, `cep_providers: unknown provider "correios"`},
+		{"Provedor de temperatura na cadeia de CEP", `{"cep_providers": ["wttr"]}`, `cep_providers: unknown provider "wttr"`},
+		{"Provedor repetido", `{"weather_providers": ["wttr", "wttr"]}`, `weather_providers: provider "wttr" listed twice`},
+		{"Provedor desconhecido nos ajustes", `{"providers": {"correios": {"timeout": "1s"}}}`, `providers: unknown provider "correios"`},
+		{"Duração inválida", `{"providers": {"viacep": {"timeout": "rápido"}}}`, `invalid duration "rápido"`},
+		{"Duração numérica", `{"providers": {"viacep": {"timeout": 5}}}`, `duration must be a string`},
+		{"Prazo negativo", `{"providers": {"viacep": {"timeout": "-1s"}}}`, "providers.viacep: timeout must not be negative"},
+		{"Tentativas demais", `{"providers": {"wttr": {"max_attempts": 11}}}`, "providers.wttr: max_attempts must be between 1 and 10"},
+		{"Tentativas do geocodificador", `{"providers": {"geocoder": {"max_attempts": 2}}}`, "providers.geocoder: max_attempts is not supported"},
+		{"TTL zero", `{"cache": {"cep_ttl": "0s"}}`, "cache.cep_ttl must be positive"},
+		{"maxStale negativo", `{"cache": {"max_stale": "-1m"}}`, "cache.max_stale must not be negative"},
+		{"Limite negativo", `{"breaker": {"threshold": -1}}`, "breaker.threshold must not be negative"},
+		{"Cooldown zero", `{"breaker": {"cooldown": "0s"}}`, "breaker.cooldown must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseResolutionPolicy([]byte(tt.policy))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("erro = %v, want contendo %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadResolutionPolicyFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(representativePolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		inline      string
+		file        string
+		expectNil   bool
+		expectedErr string
+	}{
+		{"Sem política", "", "", true, ""},
+		{"Política no ambiente", `{"cep_providers": ["brasilapi"]}`, "", false, ""},
+		{"Política em arquivo", "", path, false, ""},
+		{"Arquivo inexistente", "", filepath.Join(t.TempDir(), "missing.json"), true, "reading RESOLUTION_POLICY_FILE"},
+		{"Ambiente e arquivo juntos", `{}`, path, true, "mutually exclusive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RESOLUTION_POLICY", tt.inline)
+			t.Setenv("RESOLUTION_POLICY_FILE", tt.file)
+
+			policy, err := loadResolutionPolicyFromEnv()
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("erro = %v, want contendo %q", err, tt.expectedErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadResolutionPolicyFromEnv retornou erro: %v", err)
+			}
+			if (policy == nil) != tt.expectNil {
+				t.Errorf("política = %+v, want nil = %v", policy, tt.expectNil)
+			}
+		})
+	}
+}
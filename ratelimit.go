@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultRateLimitBurst é a rajada permitida quando RATE_LIMIT_BURST não é definido
+	defaultRateLimitBurst = 10
+	// rateLimitIdleTimeout é o tempo sem requisições após o qual o limitador de um IP é descartado
+	rateLimitIdleTimeout = 3 * time.Minute
+	// rateLimitCleanupInterval é o intervalo entre as limpezas dos limitadores por IP
+	rateLimitCleanupInterval = time.Minute
+)
+
+// clientLimiter guarda o limitador de um IP e o momento do último uso
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter limita as requisições com token bucket, de forma global ou por
+// IP do cliente, para evitar que rajadas de tráfego causem bloqueios nos
+// upstreams. As rotas em routes têm taxa e contagem próprias.
+type rateLimiter struct {
+	rps     float64
+	burst   int
+	perIP   bool
+	global  *rate.Limiter
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+	now     func() time.Time
+	routes  map[string]*rateLimiter
+}
+
+// newRateLimiter cria um limitador com a taxa e a rajada informadas. Uma taxa
+// zero desativa o limite.
+func newRateLimiter(rps float64, burst int, perIP bool) *rateLimiter {
+	l := &rateLimiter{
+		rps:     rps,
+		burst:   burst,
+		perIP:   perIP,
+		clients: make(map[string]*clientLimiter),
+		now:     time.Now,
+	}
+	if rps > 0 {
+		l.global = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+	return l
+}
+
+// withRoute define a taxa de uma rota, com a mesma rajada e o mesmo modo
+// (global ou por IP) do limitador. A rota é o padrão registrado no ServeMux,
+// sem a barra final: "/weatherbycep" vale para "/weatherbycep" e
+// "/weatherbycep/". Uma taxa zero deixa a rota sem limite.
+func (l *rateLimiter) withRoute(route string, rps float64) *rateLimiter {
+	if l.routes == nil {
+		l.routes = make(map[string]*rateLimiter)
+	}
+	l.routes[strings.TrimSuffix(route, "/")] = newRateLimiter(rps, l.burst, l.perIP)
+	return l
+}
+
+// newRateLimiterFromEnv lê RATE_LIMIT_RPS, RATE_LIMIT_BURST,
+// RATE_LIMIT_PER_IP e RATE_LIMIT_ROUTES. Sem uma taxa configurada o limite
+// fica desativado.
+func newRateLimiterFromEnv() *rateLimiter {
+	rps := envFloat("RATE_LIMIT_RPS", 0, positive[float64])
+	burst := envIntWhere("RATE_LIMIT_BURST", defaultRateLimitBurst, positive[int])
+
+	l := newRateLimiter(rps, burst, envBool("RATE_LIMIT_PER_IP"))
+	for route, routeRPS := range parseRouteRateLimits(os.Getenv("RATE_LIMIT_ROUTES")) {
+		l.withRoute(route, routeRPS)
+	}
+	if l.perIP && (l.rps > 0 || len(l.routes) > 0) {
+		go l.cleanupLoop(rateLimitCleanupInterval)
+	}
+	return l
+}
+
+// parseRouteRateLimits lê a lista de taxas por rota no formato
+// "rota=rps,rota=rps" (ex.: "/weatherbycep=5,/validate=100"). Entradas
+// inválidas são ignoradas com um aviso no log.
+func parseRouteRateLimits(value string) map[string]float64 {
+	limits := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, rawRPS, ok := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		rps, err := strconv.ParseFloat(strings.TrimSpace(rawRPS), 64)
+		if !ok || !strings.HasPrefix(route, "/") || err != nil || math.IsNaN(rps) || math.IsInf(rps, 0) || rps < 0 {
+			logger.Warn("entrada de RATE_LIMIT_ROUTES inválida, ignorando", "value", entry)
+			continue
+		}
+		limits[route] = rps
+	}
+	return limits
+}
+
+// forRoute retorna o limitador da rota da requisição, ou o padrão quando a
+// rota não tem taxa própria
+func (l *rateLimiter) forRoute(r *http.Request) *rateLimiter {
+	if route, ok := l.routes[strings.TrimSuffix(r.Pattern, "/")]; ok {
+		return route
+	}
+	return l
+}
+
+// limiterFor retorna o limitador aplicável à requisição
+func (l *rateLimiter) limiterFor(r *http.Request) *rate.Limiter {
+	if !l.perIP {
+		return l.global
+	}
+
+	ip := clientIP(r, trustedProxies)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	client, ok := l.clients[ip]
+	if !ok {
+		client = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = l.now()
+	return client.limiter
+}
+
+// cleanupLoop descarta periodicamente os limitadores de IPs inativos
+func (l *rateLimiter) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.cleanup()
+	}
+}
+
+// cleanup remove os limitadores sem uso há mais de rateLimitIdleTimeout,
+// inclusive os das rotas com taxa própria
+func (l *rateLimiter) cleanup() {
+	l.mu.Lock()
+	now := l.now()
+	for ip, client := range l.clients {
+		if now.Sub(client.lastSeen) > rateLimitIdleTimeout {
+			delete(l.clients, ip)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, route := range l.routes {
+		route.cleanup()
+	}
+}
+
+// retryAfter é o tempo, em segundos, até um novo token ficar disponível
+func (l *rateLimiter) retryAfter() int {
+	return int(math.Max(1, math.Ceil(1/l.rps)))
+}
+
+// Wrap aplica o limite de requisições ao handler informado, usando a taxa
+// da rota casada pelo ServeMux quando ela tiver uma própria
+func (l *rateLimiter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := l.forRoute(r)
+		if limiter.rps > 0 && !limiter.limiterFor(r).Allow() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(limiter.retryAfter()))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "rate limit exceeded"})
+			return
+		}
+		next(w, r)
+	}
+}
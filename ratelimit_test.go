@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// okHandler responde 200 sem fazer nada
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestRateLimiterGlobal(t *testing.T) {
+	handler := newRateLimiter(1, 3, false).Wrap(okHandler)
+
+	var ok, limited int
+	for i := 0; i < 10; i++ {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+		switch rr.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+			limited++
+			if rr.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q, want 1", rr.Header().Get("Retry-After"))
+			}
+			if body := rr.Body.String(); body != "{\"message\":\"rate limit exceeded\"}\n" {
+				t.Errorf("corpo inesperado: %s", body)
+			}
+		default:
+			t.Fatalf("status inesperado: %d", rr.Code)
+		}
+	}
+
+	// A rajada permite as 3 primeiras; as demais chegam antes de novos tokens
+	if ok != 3 || limited != 7 {
+		t.Errorf("ok = %d, limited = %d, want 3 e 7", ok, limited)
+	}
+}
+
+func TestRateLimiterPerIP(t *testing.T) {
+	handler := newRateLimiter(0.5, 1, true).Wrap(okHandler)
+
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	if code := request("10.0.0.1:1234"); code != http.StatusOK {
+		t.Errorf("primeira requisição do IP A: %d, want 200", code)
+	}
+	if code := request("10.0.0.1:5678"); code != http.StatusTooManyRequests {
+		t.Errorf("segunda requisição do IP A: %d, want 429", code)
+	}
+	if code := request("10.0.0.2:1234"); code != http.StatusOK {
+		t.Errorf("requisição do IP B não deveria ser afetada pelo IP A: %d", code)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	handler := newRateLimiter(0, defaultRateLimitBurst, false).Wrap(okHandler)
+	for i := 0; i < 50; i++ {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("limite desativado não deveria bloquear: %d", rr.Code)
+		}
+	}
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(1, 1, true)
+	limiter.now = func() time.Time { return now }
+
+	for _, addr := range []string{"10.0.0.1:1", "10.0.0.2:1"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		limiter.limiterFor(req)
+		now = now.Add(2 * time.Minute)
+	}
+
+	limiter.cleanup()
+	if _, ok := limiter.clients["10.0.0.1"]; ok {
+		t.Error("limitador inativo deveria ter sido removido")
+	}
+	if _, ok := limiter.clients["10.0.0.2"]; !ok {
+		t.Error("limitador ativo não deveria ter sido removido")
+	}
+}
+
+func TestRateLimiterPerRoute(t *testing.T) {
+	limiter := newRateLimiter(0, 2, false).withRoute("/weatherbycep", 0.5).withRoute("/validate/", 100)
+	mux := http.NewServeMux()
+	mux.Handle("/weatherbycep/", limiter.Wrap(okHandler))
+	mux.Handle("/validate/", limiter.Wrap(okHandler))
+	mux.Handle("/ufs", limiter.Wrap(okHandler))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	// A rajada da rota de temperatura permite 2 requisições
+	for i := 0; i < 2; i++ {
+		if rr := request("/weatherbycep/01310100"); rr.Code != http.StatusOK {
+			t.Fatalf("requisição %d à rota de temperatura: %d, want 200", i+1, rr.Code)
+		}
+	}
+	rr := request("/weatherbycep/01310100")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("rota de temperatura acima do limite: %d, want 429", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2 (taxa da rota)", got)
+	}
+
+	// A rota de validação tem contagem própria e continua atendendo
+	if rr := request("/validate/01310100"); rr.Code != http.StatusOK {
+		t.Errorf("rota de validação não deveria ser afetada: %d", rr.Code)
+	}
+	// Rotas sem taxa própria seguem o limite padrão, aqui desativado
+	for i := 0; i < 10; i++ {
+		if rr := request("/ufs"); rr.Code != http.StatusOK {
+			t.Fatalf("rota sem taxa própria: %d, want 200", rr.Code)
+		}
+	}
+}
+
+func TestParseRouteRateLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]float64
+		warns    int
+	}{
+		{"Vazio", "", map[string]float64{}, 0},
+		{"Várias rotas", "/weatherbycep=5, /validate=100", map[string]float64{"/weatherbycep": 5, "/validate": 100}, 0},
+		{"Rota sem limite", "/validate=0", map[string]float64{"/validate": 0}, 0},
+		{"Entradas inválidas ignoradas", "/weatherbycep=5,validate=1,/cep=-1,/rpc=dez,/ufs", map[string]float64{"/weatherbycep": 5}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := useLogger(t)
+			if got := parseRouteRateLimits(tt.value); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseRouteRateLimits(%q) = %v, want %v", tt.value, got, tt.expected)
+			}
+			if lines := logLines(t, buf); len(lines) != tt.warns {
+				t.Errorf("avisos = %d, want %d (logs: %v)", len(lines), tt.warns, lines)
+			}
+		})
+	}
+}
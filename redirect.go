@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultMaxRedirects é o número máximo de redirecionamentos seguidos por requisição
+const defaultMaxRedirects = 5
+
+// errRedirectNotAllowed indica um redirecionamento para um host não autorizado
+var errRedirectNotAllowed = errors.New("redirect to unapproved host")
+
+// errRedirectInsecure indica um redirecionamento de HTTPS para HTTP
+var errRedirectInsecure = errors.New("redirect downgrades https to http")
+
+// redirectPolicy controla quais redirecionamentos dos upstreams são seguidos.
+// Redirecionamentos para o mesmo host da requisição original são permitidos;
+// outros hosts precisam estar na lista de permitidos. Em qualquer host, a
+// troca de HTTPS por HTTP só é seguida com allowInsecure.
+type redirectPolicy struct {
+	maxRedirects  int
+	allowedHosts  map[string]bool
+	allowInsecure bool
+}
+
+// newRedirectPolicyFromEnv lê REDIRECT_MAX, REDIRECT_ALLOWED_HOSTS (lista
+// separada por vírgulas) e ALLOW_INSECURE_FALLBACK
+func newRedirectPolicyFromEnv() *redirectPolicy {
+	policy := &redirectPolicy{
+		maxRedirects:  envInt("REDIRECT_MAX", defaultMaxRedirects),
+		allowedHosts:  make(map[string]bool),
+		allowInsecure: config.AllowInsecureFallback,
+	}
+
+	for _, host := range strings.Split(os.Getenv("REDIRECT_ALLOWED_HOSTS"), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			policy.allowedHosts[host] = true
+		}
+	}
+
+	return policy
+}
+
+// check implementa a assinatura de http.Client.CheckRedirect
+func (p *redirectPolicy) check(req *http.Request, via []*http.Request) error {
+	if len(via) > p.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", p.maxRedirects)
+	}
+
+	// Mesmo no host original, seguir para HTTP exporia a consulta em texto puro
+	previous := via[len(via)-1].URL
+	if previous.Scheme == "https" && req.URL.Scheme == "http" && !p.allowInsecure {
+		logger.Warn("redirecionamento inseguro bloqueado", "from", previous.Redacted(), "to", req.URL.Redacted())
+		return fmt.Errorf("%w: %s", errRedirectInsecure, req.URL.Host)
+	}
+
+	origin := strings.ToLower(via[0].URL.Hostname())
+	target := strings.ToLower(req.URL.Hostname())
+	if target == origin || p.allowedHosts[target] {
+		return nil
+	}
+
+	logger.Warn("redirecionamento bloqueado", "from", origin, "to", target)
+	return fmt.Errorf("%w: %s", errRedirectNotAllowed, target)
+}
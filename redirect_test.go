@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPolicyRejectsDisallowedHost(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	// Mesmo servidor, mas acessado por outro nome de host
+	targetURL, _ := url.Parse(target.URL)
+	otherHost := "http://localhost:" + targetURL.Port() + "/ws/01310100/json/"
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/outro-host":
+			http.Redirect(w, r, otherHost, http.StatusFound)
+		case "/mesmo-host":
+			http.Redirect(w, r, "/destino", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		allowedHosts map[string]bool
+		expectErr    bool
+	}{
+		{"Host não autorizado", "/outro-host", map[string]bool{}, true},
+		{"Host autorizado na lista", "/outro-host", map[string]bool{"localhost": true}, false},
+		{"Mesmo host", "/mesmo-host", map[string]bool{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &redirectPolicy{maxRedirects: defaultMaxRedirects, allowedHosts: tt.allowedHosts}
+			client := &http.Client{CheckRedirect: policy.check}
+
+			resp, err := client.Get(upstream.URL + tt.path)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if tt.expectErr {
+				if !errors.Is(err, errRedirectNotAllowed) {
+					t.Fatalf("esperado errRedirectNotAllowed, got %v", err)
+				}
+				if !strings.Contains(err.Error(), "localhost") {
+					t.Errorf("o erro deveria indicar o host recusado: %v", err)
+				}
+				return
+			}
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Errorf("redirecionamento deveria ser seguido: resp=%v err=%v", resp, err)
+			}
+		})
+	}
+}
+
+func TestRedirectPolicyMaxRedirects(t *testing.T) {
+	policy := &redirectPolicy{maxRedirects: 1, allowedHosts: map[string]bool{}}
+
+	first := httptest.NewRequest("GET", "https://viacep.com.br/a", nil)
+	second := httptest.NewRequest("GET", "https://viacep.com.br/b", nil)
+	next := httptest.NewRequest("GET", "https://viacep.com.br/c", nil)
+
+	if err := policy.check(second, []*http.Request{first}); err != nil {
+		t.Errorf("primeiro redirecionamento deveria ser permitido: %v", err)
+	}
+	if err := policy.check(next, []*http.Request{first, second}); err == nil {
+		t.Error("redirecionamentos acima do limite deveriam ser recusados")
+	}
+}
+
+func TestNewRedirectPolicyFromEnv(t *testing.T) {
+	t.Setenv("REDIRECT_MAX", "2")
+	t.Setenv("REDIRECT_ALLOWED_HOSTS", "mirror.example.com, CDN.example.com")
+
+	policy := newRedirectPolicyFromEnv()
+	if policy.maxRedirects != 2 {
+		t.Errorf("maxRedirects = %d, want 2", policy.maxRedirects)
+	}
+	for _, host := range []string{"mirror.example.com", "cdn.example.com"} {
+		if !policy.allowedHosts[host] {
+			t.Errorf("host %s deveria estar permitido", host)
+		}
+	}
+}
+
+func TestRedirectPolicySchemeDowngrade(t *testing.T) {
+	tests := []struct {
+		name          string
+		from, to      string
+		allowInsecure bool
+		expected      error
+	}{
+		{"HTTPS para HTTP no mesmo host", "https://viacep.com.br/ws/01310100/json/", "http://viacep.com.br/ws/01310100/json/", false, errRedirectInsecure},
+		{"HTTPS para HTTP com fallback inseguro", "https://viacep.com.br/ws/01310100/json/", "http://viacep.com.br/ws/01310100/json/", true, nil},
+		{"HTTP para HTTPS", "http://viacep.com.br/ws/01310100/json/", "https://viacep.com.br/ws/01310100/json/", false, nil},
+		{"HTTPS para HTTPS", "https://viacep.com.br/a", "https://viacep.com.br/b", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &redirectPolicy{maxRedirects: defaultMaxRedirects, allowedHosts: map[string]bool{}, allowInsecure: tt.allowInsecure}
+			from := httptest.NewRequest("GET", tt.from, nil)
+			to := httptest.NewRequest("GET", tt.to, nil)
+
+			if err := policy.check(to, []*http.Request{from}); !errors.Is(err, tt.expected) {
+				t.Errorf("check() = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
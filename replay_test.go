@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Para regravar as fixtures a partir das APIs reais execute:
+//
+//	RECORD_CASSETTES=1 go test -run Replay
+const recordCassettesEnv = "RECORD_CASSETTES"
+
+// recordedRequest identifica uma requisição gravada
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+// recordedResponse guarda a resposta real devolvida pelo upstream
+type recordedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// interaction representa um par requisição/resposta gravado
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+// cassette é o conjunto de interações gravadas para um cenário de teste
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+// replayTransport grava interações reais ou as reproduz a partir de um cassette
+type replayTransport struct {
+	mu        sync.Mutex
+	path      string
+	recording bool
+	real      http.RoundTripper
+	cassette  cassette
+	requests  []string
+}
+
+func (rt *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.requests = append(rt.requests, req.URL.String())
+	rt.mu.Unlock()
+
+	if rt.recording {
+		return rt.record(req)
+	}
+
+	for _, it := range rt.cassette.Interactions {
+		if it.Request.Method == req.Method && it.Request.URL == req.URL.String() {
+			return it.Response.toHTTP(req), nil
+		}
+	}
+	return nil, fmt.Errorf("nenhuma interação gravada para %s %s", req.Method, req.URL)
+}
+
+// record executa a requisição real e guarda a resposta no cassette
+func (rt *replayTransport) record(req *http.Request) (*http.Response, error) {
+	resp, err := rt.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := recordedResponse{
+		Status:  resp.StatusCode,
+		Headers: map[string]string{"Content-Type": resp.Header.Get("Content-Type")},
+		Body:    string(body),
+	}
+
+	rt.mu.Lock()
+	rt.cassette.Interactions = append(rt.cassette.Interactions, interaction{
+		Request:  recordedRequest{Method: req.Method, URL: req.URL.String()},
+		Response: recorded,
+	})
+	rt.mu.Unlock()
+
+	return recorded.toHTTP(req), nil
+}
+
+// save persiste o cassette gravado em disco
+func (rt *replayTransport) save() error {
+	data, err := json.MarshalIndent(rt.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(rt.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(rt.path, append(data, '\n'), 0o644)
+}
+
+// toHTTP converte a resposta gravada em um *http.Response
+func (r recordedResponse) toHTTP(req *http.Request) *http.Response {
+	header := make(http.Header)
+	for k, v := range r.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		StatusCode:    r.Status,
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// useCassette substitui o transporte do httpClient pelo cassette informado
+// durante o teste, restaurando o transporte original ao final
+func useCassette(t *testing.T, name string) *replayTransport {
+	t.Helper()
+
+	rt := &replayTransport{
+		path:      filepath.Join("testdata", "cassettes", name+".json"),
+		recording: os.Getenv(recordCassettesEnv) != "",
+		real:      httpClient.Transport,
+	}
+
+	if !rt.recording {
+		data, err := os.ReadFile(rt.path)
+		if err != nil {
+			t.Fatalf("erro ao ler cassette %s: %v", rt.path, err)
+		}
+		if err := json.Unmarshal(data, &rt.cassette); err != nil {
+			t.Fatalf("erro ao decodificar cassette %s: %v", rt.path, err)
+		}
+	}
+
+	original := httpClient.Transport
+	httpClient.Transport = rt
+	t.Cleanup(func() {
+		httpClient.Transport = original
+		if rt.recording {
+			if err := rt.save(); err != nil {
+				t.Errorf("erro ao salvar cassette %s: %v", rt.path, err)
+			}
+		}
+	})
+
+	return rt
+}
+
+func TestWeatherByCEPHandlerReplay(t *testing.T) {
+	tests := []struct {
+		name           string
+		cassette       string
+		path           string
+		expectedStatus int
+		expectedTempC  float64
+		expectedCity   string
+		expectedCEP    string
+	}{
+		{
+			name:           "São Paulo",
+			cassette:       "01310100",
+			path:           "/weatherbycep/01310100",
+			expectedStatus: http.StatusOK,
+			expectedTempC:  22,
+			expectedCity:   "São Paulo",
+			expectedCEP:    "01310-100",
+		},
+		{
+			name:           "Rio de Janeiro com hífen",
+			cassette:       "20040002",
+			path:           "/weatherbycep/20040-002",
+			expectedStatus: http.StatusOK,
+			expectedTempC:  29,
+			expectedCity:   "Rio de Janeiro",
+			expectedCEP:    "20040-002",
+		},
+		{
+			// 99999999 não serve aqui: knownInvalidCEPs o recusa sem consultar o ViaCEP
+			name:           "CEP não encontrado",
+			cassette:       "01999999",
+			path:           "/weatherbycep/01999999",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := useCassette(t, tt.cassette)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler retornou status code errado: got %v want %v (%s)",
+					rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			// Um cassette que nunca é reproduzido não testa nada
+			if len(rt.requests) == 0 {
+				t.Fatalf("nenhuma requisição passou pelo cassette %s", tt.cassette)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				WeatherData
+				Address MinAddress `json:"address"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if resp.TempC != tt.expectedTempC {
+				t.Errorf("temp_C incorreta: got %v want %v", resp.TempC, tt.expectedTempC)
+			}
+			if resp.Address.Localidade != tt.expectedCity {
+				t.Errorf("localidade incorreta: got %v want %v", resp.Address.Localidade, tt.expectedCity)
+			}
+			if resp.Address.CEP != tt.expectedCEP {
+				t.Errorf("cep incorreto: got %v want %v", resp.Address.CEP, tt.expectedCEP)
+			}
+		})
+	}
+}
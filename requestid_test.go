@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		incoming   string
+		expectedID string
+		status     int
+	}{
+		{"Sucesso com ID recebido", "/weatherbycep/01310100", "abc-123", "abc-123", 200},
+		{"Erro com ID recebido", "/weatherbycep/99999999", "req.42", "req.42", 404},
+		{"Sucesso sem ID", "/weatherbycep/01310100", "", "", 200},
+		{"Erro sem ID", "/weatherbycep/123", "", "", 422},
+		{"ID inválido é substituído", "/weatherbycep/01310100", "com espaço\ne quebra", "", 200},
+		{"ID muito longo é substituído", "/weatherbycep/01310100", strings.Repeat("a", 129), "", 200},
+	}
+
+	handler := withRequestID(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("status = %d, want %d", rr.Code, tt.status)
+			}
+
+			id := rr.Header().Get("X-Request-ID")
+			if tt.expectedID != "" {
+				if id != tt.expectedID {
+					t.Errorf("X-Request-ID = %q, want %q", id, tt.expectedID)
+				}
+			} else if !uuidV4.MatchString(id) {
+				t.Errorf("X-Request-ID gerado não é um UUID v4: %q", id)
+			}
+		})
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	var seen string
+	handler := withRequestID(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	handler(httptest.NewRecorder(), req)
+
+	if seen != "abc-123" {
+		t.Errorf("requestIDFromContext = %q, want %q", seen, "abc-123")
+	}
+	if id := requestIDFromContext(req.Context()); id != "" {
+		t.Errorf("requestIDFromContext sem middleware = %q, want vazio", id)
+	}
+}
+
+func TestRequestIDInLogs(t *testing.T) {
+	buf := useLogger(t)
+	handler := withRequestID(newAccessLog(logger).Wrap(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})))
+
+	req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	handler(httptest.NewRecorder(), req)
+
+	lines := logLines(t, buf)
+	if len(lines) == 0 {
+		t.Fatal("nenhuma linha de log emitida")
+	}
+	for _, line := range lines {
+		if line["request_id"] != "abc-123" {
+			t.Errorf("linha de log sem request_id: %v", line)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// defaultRequestTimeout é o prazo total de uma requisição, acima do
+// handlerTimeout reservado para os upstreams
+const defaultRequestTimeout = 12 * time.Second
+
+// requestTimeout encerra com 503 as requisições que excedem o prazo total,
+// em vez de deixar a conexão pendurada
+type requestTimeout struct {
+	timeout time.Duration
+}
+
+// newRequestTimeoutFromEnv lê REQUEST_TIMEOUT (ex.: "15s")
+func newRequestTimeoutFromEnv() *requestTimeout {
+	return &requestTimeout{timeout: envDuration("REQUEST_TIMEOUT", defaultRequestTimeout)}
+}
+
+// requestTimeoutBody é o corpo JSON enviado quando o prazo estoura
+var requestTimeoutBody = func() string {
+	body, _ := json.Marshal(ErrorResponse{Message: "request timeout"})
+	return string(body) + "\n"
+}()
+
+// Wrap aplica o prazo ao handler informado. As respostas em NDJSON não
+// passam pelo http.TimeoutHandler, que guarda o corpo inteiro antes de
+// enviá-lo e impediria o streaming; o handler limita o próprio prazo.
+func (t *requestTimeout) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	guarded := http.TimeoutHandler(next, t.timeout, requestTimeoutBody)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if wantsNDJSON(r) {
+			next(w, r)
+			return
+		}
+		guarded.ServeHTTP(timeoutContentTypeWriter{w}, r)
+	}
+}
+
+// timeoutContentTypeWriter anuncia o JSON da resposta de timeout, que o
+// http.TimeoutHandler envia sem Content-Type. Só a resposta 503 sem
+// Content-Type é alterada: as demais, inclusive 204 e 304, mantêm os
+// cabeçalhos definidos pelo handler, que o TimeoutHandler copia antes do
+// WriteHeader.
+type timeoutContentTypeWriter struct {
+	http.ResponseWriter
+}
+
+func (w timeoutContentTypeWriter) WriteHeader(status int) {
+	if status == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap expõe o ResponseWriter original para o http.ResponseController
+func (w timeoutContentTypeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
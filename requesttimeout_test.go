@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowCEPResolver só responde quando o contexto da requisição termina
+type slowCEPResolver struct{}
+
+func (slowCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	select {
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	case <-time.After(2 * time.Second):
+		return nil, &CustomError{Code: 500, Message: "internal server error"}
+	}
+}
+
+func TestRequestTimeoutWrap(t *testing.T) {
+	guard := &requestTimeout{timeout: 50 * time.Millisecond}
+	handler := guard.Wrap(NewWeatherHandler(slowCEPResolver{}, &fakeWeatherResolver{tempC: 20}))
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("handler demorou %v, deveria encerrar no prazo", elapsed)
+	}
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var errorResp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+		t.Fatalf("Resposta de timeout não é um JSON válido: %q (%v)", rr.Body.String(), err)
+	}
+	if errorResp.Message != "request timeout" {
+		t.Errorf("message = %q, want %q", errorResp.Message, "request timeout")
+	}
+}
+
+func TestRequestTimeoutWrapKeepsHandlerResponse(t *testing.T) {
+	guard := &requestTimeout{timeout: time.Second}
+	handler := guard.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("ok"))
+	})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if rr.Code != http.StatusTeapot || rr.Body.String() != "ok" {
+		t.Errorf("resposta = %d %q, want 418 \"ok\"", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want o definido pelo handler", ct)
+	}
+}
+
+func TestRequestTimeoutWrapWithoutContentType(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"Not Modified", http.StatusNotModified},
+		{"No Content", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := &requestTimeout{timeout: time.Second}
+			handler := guard.Wrap(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			})
+
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.status)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "" {
+				t.Errorf("Content-Type = %q, want vazio", ct)
+			}
+		})
+	}
+}
+
+func TestRequestTimeoutWrapKeepsNegotiatedFormat(t *testing.T) {
+	guard := &requestTimeout{timeout: time.Second}
+	handler := guard.Wrap(NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23}))
+
+	req := httptest.NewRequest("GET", "/weatherbycep/01310100", nil)
+	req.Header.Set("Accept", "application/xml")
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "application/xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want application/xml; charset=utf-8", ct)
+	}
+}
+
+func TestRequestTimeoutWrapStreamsNDJSON(t *testing.T) {
+	guard := &requestTimeout{timeout: time.Second}
+	handler := guard.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.Write([]byte("{}\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush retornou erro: %v", err)
+		}
+	})
+
+	req := httptest.NewRequest("POST", "/weatherbycep/batch", nil)
+	req.Header.Set("Accept", ndjsonContentType)
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	if !rr.Flushed {
+		t.Error("resposta NDJSON deveria ser enviada sem passar pelo buffer do TimeoutHandler")
+	}
+}
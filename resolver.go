@@ -0,0 +1,81 @@
+package main
+
+import "context"
+
+// CEPResolver resolve um CEP nos dados de endereço correspondentes
+type CEPResolver interface {
+	ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError)
+}
+
+// WeatherResolver busca os dados de temperatura de uma cidade
+type WeatherResolver interface {
+	ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError)
+}
+
+// viaCEPResolver implementa CEPResolver consultando a API do ViaCEP
+type viaCEPResolver struct{}
+
+func (viaCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	return searchCEP(ctx, cep)
+}
+
+// ChainedCEPResolver consulta o resolver primário e, se ele falhar por
+// indisponibilidade (erro 5xx ou de rede), tenta o secundário. Erros do
+// cliente, como CEP inválido ou inexistente, são retornados sem fallback.
+// Se ambos falharem, o erro do primário é retornado.
+type ChainedCEPResolver struct {
+	Primary   CEPResolver
+	Secondary CEPResolver
+}
+
+func (c ChainedCEPResolver) ResolveCEP(ctx context.Context, cep string) (*CEPData, *CustomError) {
+	data, primaryErr := c.Primary.ResolveCEP(ctx, cep)
+	if primaryErr == nil || primaryErr.Code < 500 {
+		return data, primaryErr
+	}
+	// Sem tempo restante não adianta tentar outro provedor
+	if ctx.Err() != nil {
+		return nil, primaryErr
+	}
+
+	logger.WarnContext(ctx, "provedor de CEP primário falhou, usando o secundário", "cep", formatCEP(cep), "error", primaryErr.Message)
+	data, secondaryErr := c.Secondary.ResolveCEP(ctx, cep)
+	if secondaryErr != nil {
+		logger.ErrorContext(ctx, "provedor de CEP secundário também falhou", "cep", formatCEP(cep), "error", secondaryErr.Message)
+		return nil, primaryErr
+	}
+	return data, nil
+}
+
+// wttrWeatherResolver implementa WeatherResolver consultando o wttr.in
+type wttrWeatherResolver struct{}
+
+func (wttrWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	return getWeatherData(ctx, city, state)
+}
+
+// FailoverWeatherResolver consulta o resolver primário e, se ele falhar,
+// tenta o secundário. Se ambos falharem, o erro do primário é retornado.
+type FailoverWeatherResolver struct {
+	Primary   WeatherResolver
+	Secondary WeatherResolver
+}
+
+func (f FailoverWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	weatherData, primaryErr := f.Primary.ResolveWeather(ctx, city, state)
+	if primaryErr == nil {
+		return weatherData, nil
+	}
+	// Sem tempo restante não adianta tentar outro provedor
+	if ctx.Err() != nil {
+		return nil, primaryErr
+	}
+
+	logger.WarnContext(ctx, "provedor primário falhou, usando o secundário", "city", city, "state", state, "error", primaryErr.Message)
+	weatherData, secondaryErr := f.Secondary.ResolveWeather(ctx, city, state)
+	if secondaryErr != nil {
+		logger.ErrorContext(ctx, "provedor secundário também falhou", "city", city, "state", state, "error", secondaryErr.Message)
+		return nil, primaryErr
+	}
+	return weatherData, nil
+}
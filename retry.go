@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// upstreamMaxAttempts é o número máximo de tentativas em cada chamada aos upstreams
+const upstreamMaxAttempts = 3
+
+// retryBaseDelay é a espera antes da segunda tentativa; dobra a cada nova tentativa
+var retryBaseDelay = 100 * time.Millisecond
+
+// doWithRetry executa a requisição repetindo-a em caso de erro de rede ou
+// resposta 5xx, com backoff exponencial e jitter. Respostas 4xx não são
+// repetidas. A requisição não pode ter corpo, pois é reenviada a cada tentativa.
+func doWithRetry(ctx context.Context, req *http.Request, maxAttempts int) (*http.Response, error) {
+	delay := retryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		resp, err := httpClient.Do(req.WithContext(ctx))
+		if !shouldRetry(resp, err) || attempt >= maxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			logger.WarnContext(ctx, "tentativa falhou", "provider", providerForHost(req.URL.Hostname()), "attempt", attempt, "error", err)
+		} else {
+			logger.WarnContext(ctx, "tentativa falhou", "provider", providerForHost(req.URL.Hostname()), "attempt", attempt, "upstream_status", resp.StatusCode)
+			// Descarta o corpo para que a conexão possa ser reutilizada
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		// Jitter de até 50% evita que várias requisições repitam ao mesmo tempo
+		wait := delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// shouldRetry indica se o resultado de uma tentativa é uma falha transitória
+func shouldRetry(resp *http.Response, err error) bool {
+	// Sem vaga no limitador, repetir apenas disputaria as mesmas vagas
+	if err != nil {
+		return !errors.Is(err, errServerBusy)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useRetryBaseDelay reduz a espera entre as tentativas durante o teste
+func useRetryBaseDelay(t *testing.T, delay time.Duration) {
+	t.Helper()
+	previous := retryBaseDelay
+	retryBaseDelay = delay
+	t.Cleanup(func() { retryBaseDelay = previous })
+}
+
+func TestDoWithRetry(t *testing.T) {
+	useRetryBaseDelay(t, time.Millisecond)
+
+	tests := []struct {
+		name             string
+		statuses         []int
+		maxAttempts      int
+		expectedStatus   int
+		expectedAttempts int32
+	}{
+		{"Sucesso após duas falhas", []int{500, 503}, 3, http.StatusOK, 3},
+		{"Sucesso na primeira tentativa", nil, 3, http.StatusOK, 1},
+		{"Falhas esgotam as tentativas", []int{500, 502, 504}, 3, http.StatusGatewayTimeout, 3},
+		{"4xx não é repetido", []int{404}, 3, http.StatusNotFound, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+				if int(n) <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[n-1])
+					return
+				}
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			resp, err := doWithRetry(ctx, req, tt.maxAttempts)
+			if err != nil {
+				t.Fatalf("doWithRetry retornou erro: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != `{"ok":true}` {
+					t.Errorf("corpo = %s, want {\"ok\":true}", body)
+				}
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.expectedAttempts {
+				t.Errorf("tentativas = %d, want %d", got, tt.expectedAttempts)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryStopsWhenContextDone(t *testing.T) {
+	useRetryBaseDelay(t, time.Hour)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	start := time.Now()
+	if _, err := doWithRetry(ctx, req, 3); err == nil {
+		t.Error("doWithRetry deveria retornar erro quando o contexto termina durante a espera")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("doWithRetry demorou %v para retornar", elapsed)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("tentativas = %d, want 1", got)
+	}
+}
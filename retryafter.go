@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// defaultErrorRetryAfter é o tempo sugerido, em segundos, para o cliente
+// tentar novamente após uma falha do servidor ou dos upstreams
+const defaultErrorRetryAfter = 5
+
+// errorRetryAfter acrescenta Retry-After às respostas 5xx, para que clientes
+// bem-comportados esperem antes de repetir a requisição
+type errorRetryAfter struct {
+	seconds int
+}
+
+// newErrorRetryAfterFromEnv lê ERROR_RETRY_AFTER (em segundos; 0 desativa o header)
+func newErrorRetryAfterFromEnv() *errorRetryAfter {
+	return &errorRetryAfter{seconds: envInt("ERROR_RETRY_AFTER", defaultErrorRetryAfter)}
+}
+
+// Wrap aplica o Retry-After às respostas 5xx do handler informado
+func (e *errorRetryAfter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	if e.seconds == 0 {
+		return next
+	}
+	value := strconv.Itoa(e.seconds)
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(&retryAfterWriter{ResponseWriter: w, value: value}, r)
+	}
+}
+
+// retryAfterWriter define o Retry-After ao escrever um status 5xx, mantendo
+// o valor já definido pelo handler (ex.: no modo de manutenção)
+type retryAfterWriter struct {
+	http.ResponseWriter
+	value string
+}
+
+func (w *retryAfterWriter) WriteHeader(status int) {
+	if status >= http.StatusInternalServerError && w.Header().Get("Retry-After") == "" {
+		w.Header().Set("Retry-After", w.value)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap expõe o ResponseWriter original para o http.ResponseController
+func (w *retryAfterWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
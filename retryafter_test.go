@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorRetryAfterWrap(t *testing.T) {
+	tests := []struct {
+		name       string
+		weatherErr *CustomError
+		path       string
+		expected   string
+	}{
+		{"Falha no upstream (500)", &CustomError{Code: 500, Message: "internal server error"}, "/weatherbycep/01310100", "5"},
+		{"Bad gateway (502)", &CustomError{Code: 502, Message: "bad gateway"}, "/weatherbycep/01310100", "5"},
+		{"Timeout (504)", &CustomError{Code: 504, Message: "upstream timeout"}, "/weatherbycep/01310100", "5"},
+		{"CEP inválido (422)", nil, "/weatherbycep/123", ""},
+		{"CEP não encontrado (404)", nil, "/weatherbycep/20040002", ""},
+		{"Sucesso", nil, "/weatherbycep/01310100", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20, err: tt.weatherErr})
+			rr := httptest.NewRecorder()
+			(&errorRetryAfter{seconds: 5}).Wrap(handler)(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if got := rr.Header().Get("Retry-After"); got != tt.expected {
+				t.Errorf("Retry-After = %q, want %q (status %d)", got, tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestErrorRetryAfterKeepsExistingValue(t *testing.T) {
+	handler := (&errorRetryAfter{seconds: 5}).Wrap((&maintenanceMode{enabled: true, retryAfter: 120}).Wrap(
+		func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if got := rr.Header().Get("Retry-After"); got != "120" {
+		t.Errorf("Retry-After = %q, want %q", got, "120")
+	}
+}
+
+func TestNewErrorRetryAfterFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", defaultErrorRetryAfter},
+		{"30", 30},
+		{"0", 0},
+		{"-1", defaultErrorRetryAfter},
+		{"logo", defaultErrorRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("ERROR_RETRY_AFTER", tt.value)
+			if e := newErrorRetryAfterFromEnv(); e.seconds != tt.expected {
+				t.Errorf("seconds = %d, want %d", e.seconds, tt.expected)
+			}
+		})
+	}
+}
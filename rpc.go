@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Códigos de erro definidos pela especificação JSON-RPC 2.0
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	// rpcServerError é usado para erros da aplicação (CEP não encontrado, falha no upstream, etc.)
+	rpcServerError = -32000
+)
+
+// rpcRequest representa uma chamada JSON-RPC 2.0
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// rpcError representa o objeto de erro de uma resposta JSON-RPC
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// rpcResponse representa uma resposta JSON-RPC 2.0
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// rpcErrorData carrega o status HTTP equivalente ao erro da aplicação
+type rpcErrorData struct {
+	Status int `json:"status"`
+}
+
+// rpcService executa os métodos JSON-RPC com os mesmos resolvers de
+// /weatherbycep, para que os dois endpoints respondam igual para um CEP
+type rpcService struct {
+	cepResolver     CEPResolver
+	weatherResolver WeatherResolver
+}
+
+// rpcMethod executa um método JSON-RPC a partir dos parâmetros brutos
+type rpcMethod func(s rpcService, ctx context.Context, params json.RawMessage) (interface{}, *rpcError)
+
+// rpcMethods registra os métodos disponíveis no endpoint /rpc
+var rpcMethods = map[string]rpcMethod{
+	"weather.byCep": rpcService.weatherByCEP,
+	"cep.lookup":    rpcService.cepLookup,
+}
+
+// nullID é o id usado quando não é possível identificar a requisição
+var nullID = json.RawMessage("null")
+
+// parseCEPParam aceita os parâmetros por nome ({"cep": "..."}) ou por posição (["..."])
+func parseCEPParam(params json.RawMessage) (string, *rpcError) {
+	invalid := &rpcError{Code: rpcInvalidParams, Message: "invalid params"}
+
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 {
+		return "", invalid
+	}
+
+	if params[0] == '[' {
+		var positional []string
+		if err := json.Unmarshal(params, &positional); err != nil || len(positional) != 1 {
+			return "", invalid
+		}
+		return positional[0], nil
+	}
+
+	var named struct {
+		CEP string `json:"cep"`
+	}
+	if err := json.Unmarshal(params, &named); err != nil || named.CEP == "" {
+		return "", invalid
+	}
+	return named.CEP, nil
+}
+
+// rpcErrorFromCustom converte um CustomError em um erro JSON-RPC
+func rpcErrorFromCustom(err *CustomError) *rpcError {
+	return &rpcError{
+		Code:    rpcServerError,
+		Message: err.Message,
+		Data:    rpcErrorData{Status: err.Code},
+	}
+}
+
+// cepLookup implementa o método cep.lookup
+func (s rpcService) cepLookup(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	cep, paramErr := parseCEPParam(params)
+	if paramErr != nil {
+		return nil, paramErr
+	}
+
+	cepData, cepErr := s.cepResolver.ResolveCEP(ctx, cep)
+	if cepErr != nil {
+		return nil, rpcErrorFromCustom(cepErr)
+	}
+	return cepData, nil
+}
+
+// weatherByCEP implementa o método weather.byCep
+func (s rpcService) weatherByCEP(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	cep, paramErr := parseCEPParam(params)
+	if paramErr != nil {
+		return nil, paramErr
+	}
+
+	cepData, cepErr := s.cepResolver.ResolveCEP(ctx, cep)
+	if cepErr != nil {
+		return nil, rpcErrorFromCustom(cepErr)
+	}
+
+	// Como em /weatherbycep, CEPs sem cidade ou UF não consultam a temperatura
+	if !hasCompleteAddress(cepData) {
+		logger.WarnContext(ctx, "endereço incompleto", "cep", formatCEP(cep), "city", cepData.Localidade, "state", cepData.UF)
+		return nil, rpcErrorFromCustom(&CustomError{Code: http.StatusBadGateway, Message: "incomplete address data"})
+	}
+
+	weatherData, weatherErr := s.weatherResolver.ResolveWeather(ctx, cepData.Localidade, cepData.UF)
+	if weatherErr != nil {
+		return nil, rpcErrorFromCustom(weatherErr)
+	}
+
+	return WeatherResponse{
+		WeatherData: *weatherData,
+		Address:     buildAddress(addressModeMin, cepData),
+	}, nil
+}
+
+// handleRPCCall executa uma única chamada, retornando nil para notificações.
+// Cada chamada tem o mesmo prazo de um CEP do lote de /weatherbycep/batch.
+func (s rpcService) handleRPCCall(ctx context.Context, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return &rpcResponse{
+			JSONRPC: "2.0",
+			Error:   &rpcError{Code: rpcInvalidRequest, Message: "invalid request"},
+			ID:      nullID,
+		}
+	}
+
+	method, ok := rpcMethods[req.Method]
+	var result interface{}
+	var callErr *rpcError
+	if !ok {
+		callErr = &rpcError{Code: rpcMethodNotFound, Message: "method not found"}
+	} else {
+		callCtx, cancel := context.WithTimeout(ctx, batchItemTimeout)
+		result, callErr = method(s, callCtx, req.Params)
+		cancel()
+	}
+
+	// Notificações (sem id) não recebem resposta
+	if req.ID == nil {
+		return nil
+	}
+
+	return &rpcResponse{JSONRPC: "2.0", Result: result, Error: callErr, ID: req.ID}
+}
+
+// NewRPCHandler cria o handler das requisições POST para /rpc, seguindo o
+// JSON-RPC 2.0, usando os resolvers informados para o CEP e para a temperatura
+func NewRPCHandler(cepResolver CEPResolver, weatherResolver WeatherResolver) http.HandlerFunc {
+	s := rpcService{cepResolver: cepResolver, weatherResolver: weatherResolver}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			setAllow(w, http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+			return
+		}
+
+		var payload json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			if isBodyTooLarge(err) {
+				writePayloadTooLarge(w)
+				return
+			}
+			writeRPCError(w, &rpcError{Code: rpcParseError, Message: "parse error"})
+			return
+		}
+
+		// Limita o tempo total gasto com os upstreams; a desconexão do cliente
+		// também cancela as chamadas em andamento
+		ctx, cancel := context.WithTimeout(r.Context(), handlerTimeout)
+		defer cancel()
+
+		// Chamada única
+		payload = bytes.TrimSpace(payload)
+		if payload[0] != '[' {
+			resp := s.handleRPCCall(ctx, payload)
+			if resp == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			encodeJSON(w, resp)
+			return
+		}
+
+		// Lote de chamadas, com o mesmo limite de tamanho de /weatherbycep/batch
+		var batch []json.RawMessage
+		if err := json.Unmarshal(payload, &batch); err != nil || len(batch) == 0 {
+			writeRPCError(w, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"})
+			return
+		}
+		if len(batch) > batchMaxCEPs {
+			writeRPCError(w, &rpcError{Code: rpcInvalidRequest, Message: "too many calls"})
+			return
+		}
+
+		responses := s.handleRPCBatch(ctx, batch)
+
+		// Um lote composto apenas de notificações não recebe resposta
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		encodeJSON(w, responses)
+	}
+}
+
+// handleRPCBatch executa as chamadas do lote em paralelo, limitadas como em
+// /weatherbycep/batch, e devolve as respostas na ordem recebida, sem as das
+// notificações
+func (s rpcService) handleRPCBatch(ctx context.Context, batch []json.RawMessage) []*rpcResponse {
+	results := make([]*rpcResponse, len(batch))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batchConcurrency)
+	for i, raw := range batch {
+		wg.Add(1)
+		go func(i int, raw json.RawMessage) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = s.handleRPCCall(ctx, raw)
+		}(i, raw)
+	}
+	wg.Wait()
+
+	responses := make([]*rpcResponse, 0, len(batch))
+	for _, resp := range results {
+		if resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
+
+// writeRPCError responde com um erro JSON-RPC que não pertence a uma chamada
+// identificada, como corpo inválido ou lote recusado
+func writeRPCError(w http.ResponseWriter, rpcErr *rpcError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: nullID})
+}
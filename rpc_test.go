@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// doRPC envia o corpo informado para o handler de /rpc com os resolvers dos upstreams
+func doRPC(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRPCWith(t, NewRPCHandler(viaCEPResolver{}, wttrWeatherResolver{}), body)
+}
+
+// doRPCWith envia o corpo informado para o handler de /rpc indicado
+func doRPCWith(t *testing.T, handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/rpc", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr
+}
+
+func TestRPCHandlerSingleCall(t *testing.T) {
+	useCassette(t, "01310100")
+
+	rr := doRPC(t, `{"jsonrpc":"2.0","method":"weather.byCep","params":{"cep":"01310-100"},"id":7}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  WeatherResponse `json:"result"`
+		Error   *rpcError       `json:"error"`
+		ID      int             `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	if resp.JSONRPC != "2.0" || resp.ID != 7 {
+		t.Errorf("envelope incorreto: jsonrpc=%q id=%d", resp.JSONRPC, resp.ID)
+	}
+	if resp.Error != nil {
+		t.Fatalf("erro inesperado: %+v", resp.Error)
+	}
+	if resp.Result.TempC != 22 {
+		t.Errorf("temp_C incorreta: got %v want 22", resp.Result.TempC)
+	}
+}
+
+func TestRPCHandlerBatch(t *testing.T) {
+	useCassette(t, "01310100")
+
+	rr := doRPC(t, `[
+		{"jsonrpc":"2.0","method":"cep.lookup","params":["01310100"],"id":"a"},
+		{"jsonrpc":"2.0","method":"weather.byCep","params":{"cep":"123"},"id":"b"},
+		{"jsonrpc":"2.0","method":"cep.lookup","params":["01310100"]},
+		{"jsonrpc":"2.0","method":"weather.forecast","id":"c"}
+	]`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var responses []struct {
+		Result *CEPData  `json:"result"`
+		Error  *rpcError `json:"error"`
+		ID     string    `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+
+	// A notificação (sem id) não gera resposta
+	if len(responses) != 3 {
+		t.Fatalf("esperadas 3 respostas, got %d: %s", len(responses), rr.Body.String())
+	}
+
+	if responses[0].ID != "a" || responses[0].Result == nil || responses[0].Result.Localidade != "São Paulo" {
+		t.Errorf("resposta de cep.lookup incorreta: %+v", responses[0])
+	}
+	if responses[1].ID != "b" || responses[1].Error == nil || responses[1].Error.Message != "invalid zipcode" {
+		t.Errorf("resposta de erro incorreta: %+v", responses[1])
+	}
+	if responses[2].ID != "c" || responses[2].Error == nil || responses[2].Error.Code != rpcMethodNotFound {
+		t.Errorf("resposta de método inexistente incorreta: %+v", responses[2])
+	}
+}
+
+func TestRPCHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{"JSON inválido", `{"jsonrpc":`, rpcParseError},
+		{"Lote vazio", `[]`, rpcInvalidRequest},
+		{"Versão ausente", `{"method":"cep.lookup","id":1}`, rpcInvalidRequest},
+		{"Parâmetros inválidos", `{"jsonrpc":"2.0","method":"cep.lookup","params":{},"id":1}`, rpcInvalidParams},
+		{"Método inexistente", `{"jsonrpc":"2.0","method":"cep.delete","id":1}`, rpcMethodNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := doRPC(t, tt.body)
+
+			var resp rpcResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.expectedCode {
+				t.Errorf("erro incorreto: got %+v want code %d", resp.Error, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestRPCHandlerNotificationOnly(t *testing.T) {
+	rr := doRPC(t, `{"jsonrpc":"2.0","method":"cep.lookup","params":["123"]}`)
+	if rr.Code != http.StatusNoContent || rr.Body.Len() != 0 {
+		t.Errorf("notificação deveria retornar 204 sem corpo: got %v %q", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRPCHandlerUsesInjectedResolvers(t *testing.T) {
+	cepResolver := newFakeCEPResolver()
+	cepResolver.data["20040002"] = &CEPData{CEP: "20040-002", Logradouro: "Caixa Postal"}
+	weatherResolver := &fakeWeatherResolver{tempC: 31}
+	handler := NewRPCHandler(cepResolver, weatherResolver)
+
+	rr := doRPCWith(t, handler, `[
+		{"jsonrpc":"2.0","method":"weather.byCep","params":["01310100"],"id":1},
+		{"jsonrpc":"2.0","method":"weather.byCep","params":["20040002"],"id":2}
+	]`)
+
+	var responses []struct {
+		Result *WeatherData `json:"result"`
+		Error  *struct {
+			Message string       `json:"message"`
+			Data    rpcErrorData `json:"data"`
+		} `json:"error"`
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("resposta inesperada: %s (%v)", rr.Body.String(), err)
+	}
+
+	if responses[0].ID != 1 || responses[0].Result == nil || responses[0].Result.TempC != 31 {
+		t.Errorf("weather.byCep deveria usar o resolver injetado: %+v", responses[0])
+	}
+	// CEP sem cidade ou UF recebe o mesmo 502 de /weatherbycep
+	if responses[1].ID != 2 || responses[1].Error == nil || responses[1].Error.Message != "incomplete address data" || responses[1].Error.Data.Status != http.StatusBadGateway {
+		t.Errorf("endereço incompleto deveria retornar 502: %+v", responses[1])
+	}
+	if cepResolver.calls != 2 || weatherResolver.calls != 1 {
+		t.Errorf("chamadas aos resolvers = CEP %d, temperatura %d, want 2 e 1", cepResolver.calls, weatherResolver.calls)
+	}
+}
+
+func TestRPCHandlerRejectsLargeBatch(t *testing.T) {
+	calls := make([]string, batchMaxCEPs+1)
+	for i := range calls {
+		calls[i] = fmt.Sprintf(`{"jsonrpc":"2.0","method":"cep.lookup","params":["01310100"],"id":%d}`, i)
+	}
+	cepResolver := newFakeCEPResolver()
+	rr := doRPCWith(t, NewRPCHandler(cepResolver, &fakeWeatherResolver{tempC: 23}), "["+strings.Join(calls, ",")+"]")
+
+	var resp rpcResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	if resp.Error == nil || resp.Error.Code != rpcInvalidRequest || resp.Error.Message != "too many calls" {
+		t.Errorf("erro = %+v, want invalid request too many calls", resp.Error)
+	}
+	if cepResolver.calls != 0 {
+		t.Errorf("lote recusado não deveria consultar o resolver: %d chamadas", cepResolver.calls)
+	}
+}
+
+func TestRPCHandlerCallTimeout(t *testing.T) {
+	previous := batchItemTimeout
+	batchItemTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { batchItemTimeout = previous })
+
+	handler := NewRPCHandler(blockingCEPResolver{next: newFakeCEPResolver(), blocked: "20040002"}, &fakeWeatherResolver{tempC: 23})
+
+	start := time.Now()
+	rr := doRPCWith(t, handler, `[
+		{"jsonrpc":"2.0","method":"cep.lookup","params":["20040002"],"id":1},
+		{"jsonrpc":"2.0","method":"cep.lookup","params":["01310100"],"id":2}
+	]`)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("lote demorou %v, cada chamada deveria respeitar o prazo", elapsed)
+	}
+
+	var responses []struct {
+		Result *CEPData `json:"result"`
+		Error  *struct {
+			Data rpcErrorData `json:"data"`
+		} `json:"error"`
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("resposta inesperada: %s (%v)", rr.Body.String(), err)
+	}
+	if responses[0].ID != 1 || responses[0].Error == nil || responses[0].Error.Data.Status != http.StatusGatewayTimeout {
+		t.Errorf("chamada bloqueada deveria expirar com 504: %+v", responses[0])
+	}
+	if responses[1].ID != 2 || responses[1].Result == nil || responses[1].Result.Localidade != "São Paulo" {
+		t.Errorf("chamada rápida deveria ser atendida: %+v", responses[1])
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// suggestionOffsets são os deslocamentos testados no último dígito de um CEP
+// não encontrado; a lista curta limita as consultas extras ao upstream
+var suggestionOffsets = []int{-1, 1}
+
+// SuggestionsResponse é a resposta de CEP não encontrado com CEPs vizinhos válidos
+type SuggestionsResponse struct {
+	ErrorResponse
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// parseSuggest lê o parâmetro ?suggest= da query string, desligado por padrão
+func parseSuggest(r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("suggest")
+	if value == "" {
+		return false, true
+	}
+	suggest, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return suggest, true
+}
+
+// suggestCEPs consulta os CEPs que diferem apenas no último dígito (±1) e
+// retorna os que existem. Erros nas consultas são ignorados.
+func suggestCEPs(ctx context.Context, resolver CEPResolver, cep string) []string {
+	cep = formatCEP(cep)
+	lastDigit := int(cep[len(cep)-1] - '0')
+
+	var suggestions []string
+	for _, offset := range suggestionOffsets {
+		digit := lastDigit + offset
+		if digit < 0 || digit > 9 {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		candidate := cep[:len(cep)-1] + strconv.Itoa(digit)
+		cepData, err := resolver.ResolveCEP(ctx, candidate)
+		if err != nil {
+			continue
+		}
+		suggestions = append(suggestions, cepData.CEP)
+	}
+	return suggestions
+}
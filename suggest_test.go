@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSuggestCEPs(t *testing.T) {
+	resolver := &fakeCEPResolver{data: map[string]*CEPData{
+		"01310100": {CEP: "01310-100", Localidade: "São Paulo", UF: "SP"},
+		"01310102": {CEP: "01310-102", Localidade: "São Paulo", UF: "SP"},
+		"20040008": {CEP: "20040-008", Localidade: "Rio de Janeiro", UF: "RJ"},
+	}}
+
+	tests := []struct {
+		name     string
+		cep      string
+		expected []string
+		probes   int
+	}{
+		{"Vizinhos dos dois lados", "01310-101", []string{"01310-100", "01310-102"}, 2},
+		{"Nenhum vizinho", "99999998", nil, 2},
+		{"Último dígito 9", "20040009", []string{"20040-008"}, 1},
+		{"Último dígito 0", "01310090", nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver.calls = 0
+			suggestions := suggestCEPs(context.Background(), resolver, tt.cep)
+			if !reflect.DeepEqual(suggestions, tt.expected) {
+				t.Errorf("suggestCEPs(%q) = %v, want %v", tt.cep, suggestions, tt.expected)
+			}
+			if resolver.calls != tt.probes {
+				t.Errorf("suggestCEPs(%q) fez %d consultas, want %d", tt.cep, resolver.calls, tt.probes)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerSuggestions(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+		calls    int
+	}{
+		{"Com sugestões", "/weatherbycep/01310101?suggest=true", []string{"01310-100"}, 3},
+		{"Sem opt-in", "/weatherbycep/01310101", nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cepResolver := newFakeCEPResolver()
+			handler := NewWeatherHandler(cepResolver, &fakeWeatherResolver{tempC: 23})
+
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != 404 {
+				t.Fatalf("status = %d, want 404", rr.Code)
+			}
+
+			var resp SuggestionsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if resp.Message != "can not find zipcode" {
+				t.Errorf("message = %q, want %q", resp.Message, "can not find zipcode")
+			}
+			if !reflect.DeepEqual(resp.Suggestions, tt.expected) {
+				t.Errorf("suggestions = %v, want %v", resp.Suggestions, tt.expected)
+			}
+			if cepResolver.calls != tt.calls {
+				t.Errorf("resolver chamado %d vezes, want %d", cepResolver.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerInvalidSuggest(t *testing.T) {
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 23})
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310101?suggest=talvez", nil))
+
+	if rr.Code != 400 {
+		t.Errorf("status = %d, want 400", rr.Code)
+	}
+}
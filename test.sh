@@ -5,11 +5,11 @@ echo "================================================="
 
 # Executa os testes
 echo "📋 Executando testes unitários..."
-go test -v
+go test -v ./...
 
 echo ""
 echo "📊 Executando testes com coverage..."
-go test -cover
+go test -cover ./...
 
 echo ""
 echo "⚡ Executando benchmark..."
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+// withUpstreamTimeout limita o contexto ao prazo de um provedor. Sem prazo
+// configurado o contexto é usado como está, sujeito apenas ao prazo da requisição.
+func withUpstreamTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// contextError converte o encerramento do contexto de uma chamada em erro,
+// distinguindo o estouro de prazo (504) do cancelamento pelo cliente
+func contextError(ctx context.Context) *CustomError {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &CustomError{Code: 504, Message: "upstream timeout", Err: weather.ErrUpstreamTimeout}
+	}
+	return &CustomError{Code: 500, Message: "request canceled"}
+}
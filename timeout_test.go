@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+func TestWeatherByCEPHandlerUpstreamStatusMapping(t *testing.T) {
+	tests := []struct {
+		name           string
+		upstream       http.HandlerFunc
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name: "ViaCEP lento",
+			upstream: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(2 * time.Second):
+				}
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedMsg:    "upstream timeout",
+		},
+		{
+			name: "ViaCEP indisponível",
+			upstream: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectedStatus: http.StatusBadGateway,
+			expectedMsg:    "bad gateway",
+		},
+		{
+			name: "ViaCEP recusa o CEP",
+			upstream: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("<h1>Http 400</h1>"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedMsg:    "invalid zipcode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.upstream)
+			defer server.Close()
+
+			useRetryBaseDelay(t, 0)
+			useConfig(t, Config{ViaCEPBaseURL: server.URL, ViaCEPTimeout: 50 * time.Millisecond})
+
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(viaCEPResolver{}, &fakeWeatherResolver{tempC: 20})(rr,
+				httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			var errorResp ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &errorResp); err != nil {
+				t.Fatalf("Resposta de erro não é um JSON válido: %v", err)
+			}
+			if errorResp.Message != tt.expectedMsg {
+				t.Errorf("Mensagem de erro incorreta: got %q want %q", errorResp.Message, tt.expectedMsg)
+			}
+		})
+	}
+}
+
+func TestSearchCEPSentinelErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cep      string
+		status   int
+		body     string
+		sentinel error
+	}{
+		{"CEP inválido", "123", http.StatusOK, `{}`, weather.ErrInvalidCEP},
+		{"CEP recusado pelo ViaCEP", "01310100", http.StatusBadRequest, ``, weather.ErrInvalidCEP},
+		{"CEP inexistente", "01310100", http.StatusOK, `{"erro": true}`, weather.ErrCEPNotFound},
+		{"ViaCEP fora do ar", "01310100", http.StatusBadGateway, ``, weather.ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			useConfig(t, Config{ViaCEPBaseURL: server.URL + "/ws"})
+			useRetryBaseDelay(t, 0)
+
+			_, cepErr := searchCEP(context.Background(), tt.cep)
+			if !errors.Is(cepErr, tt.sentinel) {
+				t.Fatalf("erro = %v, want errors.Is(%v)", cepErr, tt.sentinel)
+			}
+			if cepErr.Code != weather.HTTPStatus(tt.sentinel) {
+				t.Errorf("código = %d, want %d", cepErr.Code, weather.HTTPStatus(tt.sentinel))
+			}
+		})
+	}
+}
+
+func TestUpstreamTimeoutSentinel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	if err := contextError(ctx); !errors.Is(err, weather.ErrUpstreamTimeout) {
+		t.Errorf("contextError = %v, want errors.Is(ErrUpstreamTimeout)", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// serverTLSConfig indica o certificado e a chave usados para servir HTTPS
+// diretamente, sem um proxy reverso na frente
+type serverTLSConfig struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Enabled indica se o servidor deve atender via TLS
+func (c serverTLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// loadTLSConfigFromEnv lê TLS_CERT_FILE e TLS_KEY_FILE. Sem nenhuma das
+// duas o servidor atende em texto puro; informar apenas uma, ou um arquivo
+// inexistente, é erro de configuração.
+func loadTLSConfigFromEnv() (serverTLSConfig, error) {
+	cfg := serverTLSConfig{
+		CertFile: strings.TrimSpace(os.Getenv("TLS_CERT_FILE")),
+		KeyFile:  strings.TrimSpace(os.Getenv("TLS_KEY_FILE")),
+	}
+
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		return cfg, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return serverTLSConfig{}, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, file := range []string{cfg.CertFile, cfg.KeyFile} {
+		if _, err := os.Stat(file); err != nil {
+			return serverTLSConfig{}, fmt.Errorf("TLS file %q is not readable: %w", file, err)
+		}
+	}
+	return cfg, nil
+}
+
+// serve inicia o servidor com TLS quando configurado, ou em texto puro
+func serve(server *http.Server, cfg serverTLSConfig) error {
+	if cfg.Enabled() {
+		return server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifica os spans criados pela aplicação
+const tracerName = "golang-weatherbycep"
+
+// Nomes dos spans das consultas aos upstreams
+const (
+	spanViaCEPLookup    = "viacep.lookup"
+	spanBrasilAPILookup = "brasilapi.lookup"
+	spanWeatherLookup   = "weather.lookup"
+)
+
+// initTracing configura o provedor de traces com o exportador OTLP/HTTP
+// quando OTEL_EXPORTER_OTLP_ENDPOINT ou OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+// estão definidas; caso contrário mantém o provedor no-op global. O exportador
+// lê as demais variáveis OTEL_* padrão, como headers e OTEL_SERVICE_NAME.
+// A função retornada envia os spans pendentes ao encerrar.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// startSpan inicia um span filho do span presente no contexto usando o
+// provedor global, que pode ser trocado nos testes
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan registra no span o erro da consulta, se houver, e o encerra
+func endSpan(span trace.Span, err *CustomError) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Message)
+		span.SetAttributes(attribute.Int("error.status", err.Code))
+	}
+	span.End()
+}
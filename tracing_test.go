@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useSpanRecorder registra em memória os spans criados durante o teste
+func useSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+// spanAttribute retorna o valor de um atributo do span, ou vazio se ausente
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) string {
+	for _, attr := range span.Attributes() {
+		if attr.Key == attribute.Key(key) {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestWeatherByCEPHandlerTracing(t *testing.T) {
+	useCassette(t, "01310100")
+	recorder := useSpanRecorder(t)
+
+	handler := otelhttp.NewHandler(NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{}), "weatherbycep")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rr.Code, rr.Body.String())
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	root, ok := spans["weatherbycep"]
+	if !ok {
+		t.Fatalf("span da requisição não foi criado: %v", spans)
+	}
+	for _, name := range []string{spanViaCEPLookup, spanWeatherLookup} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("span %s não foi criado", name)
+			continue
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("span %s deveria ser filho do span da requisição", name)
+		}
+		if span.Status().Code == codes.Error {
+			t.Errorf("span %s não deveria ter erro: %v", name, span.Status())
+		}
+	}
+	if len(spans) != 3 {
+		t.Errorf("spans criados = %d, want 3", len(spans))
+	}
+
+	if cep := spanAttribute(spans[spanViaCEPLookup], "cep"); cep != "01310100" {
+		t.Errorf("atributo cep = %q, want 01310100", cep)
+	}
+	if city := spanAttribute(spans[spanViaCEPLookup], "city"); city != "São Paulo" {
+		t.Errorf("atributo city do ViaCEP = %q, want São Paulo", city)
+	}
+	if city := spanAttribute(spans[spanWeatherLookup], "city"); city != "São Paulo" {
+		t.Errorf("atributo city do wttr.in = %q, want São Paulo", city)
+	}
+}
+
+func TestSearchCEPTracingRecordsError(t *testing.T) {
+	recorder := useSpanRecorder(t)
+
+	if _, err := searchCEP(context.Background(), "123"); err == nil {
+		t.Fatal("searchCEP deveria rejeitar o CEP inválido")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != spanViaCEPLookup {
+		t.Fatalf("spans = %v, want apenas %s", spans, spanViaCEPLookup)
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("status do span = %v, want Error", spans[0].Status())
+	}
+	if len(spans[0].Events()) == 0 {
+		t.Error("o erro deveria ser registrado como evento do span")
+	}
+}
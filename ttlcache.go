@@ -0,0 +1,186 @@
+package main
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// ttlCacheMaxCleanupInterval limita o intervalo entre as remoções de entradas expiradas
+	ttlCacheMaxCleanupInterval = 10 * time.Minute
+	// defaultCacheMaxEntries limita a quantidade de entradas de cada cache
+	defaultCacheMaxEntries = 10000
+)
+
+// ttlEntry guarda um valor do cache, o momento em que foi obtido do
+// upstream e o momento em que expira
+type ttlEntry[V any] struct {
+	key       string
+	value     V
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// ttlCache mantém valores em memória por um tempo limitado, com tamanho
+// máximo: quando cheio, a entrada consultada há mais tempo é descartada. Uma
+// goroutine em segundo plano remove as entradas expiradas. Com maxStale, as
+// entradas expiradas ainda ficam guardadas por esse tempo para getStale.
+type ttlCache[V any] struct {
+	cacheStats
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	ttl        time.Duration
+	maxStale   time.Duration
+	maxEntries int
+	now        func() time.Time
+	stop       chan struct{}
+	once       sync.Once
+}
+
+// newTTLCache cria um cache com o TTL e o tamanho informados e inicia a
+// remoção periódica das entradas expiradas
+func newTTLCache[V any](ttl time.Duration, maxEntries int) *ttlCache[V] {
+	c := &ttlCache[V]{
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		stop:       make(chan struct{}),
+	}
+
+	interval := ttl
+	if interval > ttlCacheMaxCleanupInterval {
+		interval = ttlCacheMaxCleanupInterval
+	}
+	go c.evictLoop(interval)
+
+	return c
+}
+
+// get retorna o valor e o momento em que foi armazenado, se a chave estiver
+// no cache e ainda não tiver expirado
+func (c *ttlCache[V]) get(key string) (value V, storedAt time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		c.record(false)
+		return value, storedAt, false
+	}
+	entry := elem.Value.(*ttlEntry[V])
+	if now := c.now(); !now.Before(entry.expiresAt) {
+		// A entrada expirada continua disponível para getStale até maxStale
+		if !now.Before(entry.expiresAt.Add(c.maxStale)) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+		c.record(false)
+		return value, storedAt, false
+	}
+	c.order.MoveToFront(elem)
+	c.record(true)
+	return entry.value, entry.storedAt, true
+}
+
+// getStale retorna o valor mesmo expirado, desde que tenha expirado há menos
+// de maxStale. É usado quando o upstream falha; não conta nas estatísticas.
+func (c *ttlCache[V]) getStale(key string) (value V, storedAt time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return value, storedAt, false
+	}
+	entry := elem.Value.(*ttlEntry[V])
+	if !c.now().Before(entry.expiresAt.Add(c.maxStale)) {
+		return value, storedAt, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, entry.storedAt, true
+}
+
+// set armazena o valor pelo TTL configurado, descartando a entrada menos
+// consultada se o cache estiver cheio
+func (c *ttlCache[V]) set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*ttlEntry[V])
+		entry.value, entry.storedAt, entry.expiresAt = value, now, now.Add(c.ttl)
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.maxEntries > 0 && c.order.Len() >= c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ttlEntry[V]).key)
+	}
+	c.entries[key] = c.order.PushFront(&ttlEntry[V]{key: key, value: value, storedAt: now, expiresAt: now.Add(c.ttl)})
+}
+
+// Len retorna a quantidade de entradas guardadas, inclusive as já expiradas
+func (c *ttlCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stop encerra a remoção periódica das entradas expiradas
+func (c *ttlCache[V]) Stop() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+// evictLoop remove as entradas expiradas a cada intervalo até o cache ser parado
+func (c *ttlCache[V]) evictLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.evictExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// evictExpired remove as entradas cujo TTL, somado a maxStale, já passou
+func (c *ttlCache[V]) evictExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, elem := range c.entries {
+		if !now.Before(elem.Value.(*ttlEntry[V]).expiresAt.Add(c.maxStale)) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// normalizeCacheKey padroniza um trecho de chave para que variações de
+// digitação da mesma localidade ("São Paulo", " sao paulo") compartilhem a
+// entrada: remove espaços nas pontas, converte para minúsculas e retira os acentos
+func normalizeCacheKey(value string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), value)
+	if err != nil {
+		folded = value
+	}
+	return strings.ToLower(strings.TrimSpace(folded))
+}
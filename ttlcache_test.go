@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTTLCache[int](time.Hour, 2)
+	cache.now = func() time.Time { return now }
+	t.Cleanup(cache.Stop)
+
+	cache.set("a", 1)
+	cache.set("b", 2)
+	// Consultar "a" o torna o mais recente; "b" passa a ser o descartado
+	if _, _, ok := cache.get("a"); !ok {
+		t.Fatal("a deveria estar no cache")
+	}
+	cache.set("c", 3)
+
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+	if _, _, ok := cache.get("b"); ok {
+		t.Error("b deveria ter sido descartado")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, ok := cache.get(key); !ok {
+			t.Errorf("%s deveria continuar no cache", key)
+		}
+	}
+}
+
+func TestTTLCacheStoredAt(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTTLCache[int](time.Hour, 0)
+	cache.now = func() time.Time { return now }
+	t.Cleanup(cache.Stop)
+
+	stored := now
+	cache.set("a", 1)
+	now = now.Add(10 * time.Minute)
+
+	if _, storedAt, ok := cache.get("a"); !ok || !storedAt.Equal(stored) {
+		t.Errorf("get = (%v, %v), want (%v, true)", storedAt, ok, stored)
+	}
+}
+
+func TestNormalizeCacheKey(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"São Paulo", "sao paulo"},
+		{"  SAO PAULO ", "sao paulo"},
+		{"Florianópolis", "florianopolis"},
+		{"Mogi das Cruzes", "mogi das cruzes"},
+		{"Ji-Paraná", "ji-parana"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := normalizeCacheKey(tt.input); got != tt.expected {
+				t.Errorf("normalizeCacheKey(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTTLCacheKeepsStaleEntriesUntilMaxStale(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTTLCache[string](time.Minute, 10)
+	cache.maxStale = time.Hour
+	cache.now = func() time.Time { return now }
+	t.Cleanup(cache.Stop)
+
+	cache.set("a", "valor")
+	now = now.Add(30 * time.Minute)
+
+	if _, _, ok := cache.get("a"); ok {
+		t.Error("get não deveria retornar uma entrada expirada")
+	}
+	cache.evictExpired()
+	if value, _, ok := cache.getStale("a"); !ok || value != "valor" {
+		t.Errorf("getStale = (%q, %v), want (valor, true) dentro de maxStale", value, ok)
+	}
+
+	now = now.Add(31 * time.Minute)
+	if _, _, ok := cache.getStale("a"); ok {
+		t.Error("getStale não deveria retornar uma entrada expirada há mais de maxStale")
+	}
+	cache.evictExpired()
+	if cache.Len() != 0 {
+		t.Errorf("Len = %d, want 0 após maxStale", cache.Len())
+	}
+}
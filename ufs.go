@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	// A imagem final (Alpine) não traz o banco de fusos horários do sistema
+	_ "time/tzdata"
+)
+
+// ufTimezones mapeia cada UF brasileira para o seu fuso horário IANA.
+// Estados com mais de um fuso usam o da capital.
+var ufTimezones = map[string]string{
+	"AC": "America/Rio_Branco",
+	"AL": "America/Maceio",
+	"AM": "America/Manaus",
+	"AP": "America/Belem",
+	"BA": "America/Bahia",
+	"CE": "America/Fortaleza",
+	"DF": "America/Sao_Paulo",
+	"ES": "America/Sao_Paulo",
+	"GO": "America/Sao_Paulo",
+	"MA": "America/Fortaleza",
+	"MG": "America/Sao_Paulo",
+	"MS": "America/Campo_Grande",
+	"MT": "America/Cuiaba",
+	"PA": "America/Belem",
+	"PB": "America/Fortaleza",
+	"PE": "America/Recife",
+	"PI": "America/Fortaleza",
+	"PR": "America/Sao_Paulo",
+	"RJ": "America/Sao_Paulo",
+	"RN": "America/Fortaleza",
+	"RO": "America/Porto_Velho",
+	"RR": "America/Boa_Vista",
+	"RS": "America/Sao_Paulo",
+	"SC": "America/Sao_Paulo",
+	"SE": "America/Maceio",
+	"SP": "America/Sao_Paulo",
+	"TO": "America/Araguaina",
+}
+
+// UFInfo representa uma UF e o seu fuso horário
+type UFInfo struct {
+	UF       string `json:"uf"`
+	Timezone string `json:"timezone"`
+}
+
+// timezoneForUF retorna o fuso horário da UF informada
+func timezoneForUF(uf string) (*time.Location, bool) {
+	name, ok := ufTimezones[strings.ToUpper(uf)]
+	if !ok {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
+
+// listUFs retorna todas as UFs com os seus fusos, em ordem alfabética
+func listUFs() []UFInfo {
+	ufs := make([]UFInfo, 0, len(ufTimezones))
+	for uf, tz := range ufTimezones {
+		ufs = append(ufs, UFInfo{UF: uf, Timezone: tz})
+	}
+	sort.Slice(ufs, func(i, j int) bool { return ufs[i].UF < ufs[j].UF })
+	return ufs
+}
+
+// ufsHandler lida com as requisições GET para /ufs
+func ufsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		setAllow(w, http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listUFs())
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUFsHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/ufs", nil)
+	rr := httptest.NewRecorder()
+	ufsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var ufs []UFInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &ufs); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+
+	if len(ufs) != 27 {
+		t.Errorf("esperadas 27 UFs, got %d", len(ufs))
+	}
+
+	seen := make(map[string]bool)
+	for _, info := range ufs {
+		if seen[info.UF] {
+			t.Errorf("UF duplicada: %s", info.UF)
+		}
+		seen[info.UF] = true
+
+		if _, err := time.LoadLocation(info.Timezone); err != nil {
+			t.Errorf("fuso inválido para %s: %s (%v)", info.UF, info.Timezone, err)
+		}
+	}
+}
+
+func TestUFsHandlerMethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest("POST", "/ufs", nil)
+	rr := httptest.NewRecorder()
+	ufsHandler(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestTimezoneForUF(t *testing.T) {
+	tests := []struct {
+		uf       string
+		expected string
+		ok       bool
+	}{
+		{"SP", "America/Sao_Paulo", true},
+		{"ac", "America/Rio_Branco", true},
+		{"AM", "America/Manaus", true},
+		{"XX", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uf, func(t *testing.T) {
+			loc, ok := timezoneForUF(tt.uf)
+			if ok != tt.ok {
+				t.Fatalf("timezoneForUF(%s) ok = %v, want %v", tt.uf, ok, tt.ok)
+			}
+			if ok && loc.String() != tt.expected {
+				t.Errorf("timezoneForUF(%s) = %s, want %s", tt.uf, loc, tt.expected)
+			}
+		})
+	}
+}
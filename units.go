@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// derivedUnitsTolerance é a diferença máxima aceita entre F/K e os valores derivados de C
+const derivedUnitsTolerance = 0.01
+
+// omitDerivedUnits remove temp_F e temp_K das respostas JSON quando são apenas
+// conversões de temp_C, para esquemas que rejeitam campos redundantes. Vale
+// para todos os endpoints que escrevem a temperatura com encodeJSON.
+var omitDerivedUnits = envBool("OMIT_DERIVED_UNITS")
+
+// envBool lê uma variável de ambiente booleana, considerando false quando ausente ou inválida
+func envBool(name string) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && value
+}
+
+// inferUnits escolhe a escala padrão pela região do header Accept-Language
+// quando ?units= não é informado, via INFER_UNITS
+var inferUnits = envBool("INFER_UNITS")
+
+// Modos de representação das temperaturas aceitos em ?units=. O modo flat
+// (padrão, ou ?units=all) traz as três escalas; C, F e K trazem apenas uma.
+const (
+	unitsModeFlat       = "flat"
+	unitsModeExplicit   = "explicit"
+	unitsModeCelsius    = "C"
+	unitsModeFahrenheit = "F"
+	unitsModeKelvin     = "K"
+)
+
+// Measurement representa um valor acompanhado da sua unidade de medida
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// ExplicitWeatherResponse é a variante de WeatherResponse em que cada
+// temperatura traz a sua unidade. Os campos declarados aqui sobrepõem os
+// campos numéricos de WeatherData na serialização JSON.
+type ExplicitWeatherResponse struct {
+	WeatherResponse
+	TempC Measurement `json:"temp_C"`
+	TempF Measurement `json:"temp_F"`
+	TempK Measurement `json:"temp_K"`
+}
+
+// SingleUnitWeatherResponse é a variante de WeatherResponse apenas com a
+// escala pedida em ?units=. Os campos declarados aqui sobrepõem os de
+// WeatherData; os nulos não aparecem na serialização JSON.
+type SingleUnitWeatherResponse struct {
+	WeatherResponse
+	TempC *float64 `json:"temp_C,omitempty"`
+	TempF *float64 `json:"temp_F,omitempty"`
+	TempK *float64 `json:"temp_K,omitempty"`
+}
+
+// hasOnlyDerivedUnits verifica se F e K são conversões de C dentro da tolerância
+func hasOnlyDerivedUnits(tempC, tempF, tempK float64) bool {
+	expected := weatherFromCelsius(tempC)
+	return math.Abs(tempF-expected.TempF) <= derivedUnitsTolerance &&
+		math.Abs(tempK-expected.TempK) <= derivedUnitsTolerance
+}
+
+// renderWeatherResponse escolhe a representação das temperaturas conforme o
+// modo de unidades
+func renderWeatherResponse(resp WeatherResponse, unitsMode string) interface{} {
+	switch unitsMode {
+	case unitsModeExplicit:
+		return withExplicitUnits(resp)
+	case unitsModeCelsius:
+		return SingleUnitWeatherResponse{WeatherResponse: resp, TempC: &resp.TempC}
+	case unitsModeFahrenheit:
+		return SingleUnitWeatherResponse{WeatherResponse: resp, TempF: &resp.TempF}
+	case unitsModeKelvin:
+		return SingleUnitWeatherResponse{WeatherResponse: resp, TempK: &resp.TempK}
+	}
+	return resp
+}
+
+// encodeJSON escreve v como json.Encoder.Encode e, com OMIT_DERIVED_UNITS,
+// remove temp_F e temp_K de cada objeto em que são apenas conversões do
+// temp_C do mesmo objeto. Os objetos de ?units=explicit e as respostas de uma
+// única escala não têm as três temperaturas numéricas e ficam intactos.
+func encodeJSON(w io.Writer, v interface{}) error {
+	if !omitDerivedUnits {
+		return json.NewEncoder(w).Encode(v)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var out bytes.Buffer
+	if err := stripDerivedUnits(dec, &out); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// jsonField é um campo de objeto JSON já reescrito, na ordem original
+type jsonField struct {
+	key   string
+	value []byte
+}
+
+// stripDerivedUnits reescreve o próximo valor do decoder em out, preservando
+// a ordem dos campos e removendo as escalas derivadas dos objetos
+func stripDerivedUnits(dec *json.Decoder, out *bytes.Buffer) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		encoded, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+		return nil
+	}
+
+	if delim == '[' {
+		out.WriteByte('[')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := stripDerivedUnits(dec, out); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+		_, err := dec.Token()
+		return err
+	}
+
+	var fields []jsonField
+	temps := make(map[string]float64)
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := keyTok.(string)
+		var value bytes.Buffer
+		if err := stripDerivedUnits(dec, &value); err != nil {
+			return err
+		}
+		if key == "temp_C" || key == "temp_F" || key == "temp_K" {
+			if temp, err := strconv.ParseFloat(value.String(), 64); err == nil {
+				temps[key] = temp
+			}
+		}
+		fields = append(fields, jsonField{key: key, value: value.Bytes()})
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	tempC, hasC := temps["temp_C"]
+	tempF, hasF := temps["temp_F"]
+	tempK, hasK := temps["temp_K"]
+	omit := hasC && hasF && hasK && hasOnlyDerivedUnits(tempC, tempF, tempK)
+
+	out.WriteByte('{')
+	written := 0
+	for _, field := range fields {
+		if omit && (field.key == "temp_F" || field.key == "temp_K") {
+			continue
+		}
+		if written > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(field.value)
+		written++
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+// parseUnitsMode lê o modo de unidades da query string, usando as três escalas
+// em formato numérico como padrão ou, com INFER_UNITS, a escala inferida de
+// Accept-Language. As escalas aceitam letras minúsculas.
+func parseUnitsMode(r *http.Request) (string, bool) {
+	switch value := r.URL.Query().Get("units"); value {
+	case "":
+		if inferUnits {
+			return inferUnitsMode(r), true
+		}
+		return unitsModeFlat, true
+	case "all":
+		return unitsModeFlat, true
+	case unitsModeExplicit:
+		return unitsModeExplicit, true
+	case "C", "c", "F", "f", "K", "k":
+		return strings.ToUpper(value), true
+	default:
+		return "", false
+	}
+}
+
+// inferUnitsMode escolhe a escala pela região do idioma de maior peso em
+// Accept-Language: Fahrenheit nos Estados Unidos (en-US) e Celsius nas
+// demais (pt-BR, en-GB, es-419). Kelvin nunca é inferido. Sem o header ou
+// sem região explícita (como em "en"), mantém as três escalas.
+func inferUnitsMode(r *http.Request) string {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return unitsModeFlat
+	}
+	region, confidence := tags[0].Region()
+	if confidence != language.Exact {
+		return unitsModeFlat
+	}
+	if region.String() == "US" {
+		return unitsModeFahrenheit
+	}
+	return unitsModeCelsius
+}
+
+// withExplicitUnits converte a resposta para o formato com unidades explícitas
+func withExplicitUnits(resp WeatherResponse) ExplicitWeatherResponse {
+	return ExplicitWeatherResponse{
+		WeatherResponse: resp,
+		TempC:           Measurement{Value: resp.TempC, Unit: "C"},
+		TempF:           Measurement{Value: resp.TempF, Unit: "F"},
+		TempK:           Measurement{Value: resp.TempK, Unit: "K"},
+	}
+}
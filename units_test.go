@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWeatherByCEPHandlerExplicitUnits(t *testing.T) {
+	useCassette(t, "01310100")
+
+	req := httptest.NewRequest("GET", "/weatherbycep/01310100?units=explicit", nil)
+	rr := httptest.NewRecorder()
+	NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v (%s)",
+			rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+
+	expected := map[string]Measurement{
+		"temp_C": {Value: 22, Unit: "C"},
+		"temp_F": {Value: 71.6, Unit: "F"},
+		"temp_K": {Value: 295.2, Unit: "K"},
+	}
+	for field, want := range expected {
+		var got Measurement
+		if err := json.Unmarshal(resp[field], &got); err != nil {
+			t.Errorf("%s não está no formato {value, unit}: %s", field, resp[field])
+			continue
+		}
+		if got.Unit != want.Unit || got.Value != want.Value {
+			t.Errorf("%s incorreto: got %+v want %+v", field, got, want)
+		}
+	}
+	if _, ok := resp["address"]; !ok {
+		t.Errorf("modo explícito não deveria remover o endereço: %s", rr.Body.String())
+	}
+}
+
+func TestParseUnitsMode(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+		valid    bool
+	}{
+		{"", unitsModeFlat, true},
+		{"?units=all", unitsModeFlat, true},
+		{"?units=explicit", unitsModeExplicit, true},
+		{"?units=C", unitsModeCelsius, true},
+		{"?units=f", unitsModeFahrenheit, true},
+		{"?units=K", unitsModeKelvin, true},
+		{"?units=kelvin", "", false},
+		{"?units=R", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil)
+			mode, ok := parseUnitsMode(req)
+			if mode != tt.expected || ok != tt.valid {
+				t.Errorf("parseUnitsMode(%s) = (%s, %v), want (%s, %v)",
+					tt.query, mode, ok, tt.expected, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParseUnitsModeInferred(t *testing.T) {
+	tests := []struct {
+		name           string
+		infer          bool
+		query          string
+		acceptLanguage string
+		expected       string
+	}{
+		{"Inglês dos Estados Unidos", true, "", "en-US", unitsModeFahrenheit},
+		{"Português do Brasil", true, "", "pt-BR", unitsModeCelsius},
+		{"Inglês britânico", true, "", "en-GB,en-US;q=0.8", unitsModeCelsius},
+		{"Maior peso vence", true, "", "pt-BR;q=0.5, en-US", unitsModeFahrenheit},
+		{"Sem região", true, "", "en", unitsModeFlat},
+		{"Sem header", true, "", "", unitsModeFlat},
+		{"Parâmetro explícito prevalece", true, "?units=C", "en-US", unitsModeCelsius},
+		{"Kelvin só explícito", true, "?units=K", "pt-BR", unitsModeKelvin},
+		{"Todas as escalas explícitas", true, "?units=all", "en-US", unitsModeFlat},
+		{"Inferência desativada", false, "", "en-US", unitsModeFlat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := inferUnits
+			inferUnits = tt.infer
+			t.Cleanup(func() { inferUnits = previous })
+
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			mode, ok := parseUnitsMode(req)
+			if mode != tt.expected || !ok {
+				t.Errorf("parseUnitsMode(%s, %q) = (%s, %v), want (%s, true)",
+					tt.query, tt.acceptLanguage, mode, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerSingleUnit(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"temp_C", "temp_F", "temp_K"}},
+		{"?units=all", []string{"temp_C", "temp_F", "temp_K"}},
+		{"?units=C", []string{"temp_C"}},
+		{"?units=F", []string{"temp_F"}},
+		{"?units=k", []string{"temp_K"}},
+	}
+
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20})
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil))
+			if rr.Code != 200 {
+				t.Fatalf("status = %d, want 200", rr.Code)
+			}
+
+			var fields map[string]interface{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &fields); err != nil {
+				t.Fatal(err)
+			}
+
+			var present []string
+			for _, field := range []string{"temp_C", "temp_F", "temp_K"} {
+				if _, ok := fields[field]; ok {
+					present = append(present, field)
+				}
+			}
+			if !reflect.DeepEqual(present, tt.expected) {
+				t.Errorf("campos de temperatura = %v, want %v (%s)", present, tt.expected, rr.Body.String())
+			}
+			if _, ok := fields["address"]; !ok {
+				t.Errorf("address deveria permanecer: %s", rr.Body.String())
+			}
+		})
+	}
+
+	// Os valores continuam sendo as conversões de Celsius
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100?units=F", nil))
+	var resp struct {
+		TempF float64 `json:"temp_F"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.TempF != 68 {
+		t.Errorf("temp_F = %v, want 68", resp.TempF)
+	}
+}
+
+func TestWeatherByCEPHandlerInvalidUnits(t *testing.T) {
+	handler := NewWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 20})
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100?units=R", nil))
+
+	if rr.Code != 400 {
+		t.Errorf("status = %d, want 400", rr.Code)
+	}
+}
+
+// useOmitDerivedUnits ativa OMIT_DERIVED_UNITS durante o teste
+func useOmitDerivedUnits(t *testing.T) {
+	t.Helper()
+	original := omitDerivedUnits
+	omitDerivedUnits = true
+	t.Cleanup(func() { omitDerivedUnits = original })
+}
+
+// encodeToBytes codifica v com encodeJSON
+func encodeToBytes(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeJSONOmitDerivedUnits(t *testing.T) {
+	useOmitDerivedUnits(t)
+
+	resp := WeatherResponse{
+		WeatherData: WeatherData{TempC: 22, TempF: 71.6, TempK: 295.2},
+		Address:     MinAddress{Localidade: "São Paulo", UF: "SP"},
+	}
+
+	body := encodeToBytes(t, renderWeatherResponse(resp, unitsModeFlat))
+	if want := `{"temp_C":22,"address":{"cep":"","localidade":"São Paulo","uf":"SP"}}` + "\n"; string(body) != want {
+		t.Errorf("corpo = %s, want %s (a ordem dos campos deve ser mantida)", body, want)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["temp_C"] != 22.0 {
+		t.Errorf("temp_C deveria permanecer: %s", body)
+	}
+	for _, field := range []string{"temp_F", "temp_K"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("%s deveria ser omitido: %s", field, body)
+		}
+	}
+	if _, ok := fields["address"]; !ok {
+		t.Errorf("address deveria permanecer: %s", body)
+	}
+
+	// O modo explícito não é afetado pela configuração
+	body = encodeToBytes(t, renderWeatherResponse(resp, unitsModeExplicit))
+	if !strings.Contains(string(body), `"temp_F"`) || !strings.Contains(string(body), `"temp_K"`) {
+		t.Errorf("modo explícito deveria manter F e K: %s", body)
+	}
+
+	// Valores que não são conversões de C também são mantidos
+	body = encodeToBytes(t, WeatherData{TempC: 22, TempF: 80, TempK: 295.2})
+	if !strings.Contains(string(body), `"temp_F":80`) {
+		t.Errorf("temp_F divergente deveria ser mantido: %s", body)
+	}
+}
+
+func TestBatchWeatherHandlerOmitDerivedUnits(t *testing.T) {
+	useOmitDerivedUnits(t)
+	handler := NewBatchWeatherHandler(newFakeCEPResolver(), &fakeWeatherResolver{tempC: 22})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("POST", "/weatherbycep/batch", strings.NewReader(`{"ceps": ["01310100"]}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rr.Code, rr.Body.String())
+	}
+
+	var results []struct {
+		Weather map[string]interface{} `json:"weather"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	weather := results[0].Weather
+	if weather["temp_C"] != 22.0 {
+		t.Errorf("temp_C deveria permanecer: %s", rr.Body.String())
+	}
+	for _, field := range []string{"temp_F", "temp_K"} {
+		if _, ok := weather[field]; ok {
+			t.Errorf("%s deveria ser omitido também no lote: %s", field, rr.Body.String())
+		}
+	}
+}
+
+func TestHasOnlyDerivedUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		weather  WeatherData
+		expected bool
+	}{
+		{"Conversões exatas", WeatherData{TempC: 22, TempF: 71.6, TempK: 295.2}, true},
+		{"Dentro da tolerância", WeatherData{TempC: 22, TempF: 71.605, TempK: 295.2}, true},
+		{"F divergente", WeatherData{TempC: 22, TempF: 72, TempK: 295.2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := hasOnlyDerivedUnits(tt.weather.TempC, tt.weather.TempF, tt.weather.TempK); result != tt.expected {
+				t.Errorf("hasOnlyDerivedUnits(%+v) = %v, want %v", tt.weather, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	// defaultMaxUpstreamCalls é o número padrão de consultas simultâneas aos upstreams
+	defaultMaxUpstreamCalls = 20
+	// defaultUpstreamAcquireWait é quanto uma requisição espera por uma vaga antes de desistir
+	defaultUpstreamAcquireWait = 100 * time.Millisecond
+)
+
+// upstreamLimiter limita o total de consultas simultâneas aos upstreams
+// (ViaCEP, wttr.in, geocodificadores e fallbacks), somando todas as
+// requisições, para que um pico de tráfego não abra centenas de conexões aos
+// provedores. Quem não consegue uma vaga dentro da espera recebe 503 em vez
+// de enfileirar.
+type upstreamLimiter struct {
+	sem  *semaphore.Weighted
+	wait time.Duration
+}
+
+// newUpstreamLimiter cria um limitador com maxCalls vagas
+func newUpstreamLimiter(maxCalls int64, wait time.Duration) *upstreamLimiter {
+	return &upstreamLimiter{sem: semaphore.NewWeighted(maxCalls), wait: wait}
+}
+
+// newUpstreamLimiterFromEnv lê MAX_UPSTREAM_CALLS e UPSTREAM_ACQUIRE_WAIT;
+// retorna nil (sem limite) quando MAX_UPSTREAM_CALLS é 0
+func newUpstreamLimiterFromEnv() *upstreamLimiter {
+	maxCalls := envInt64("MAX_UPSTREAM_CALLS", defaultMaxUpstreamCalls, nonNegative[int64])
+	if maxCalls == 0 {
+		return nil
+	}
+	wait := envDurationWhere("UPSTREAM_ACQUIRE_WAIT", defaultUpstreamAcquireWait, nonNegative[time.Duration])
+
+	return newUpstreamLimiter(maxCalls, wait)
+}
+
+// errServerBusy é a falha de uma chamada que não conseguiu vaga no
+// upstreamLimiter; não é repetida nem conta como falha do upstream
+var errServerBusy = errors.New("server busy")
+
+// serverBusyError é o erro das consultas recusadas por falta de vaga no limitador
+func serverBusyError() *CustomError {
+	return &CustomError{Code: 503, Message: "server busy", Err: errServerBusy}
+}
+
+// acquire reserva uma vaga, esperando no máximo l.wait. Um limitador nil não
+// limita nada. Se a própria requisição expirar ou for cancelada durante a
+// espera, o erro é o do contexto, como nas demais consultas.
+func (l *upstreamLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if l.sem.TryAcquire(1) {
+		return nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, l.wait)
+	defer cancel()
+	if err := l.sem.Acquire(waitCtx, 1); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.WarnContext(ctx, "limite de consultas simultâneas aos upstreams atingido")
+		return errServerBusy
+	}
+	return nil
+}
+
+// release devolve a vaga reservada por acquire
+func (l *upstreamLimiter) release() {
+	if l != nil {
+		l.sem.Release(1)
+	}
+}
+
+// limitTransport faz cada chamada do httpClient ocupar uma vaga do limitador
+// até o corpo da resposta ser fechado, de modo que todas as consultas aos
+// upstreams, de qualquer endpoint, disputam as mesmas vagas
+type limitTransport struct {
+	limiter *upstreamLimiter
+	next    http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.acquire(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.limiter.release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: t.limiter.release}
+	return resp, nil
+}
+
+// releasingBody devolve a vaga do limitador no primeiro Close do corpo
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
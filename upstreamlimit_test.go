@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// inFlightTransport conta as chamadas em andamento e registra o maior valor
+// observado, segurando cada chamada até release receber um valor
+type inFlightTransport struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	release  chan struct{}
+}
+
+func (f *inFlightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.peak {
+		f.peak = f.inFlight
+	}
+	f.mu.Unlock()
+	<-f.release
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	body := `{"current_condition": [{"temp_C": "23"}]}`
+	if strings.Contains(req.URL.Path, "/ws/") {
+		body = `{"cep": "01310-100", "localidade": "São Paulo", "uf": "SP"}`
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func (f *inFlightTransport) Peak() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.peak
+}
+
+// useTransport substitui o transporte do httpClient durante o teste
+func useTransport(t *testing.T, transport http.RoundTripper) {
+	t.Helper()
+	original := httpClient.Transport
+	httpClient.Transport = transport
+	t.Cleanup(func() { httpClient.Transport = original })
+}
+
+func TestLimitTransportBoundsConcurrentCalls(t *testing.T) {
+	const limit = 3
+	upstream := &inFlightTransport{release: make(chan struct{})}
+	useTransport(t, &limitTransport{limiter: newUpstreamLimiter(limit, 5*time.Second), next: upstream})
+	useConfig(t, Config{ViaCEPBaseURL: "http://viacep.test/ws", WeatherBaseURL: "http://wttr.test"})
+
+	// CEP, temperatura por cidade e por coordenada disputam as mesmas vagas
+	lookups := []func() *CustomError{
+		func() *CustomError { _, err := searchCEP(context.Background(), "01310100"); return err },
+		func() *CustomError { _, err := getWeatherData(context.Background(), "São Paulo", "SP"); return err },
+		func() *CustomError { _, err := getWeatherByCoords(context.Background(), -23.55, -46.63); return err },
+	}
+	var wg sync.WaitGroup
+	errs := make(chan *CustomError, 10*len(lookups))
+	for i := 0; i < 10; i++ {
+		for _, lookup := range lookups {
+			wg.Add(1)
+			go func(lookup func() *CustomError) {
+				defer wg.Done()
+				errs <- lookup()
+			}(lookup)
+		}
+	}
+
+	// Libera as consultas aos poucos, dando tempo para as demais disputarem vagas
+	for i := 0; i < 10*len(lookups); i++ {
+		time.Sleep(time.Millisecond)
+		upstream.release <- struct{}{}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("consulta falhou: %+v", err)
+		}
+	}
+	if peak := upstream.Peak(); peak != limit {
+		t.Errorf("pico de chamadas simultâneas = %d, want %d", peak, limit)
+	}
+}
+
+func TestLimitTransportServerBusy(t *testing.T) {
+	transport := &limitTransport{
+		limiter: newUpstreamLimiter(1, 10*time.Millisecond),
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+	}
+	newRequest := func(ctx context.Context) *http.Request {
+		return httptest.NewRequest("GET", "http://upstream.test/", nil).WithContext(ctx)
+	}
+
+	// A vaga fica ocupada até o corpo da resposta ser fechado
+	held, err := transport.RoundTrip(newRequest(context.Background()))
+	if err != nil {
+		t.Fatalf("primeira chamada recusada: %v", err)
+	}
+	if _, err := transport.RoundTrip(newRequest(context.Background())); !errors.Is(err, errServerBusy) {
+		t.Errorf("RoundTrip() sem vaga = %v, want errServerBusy", err)
+	}
+
+	// O cancelamento da própria requisição mantém o erro do contexto
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := transport.RoundTrip(newRequest(ctx)); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip() com contexto cancelado = %v, want context.Canceled", err)
+	}
+
+	// Fechar o corpo mais de uma vez devolve a vaga uma única vez
+	held.Body.Close()
+	held.Body.Close()
+	resp, err := transport.RoundTrip(newRequest(context.Background()))
+	if err != nil {
+		t.Fatalf("chamada após liberar a vaga recusada: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestUpstreamRoutesServerBusy(t *testing.T) {
+	limiter := newUpstreamLimiter(1, 10*time.Millisecond)
+	limiter.acquire(context.Background())
+	defer limiter.release()
+	useTransport(t, &limitTransport{limiter: limiter, next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Errorf("upstream chamado sem vaga: %s", req.URL)
+		return nil, errors.New("sem vaga")
+	})})
+	useRetryBaseDelay(t, 0)
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		path           string
+		expectedStatus int
+	}{
+		{"weatherbycep", NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{}), "/weatherbycep/01310100", http.StatusServiceUnavailable},
+		{"weatherbyaddress", NewWeatherByAddressHandler(addressGeocoder, wttrWeatherResolver{}), "/weatherbyaddress?q=Av+Paulista,+Sao+Paulo", http.StatusServiceUnavailable},
+		{"cepsearch", cepSearchHandler, "/cepsearch?uf=SP&city=S%C3%A3o+Paulo&street=Paulista", http.StatusServiceUnavailable},
+		// A grade responde 200 com o erro de cada ponto
+		{"weather/bbox", NewBBoxWeatherHandler(nil), "/weather/bbox?minlat=-23.6&minlon=-46.7&maxlat=-23.5&maxlon=-46.6&step=0.1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if !strings.Contains(rr.Body.String(), "server busy") {
+				t.Errorf("corpo = %s, want server busy", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewUpstreamLimiterFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxCalls     string
+		wait         string
+		expectNil    bool
+		expectedWait time.Duration
+	}{
+		{"Padrão", "", "", false, defaultUpstreamAcquireWait},
+		{"Espera customizada", "5", "250ms", false, 250 * time.Millisecond},
+		{"Zero desativa", "0", "", true, 0},
+		{"Inválido usa o padrão", "muitas", "logo", false, defaultUpstreamAcquireWait},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_UPSTREAM_CALLS", tt.maxCalls)
+			t.Setenv("UPSTREAM_ACQUIRE_WAIT", tt.wait)
+			limiter := newUpstreamLimiterFromEnv()
+			if (limiter == nil) != tt.expectNil {
+				t.Fatalf("limitador = %v, want nil = %v", limiter, tt.expectNil)
+			}
+			if limiter != nil && limiter.wait != tt.expectedWait {
+				t.Errorf("wait = %v, want %v", limiter.wait, tt.expectedWait)
+			}
+		})
+	}
+}
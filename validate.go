@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ValidationResponse representa o resultado da validação de formato de um CEP
+type ValidationResponse struct {
+	CEP    string `json:"cep"`
+	Valid  bool   `json:"valid"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// validateHandler lida com as requisições GET para /validate/{cep}: verifica
+// apenas o formato e a faixa do CEP, sem consultar os upstreams
+func validateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		setAllow(w, http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "method not allowed"})
+		return
+	}
+
+	cep := cepFromPath(r, "/validate/")
+	if cep == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Message: "cep parameter is required"})
+		return
+	}
+
+	detail := cepValidationDetail(cep)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ValidationResponse{CEP: formatCEP(cep), Valid: detail == "", Detail: detail})
+}
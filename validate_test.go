@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expected       ValidationResponse
+	}{
+		{"CEP válido", "GET", "/validate/01310-100", http.StatusOK, ValidationResponse{CEP: "01310100", Valid: true}},
+		{"CEP curto", "GET", "/validate/123", http.StatusOK, ValidationResponse{CEP: "123", Detail: cepValidationDetail("123")}},
+		{"CEP fora da faixa", "GET", "/validate/00000000", http.StatusOK, ValidationResponse{CEP: "00000000", Detail: cepValidationDetail("00000000")}},
+		{"Sem CEP", "GET", "/validate/", http.StatusBadRequest, ValidationResponse{}},
+		{"Método não permitido", "POST", "/validate/01310100", http.StatusMethodNotAllowed, ValidationResponse{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			validateHandler(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("status = %d, want %d (corpo: %s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp ValidationResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("resposta não é um JSON válido: %v", err)
+			}
+			if resp != tt.expected {
+				t.Errorf("resposta = %+v, want %+v", resp, tt.expected)
+			}
+			if !resp.Valid && resp.Detail == "" {
+				t.Error("CEP inválido deveria trazer o motivo em detail")
+			}
+		})
+	}
+}
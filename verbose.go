@@ -0,0 +1,298 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang-weatherbycep/weather"
+)
+
+// heatIndexThresholdC é a temperatura a partir da qual o índice de calor é calculado
+const heatIndexThresholdC = 27.0
+
+// recentTempsMaxPoints limita a quantidade de leituras em recent_temps
+const recentTempsMaxPoints = 8
+
+// Distâncias entre a área usada pelo wttr.in e a cidade do CEP que definem
+// confidence: até confidenceFullKm a confiança é 1, e cai linearmente até
+// chegar a 0 em confidenceZeroKm
+const (
+	confidenceFullKm = 10.0
+	confidenceZeroKm = 100.0
+)
+
+// Nomes dos campos do modo verbose, usados em partial_fields
+const (
+	fieldHumidity      = "humidity"
+	fieldHeatIndexC    = "heat_index_C"
+	fieldObservedLocal = "observed_local"
+	fieldRecentTemps   = "recent_temps"
+	fieldConfidence    = "confidence"
+)
+
+// Layouts de data/hora usados pelo wttr.in
+const (
+	wttrObservationTimeLayout = "03:04 PM"
+	wttrLocalObsLayout        = "2006-01-02 03:04 PM"
+	wttrDateLayout            = "2006-01-02"
+)
+
+// TempPoint representa uma leitura de temperatura em um horário
+type TempPoint struct {
+	Time  string  `json:"time"`
+	TempC float64 `json:"temp_C"`
+}
+
+// WeatherDetails reúne os dados complementares exibidos no modo verbose. A
+// umidade em si faz parte de WeatherData; aqui ela só é usada no índice de calor.
+// Campos que não puderam ser obtidos do upstream ficam ausentes e são
+// listados em PartialFields, em vez de invalidar toda a resposta.
+type WeatherDetails struct {
+	HeatIndexC    *float64    `json:"heat_index_C,omitempty"`
+	ObservedLocal string      `json:"observed_local,omitempty"`
+	RecentTemps   []TempPoint `json:"recent_temps,omitempty"`
+	Confidence    *float64    `json:"confidence,omitempty"`
+	PartialFields []string    `json:"partial_fields,omitempty"`
+
+	// Alerts traz os alertas meteorológicos do provedor; é sempre um array,
+	// vazio quando o provedor não informa alertas
+	Alerts []WeatherAlert `json:"alerts"`
+
+	// Dados de horário como informados pelo upstream, convertidos para o
+	// fuso do CEP apenas no momento da resposta
+	raw wttrRawDetails
+}
+
+// WeatherAlert representa um alerta meteorológico informado pelo provedor,
+// como um aviso de tempestade
+type WeatherAlert struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// wttrHourly representa uma leitura horária do wttr.in, no horário local da localização
+type wttrHourly = weather.HourlyTemp
+
+// wttrRawDetails guarda os valores brutos do wttr.in usados no modo verbose
+type wttrRawDetails = weather.Observation
+
+// buildWeatherDetails monta os dados do modo verbose a partir dos valores
+// brutos do upstream, registrando os campos que não puderam ser preenchidos
+func buildWeatherDetails(tempC float64, raw wttrRawDetails) *WeatherDetails {
+	details := &WeatherDetails{raw: raw}
+
+	humidity, err := strconv.ParseFloat(raw.Humidity, 64)
+	if err != nil {
+		logger.Warn("umidade indisponível", "provider", providerWttr, "value", raw.Humidity, "error", err)
+		// O índice de calor depende da umidade
+		details.PartialFields = append(details.PartialFields, fieldHumidity, fieldHeatIndexC)
+		return details
+	}
+
+	hi := heatIndex(tempC, humidity)
+	details.HeatIndexC = &hi
+	return details
+}
+
+// parseVerbose lê o parâmetro ?verbose= da query string, desligado por padrão
+func parseVerbose(r *http.Request) (bool, bool) {
+	value := r.URL.Query().Get("verbose")
+	if value == "" {
+		return false, true
+	}
+	verbose, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return verbose, true
+}
+
+// localize retorna uma cópia dos detalhes com os campos dependentes de
+// horário (observed_local e recent_temps) no fuso horário da UF informada.
+// Sem detalhes do upstream, todos os campos são listados como parciais.
+// Os alertas vêm à parte porque nem todo provedor que os informa traz os
+// demais detalhes.
+func (d *WeatherDetails) localize(uf string, alerts []WeatherAlert, now time.Time) *WeatherDetails {
+	if d == nil {
+		d = &WeatherDetails{PartialFields: []string{fieldHumidity, fieldHeatIndexC}}
+	}
+	localized := *d
+	localized.PartialFields = append([]string(nil), d.PartialFields...)
+	localized.Alerts = append([]WeatherAlert{}, alerts...)
+
+	loc, ok := timezoneForUF(uf)
+	if !ok {
+		localized.PartialFields = append(localized.PartialFields, fieldObservedLocal, fieldRecentTemps)
+		return &localized
+	}
+
+	observed, ok := observedLocal(d.raw.ObservationTimeUTC, d.raw.LocalObsDateTime, loc, now)
+	if !ok {
+		// As leituras recentes são relativas ao horário da observação
+		localized.PartialFields = append(localized.PartialFields, fieldObservedLocal, fieldRecentTemps)
+		return &localized
+	}
+	localized.ObservedLocal = observed.Format(time.RFC3339)
+
+	localized.RecentTemps = recentTemps(d.raw.Hourly, loc, observed, recentTempsMaxPoints)
+	if len(localized.RecentTemps) == 0 {
+		localized.PartialFields = append(localized.PartialFields, fieldRecentTemps)
+	}
+
+	return &localized
+}
+
+// withConfidence preenche confidence, de 0 a 1, pela distância entre a área
+// usada pelo wttr.in (nearest_area) e as coordenadas da cidade do CEP: quanto
+// mais longe, menos os dados representam a cidade. Sem uma das duas
+// posições, o campo é listado em partial_fields. Altera os detalhes, então
+// deve ser chamado na cópia retornada por localize.
+func (d *WeatherDetails) withConfidence(city *Coordinates) *WeatherDetails {
+	lat, latErr := strconv.ParseFloat(d.raw.NearestAreaLatitude, 64)
+	lon, lonErr := strconv.ParseFloat(d.raw.NearestAreaLongitude, 64)
+	if city == nil || latErr != nil || lonErr != nil {
+		d.PartialFields = append(d.PartialFields, fieldConfidence)
+		return d
+	}
+
+	confidence := matchConfidence(distanceKm(Coordinates{Lat: lat, Lon: lon}, *city))
+	d.Confidence = &confidence
+	return d
+}
+
+// matchConfidence converte a distância em km na confiança, arredondada a
+// duas casas decimais
+func matchConfidence(distance float64) float64 {
+	switch {
+	case distance <= confidenceFullKm:
+		return 1
+	case distance >= confidenceZeroKm:
+		return 0
+	}
+	confidence := (confidenceZeroKm - distance) / (confidenceZeroKm - confidenceFullKm)
+	return math.Round(confidence*100) / 100
+}
+
+// recentTemps extrai, em ordem cronológica, as últimas leituras horárias até
+// o horário da observação, limitadas a maxPoints. Leituras com data, hora ou
+// temperatura inválidas são ignoradas.
+func recentTemps(hourly []wttrHourly, loc *time.Location, until time.Time, maxPoints int) []TempPoint {
+	type reading struct {
+		at    time.Time
+		tempC float64
+	}
+
+	var readings []reading
+	for _, h := range hourly {
+		at, tempC, ok := parseHourlyReading(h, loc)
+		if !ok || at.After(until) {
+			continue
+		}
+		readings = append(readings, reading{at: at, tempC: tempC})
+	}
+
+	sort.Slice(readings, func(i, j int) bool { return readings[i].at.Before(readings[j].at) })
+	if len(readings) > maxPoints {
+		readings = readings[len(readings)-maxPoints:]
+	}
+
+	points := make([]TempPoint, 0, len(readings))
+	for _, r := range readings {
+		points = append(points, TempPoint{Time: r.at.Format(time.RFC3339), TempC: r.tempC})
+	}
+	return points
+}
+
+// parseHourlyReading interpreta uma leitura horária do wttr.in no fuso
+// informado, indicando se a data, a hora e a temperatura são válidas
+func parseHourlyReading(h wttrHourly, loc *time.Location) (time.Time, float64, bool) {
+	day, err := time.ParseInLocation(wttrDateLayout, h.Date, loc)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	// O wttr.in informa a hora como HHMM sem zeros à esquerda ("0", "300", "1200")
+	hhmm, err := strconv.Atoi(h.Time)
+	if err != nil || hhmm < 0 || hhmm/100 > 23 || hhmm%100 > 59 {
+		return time.Time{}, 0, false
+	}
+	tempC, err := strconv.ParseFloat(h.TempC, 64)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hhmm/100, hhmm%100, 0, 0, loc), tempC, true
+}
+
+// observedLocal converte o horário da observação para o fuso informado.
+// O wttr.in informa em observation_time apenas a hora em UTC, sem data; nesse
+// caso a data é a da observação mais recente que não esteja no futuro em
+// relação a now. Sem a hora em UTC, localObsDateTime é interpretado no fuso.
+func observedLocal(observationTimeUTC, localObsDateTime string, loc *time.Location, now time.Time) (time.Time, bool) {
+	if clock, err := time.Parse(wttrObservationTimeLayout, observationTimeUTC); err == nil {
+		now = now.UTC()
+		observed := time.Date(now.Year(), now.Month(), now.Day(),
+			clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+		if observed.After(now) {
+			observed = observed.AddDate(0, 0, -1)
+		}
+		return observed.In(loc), true
+	}
+
+	if observed, err := time.ParseInLocation(wttrLocalObsLayout, localObsDateTime, loc); err == nil {
+		return observed, true
+	}
+
+	return time.Time{}, false
+}
+
+// formatAddress monta o endereço do CEP em uma única linha, no formato
+// "logradouro, bairro, cidade - UF, CEP, Brasil", ignorando partes vazias
+func formatAddress(cepData *CEPData) string {
+	cityUF := cepData.Localidade
+	if cepData.UF != "" {
+		if cityUF != "" {
+			cityUF += " - "
+		}
+		cityUF += cepData.UF
+	}
+
+	var parts []string
+	for _, part := range []string{cepData.Logradouro, cepData.Bairro, cityUF, cepData.CEP} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(append(parts, "Brasil"), ", ")
+}
+
+// heatIndex calcula a temperatura aparente (índice de calor) em Celsius usando
+// a regressão de Rothfusz adotada pelo NWS. Abaixo de 27°C o índice de calor
+// não é significativo e a própria temperatura é retornada.
+func heatIndex(tempC, humidity float64) float64 {
+	if tempC <= heatIndexThresholdC {
+		return tempC
+	}
+
+	t := (tempC * 9 / 5) + 32
+	rh := humidity
+
+	hi := -42.379 + 2.04901523*t + 10.14333127*rh -
+		0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+		0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+
+	// Ajustes do NWS para umidade muito baixa ou muito alta
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= ((13 - rh) / 4) * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += ((rh - 85) / 10) * ((87 - t) / 5)
+	}
+
+	return (hi - 32) * 5 / 9
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeatIndex(t *testing.T) {
+	// Valores de referência da tabela de índice de calor do NWS, em Fahrenheit
+	tests := []struct {
+		name      string
+		tempF     float64
+		humidity  float64
+		expectedF float64
+	}{
+		{"90F e 70%", 90, 70, 106},
+		{"96F e 50%", 96, 50, 108},
+		{"86F e 90%", 86, 90, 105},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempC := (tt.tempF - 32) * 5 / 9
+			resultF := heatIndex(tempC, tt.humidity)*9/5 + 32
+			if math.Abs(resultF-tt.expectedF) > 1 {
+				t.Errorf("heatIndex(%.1fF, %.0f%%) = %.1fF, want %.0fF",
+					tt.tempF, tt.humidity, resultF, tt.expectedF)
+			}
+		})
+	}
+}
+
+func TestHeatIndexBelowThreshold(t *testing.T) {
+	for _, tempC := range []float64{-5, 20, 27} {
+		if result := heatIndex(tempC, 90); result != tempC {
+			t.Errorf("heatIndex(%v, 90) = %v, want %v", tempC, result, tempC)
+		}
+	}
+}
+
+func TestParseVerbose(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected bool
+		valid    bool
+	}{
+		{"", false, true},
+		{"?verbose=true", true, true},
+		{"?verbose=1", true, true},
+		{"?verbose=false", false, true},
+		{"?verbose=sim", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil)
+			verbose, ok := parseVerbose(req)
+			if verbose != tt.expected || ok != tt.valid {
+				t.Errorf("parseVerbose(%s) = (%v, %v), want (%v, %v)",
+					tt.query, verbose, ok, tt.expected, tt.valid)
+			}
+		})
+	}
+}
+
+func TestWeatherByCEPHandlerVerbose(t *testing.T) {
+	useCassette(t, "20040002")
+	// Centro do Rio de Janeiro, a cerca de 3 km da área informada pelo wttr.in
+	useLocalityGeocoder(t, &fakeGeocoder{coords: Coordinates{Lat: -22.9035, Lon: -43.2096}})
+
+	req := httptest.NewRequest("GET", "/weatherbycep/20040002?verbose=true", nil)
+	rr := httptest.NewRecorder()
+	NewWeatherHandler(viaCEPResolver{}, wttrWeatherResolver{})(rr, req)
+
+	var resp WeatherResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+	if resp.WeatherDetails == nil {
+		t.Fatalf("modo verbose sem detalhes: %s", rr.Body.String())
+	}
+	if resp.Humidity != 70 {
+		t.Errorf("humidity incorreta: got %v want 70", resp.Humidity)
+	}
+	if expected := heatIndex(resp.TempC, 70); resp.HeatIndexC == nil || *resp.HeatIndexC != expected {
+		t.Errorf("heat_index_C incorreto: got %v want %v", resp.HeatIndexC, expected)
+	}
+	if len(resp.PartialFields) != 0 {
+		t.Errorf("partial_fields deveria estar vazio: %v", resp.PartialFields)
+	}
+	if resp.Confidence == nil || *resp.Confidence != 1 {
+		t.Errorf("confidence = %v, want 1", resp.Confidence)
+	}
+	if !strings.HasSuffix(resp.ObservedLocal, ":00-03:00") {
+		t.Errorf("observed_local deveria estar no fuso do Rio de Janeiro: %q", resp.ObservedLocal)
+	}
+	if expected := "Rua da Quitanda, Centro, Rio de Janeiro - RJ, 20040-002, Brasil"; resp.FormattedAddress != expected {
+		t.Errorf("formatted_address incorreto: got %q want %q", resp.FormattedAddress, expected)
+	}
+}
+
+// alertWeatherResolver simula um provedor que informa alertas meteorológicos
+type alertWeatherResolver struct {
+	alerts []WeatherAlert
+}
+
+func (a alertWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	weatherData := weatherFromCelsius(23)
+	weatherData.Alerts = a.alerts
+	return &weatherData, nil
+}
+
+func TestWeatherByCEPHandlerAlerts(t *testing.T) {
+	storm := WeatherAlert{Type: "storm", Description: "Tempestade com rajadas de vento"}
+	tests := []struct {
+		name     string
+		query    string
+		alerts   []WeatherAlert
+		expected string
+	}{
+		{"Verbose com alerta", "?verbose=true", []WeatherAlert{storm}, `[{"type":"storm","description":"Tempestade com rajadas de vento"}]`},
+		{"Verbose sem alertas", "?verbose=true", nil, `[]`},
+		{"Sem verbose", "", []WeatherAlert{storm}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weatherbycep/01310100"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			NewWeatherHandler(newFakeCEPResolver(), alertWeatherResolver{alerts: tt.alerts})(rr, req)
+
+			var resp map[string]json.RawMessage
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Resposta não é um JSON válido: %v", err)
+			}
+			if got := string(resp["alerts"]); got != tt.expected {
+				t.Errorf("alerts = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseWttrResponsePartialFields(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedPartial []string
+	}{
+		{
+			name:            "Todos os campos presentes",
+			body:            `{"current_condition":[{"temp_C":"30","humidity":"60"}]}`,
+			expectedPartial: nil,
+		},
+		{
+			name:            "Umidade ausente",
+			body:            `{"current_condition":[{"temp_C":"30"}]}`,
+			expectedPartial: []string{fieldHumidity, fieldHeatIndexC},
+		},
+		{
+			name:            "Umidade inválida",
+			body:            `{"current_condition":[{"temp_C":"30","humidity":"n/a"}]}`,
+			expectedPartial: []string{fieldHumidity, fieldHeatIndexC},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherData := parseWttr(t, []byte(tt.body))
+
+			// A temperatura continua disponível mesmo com campos parciais
+			if weatherData.TempC != 30 {
+				t.Errorf("temp_C incorreta: got %v want 30", weatherData.TempC)
+			}
+			if !reflect.DeepEqual(weatherData.Details.PartialFields, tt.expectedPartial) {
+				t.Errorf("partial_fields incorreto: got %v want %v",
+					weatherData.Details.PartialFields, tt.expectedPartial)
+			}
+
+			hasHumidity := weatherData.Details.HeatIndexC != nil
+			if hasHumidity != (tt.expectedPartial == nil) {
+				t.Errorf("humidity preenchida = %v, partial_fields = %v", hasHumidity, tt.expectedPartial)
+			}
+		})
+	}
+}
+
+func TestFormatAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		cepData  CEPData
+		expected string
+	}{
+		{
+			name: "Endereço completo",
+			cepData: CEPData{
+				CEP: "01310-100", Logradouro: "Avenida Paulista", Bairro: "Bela Vista",
+				Localidade: "São Paulo", UF: "SP",
+			},
+			expected: "Avenida Paulista, Bela Vista, São Paulo - SP, 01310-100, Brasil",
+		},
+		{
+			name:     "CEP geral de cidade, sem logradouro e bairro",
+			cepData:  CEPData{CEP: "69900-970", Localidade: "Rio Branco", UF: "AC"},
+			expected: "Rio Branco - AC, 69900-970, Brasil",
+		},
+		{
+			name:     "Componentes apenas com espaços",
+			cepData:  CEPData{CEP: "20040-002", Logradouro: " ", Bairro: "Centro", Localidade: "Rio de Janeiro", UF: "RJ"},
+			expected: "Centro, Rio de Janeiro - RJ, 20040-002, Brasil",
+		},
+		{
+			name:     "Sem cidade",
+			cepData:  CEPData{CEP: "20040-002", UF: "RJ"},
+			expected: "RJ, 20040-002, Brasil",
+		},
+		{
+			name:     "Vazio",
+			cepData:  CEPData{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := formatAddress(&tt.cepData); result != tt.expected {
+				t.Errorf("formatAddress() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestObservedLocal(t *testing.T) {
+	acre, _ := timezoneForUF("AC")
+	saoPaulo, _ := timezoneForUF("SP")
+	now := time.Date(2025, 1, 15, 16, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		observationTimeUTC string
+		localObsDateTime   string
+		loc                *time.Location
+		expected           string
+		ok                 bool
+	}{
+		{
+			name:               "Acre a partir do horário UTC",
+			observationTimeUTC: "03:00 PM",
+			loc:                acre,
+			expected:           "2025-01-15T10:00:00-05:00",
+			ok:                 true,
+		},
+		{
+			name:               "Horário UTC posterior a now pertence ao dia anterior",
+			observationTimeUTC: "11:45 PM",
+			loc:                acre,
+			expected:           "2025-01-14T18:45:00-05:00",
+			ok:                 true,
+		},
+		{
+			name:             "Sem horário UTC usa o horário local",
+			localObsDateTime: "2025-01-15 01:15 PM",
+			loc:              saoPaulo,
+			expected:         "2025-01-15T13:15:00-03:00",
+			ok:               true,
+		},
+		{
+			name: "Sem horário da observação",
+			loc:  acre,
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observed, ok := observedLocal(tt.observationTimeUTC, tt.localObsDateTime, tt.loc, now)
+			if ok != tt.ok {
+				t.Fatalf("observedLocal() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && observed.Format(time.RFC3339) != tt.expected {
+				t.Errorf("observedLocal() = %s, want %s", observed.Format(time.RFC3339), tt.expected)
+			}
+		})
+	}
+}
+
+func TestWeatherDetailsLocalize(t *testing.T) {
+	details := buildWeatherDetails(25, wttrRawDetails{Humidity: "80", ObservationTimeUTC: "03:00 PM"})
+	now := time.Date(2025, 1, 15, 16, 30, 0, 0, time.UTC)
+
+	localized := details.localize("AC", nil, now)
+	if localized.ObservedLocal != "2025-01-15T10:00:00-05:00" {
+		t.Errorf("observed_local incorreto: %q", localized.ObservedLocal)
+	}
+	if details.ObservedLocal != "" {
+		t.Error("localize não deveria alterar os detalhes originais")
+	}
+
+	// Sem leituras horárias, recent_temps entra em partial_fields
+	if !reflect.DeepEqual(localized.PartialFields, []string{fieldRecentTemps}) {
+		t.Errorf("partial_fields incorreto: %v", localized.PartialFields)
+	}
+
+	// UF desconhecida: os campos de horário entram em partial_fields
+	localized = details.localize("XX", nil, now)
+	if localized.ObservedLocal != "" || !reflect.DeepEqual(localized.PartialFields, []string{fieldObservedLocal, fieldRecentTemps}) {
+		t.Errorf("localize com UF inválida = %+v", localized)
+	}
+}
+
+func TestWeatherDetailsWithConfidence(t *testing.T) {
+	// Centro de São Paulo
+	city := &Coordinates{Lat: -23.5505, Lon: -46.6333}
+
+	tests := []struct {
+		name     string
+		lat, lon string
+		city     *Coordinates
+		partial  bool
+		expected float64
+	}{
+		{"Área próxima", "-23.533", "-46.617", city, false, 1},
+		{"Área a cerca de 55 km", "-23.056", "-46.633", city, false, 0.5},
+		{"Área distante", "-22.900", "-43.233", city, false, 0},
+		{"Sem nearest_area", "", "", city, true, 0},
+		{"Sem coordenadas da cidade", "-23.533", "-46.617", nil, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := &WeatherDetails{raw: wttrRawDetails{NearestAreaLatitude: tt.lat, NearestAreaLongitude: tt.lon}}
+			got := details.withConfidence(tt.city)
+
+			if tt.partial {
+				if got.Confidence != nil || !reflect.DeepEqual(got.PartialFields, []string{fieldConfidence}) {
+					t.Errorf("confidence = %v, partial_fields = %v, want ausente e listado", got.Confidence, got.PartialFields)
+				}
+				return
+			}
+			if got.Confidence == nil {
+				t.Fatalf("confidence ausente, want %v (partial_fields = %v)", tt.expected, got.PartialFields)
+			}
+			if math.Abs(*got.Confidence-tt.expected) > 0.01 {
+				t.Errorf("confidence = %v, want %v", *got.Confidence, tt.expected)
+			}
+		})
+	}
+
+	// Uma área mais distante nunca tem confiança maior
+	near := (&WeatherDetails{raw: wttrRawDetails{NearestAreaLatitude: "-23.533", NearestAreaLongitude: "-46.617"}}).withConfidence(city)
+	far := (&WeatherDetails{raw: wttrRawDetails{NearestAreaLatitude: "-23.187", NearestAreaLongitude: "-46.884"}}).withConfidence(city)
+	if *far.Confidence >= *near.Confidence {
+		t.Errorf("confidence distante = %v, want menor que a próxima (%v)", *far.Confidence, *near.Confidence)
+	}
+}
+
+func TestRecentTemps(t *testing.T) {
+	loc, _ := timezoneForUF("SP")
+	until := time.Date(2025, 1, 16, 7, 15, 0, 0, loc)
+
+	hourly := []wttrHourly{
+		{Date: "2025-01-16", Time: "900", TempC: "21"}, // depois da observação
+		{Date: "2025-01-16", Time: "600", TempC: "18"},
+		{Date: "2025-01-16", Time: "300", TempC: "n/a"}, // leitura esparsa
+		{Date: "2025-01-16", Time: "0", TempC: "19"},
+		{Date: "2025-01-15", Time: "2100", TempC: "20"},
+		{Date: "2025-01-15", Time: "1800", TempC: "23"},
+		{Date: "2025-01-15", Time: "1500", TempC: "26"},
+		{Date: "", Time: "1200", TempC: "25"}, // sem data
+	}
+
+	points := recentTemps(hourly, loc, until, 3)
+	expected := []TempPoint{
+		{Time: "2025-01-15T21:00:00-03:00", TempC: 20},
+		{Time: "2025-01-16T00:00:00-03:00", TempC: 19},
+		{Time: "2025-01-16T06:00:00-03:00", TempC: 18},
+	}
+	if !reflect.DeepEqual(points, expected) {
+		t.Errorf("recentTemps() = %+v, want %+v", points, expected)
+	}
+
+	if points := recentTemps(nil, loc, until, 3); len(points) != 0 {
+		t.Errorf("recentTemps(nil) = %+v, want vazio", points)
+	}
+}
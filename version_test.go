@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestVersionHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	versionHandler(rr, httptest.NewRequest("GET", "/version", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var resp VersionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Resposta não é um JSON válido: %v", err)
+	}
+
+	// Sem -ldflags os valores padrão são usados
+	expected := VersionResponse{Version: "dev", Commit: "unknown", BuildTime: "unknown", GoVersion: runtime.Version()}
+	if resp != expected {
+		t.Errorf("resposta = %+v, want %+v", resp, expected)
+	}
+}
+
+func TestVersionHandlerMethodNotAllowed(t *testing.T) {
+	rr := httptest.NewRecorder()
+	versionHandler(rr, httptest.NewRequest("POST", "/version", nil))
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("handler retornou status code errado: got %v want %v", rr.Code, http.StatusMethodNotAllowed)
+	}
+}
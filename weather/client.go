@@ -0,0 +1,345 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Endereços públicos dos provedores usados pelo DefaultClient
+const (
+	DefaultViaCEPBaseURL  = "https://viacep.com.br/ws"
+	DefaultWeatherBaseURL = "https://wttr.in"
+)
+
+// Doer executa as requisições HTTP do cliente. *http.Client o implementa;
+// outras implementações podem acrescentar retentativas, limites ou métricas.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client consulta o ViaCEP e o wttr.in. O valor zero não é utilizável; use
+// NewClient ou preencha os campos de URL e o HTTPClient.
+type Client struct {
+	// ViaCEPBaseURL e WeatherBaseURL permitem apontar para espelhos ou servidores de teste
+	ViaCEPBaseURL  string
+	WeatherBaseURL string
+
+	// HTTPClient faz as chamadas aos provedores
+	HTTPClient Doer
+
+	// UserAgent identifica o chamador para os provedores; vazio mantém o padrão do Go
+	UserAgent string
+
+	// Lang é o idioma da descrição do tempo (ex.: "pt"); vazio mantém o padrão do wttr.in
+	Lang string
+}
+
+// NewClient cria um cliente apontando para os provedores públicos
+func NewClient() *Client {
+	return &Client{
+		ViaCEPBaseURL:  DefaultViaCEPBaseURL,
+		WeatherBaseURL: DefaultWeatherBaseURL,
+		HTTPClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// DefaultClient é o cliente usado pelas funções do pacote
+var DefaultClient = NewClient()
+
+// LookupByCEP consulta o endereço do CEP e a temperatura da cidade usando o DefaultClient
+func LookupByCEP(ctx context.Context, cep string) (*WeatherResult, error) {
+	return DefaultClient.LookupByCEP(ctx, cep)
+}
+
+// LookupByCEP consulta o endereço do CEP e a temperatura da cidade. Os erros
+// são sempre do tipo *Error.
+func (c *Client) LookupByCEP(ctx context.Context, cep string) (*WeatherResult, error) {
+	address, err := c.SearchCEP(ctx, cep)
+	if err != nil {
+		return nil, err
+	}
+	current, err := c.CurrentWeather(ctx, address.Localidade, address.UF)
+	if err != nil {
+		return nil, err
+	}
+	return &WeatherResult{Address: *address, Weather: *current}, nil
+}
+
+// SearchCEP consulta o endereço do CEP no ViaCEP
+func (c *Client) SearchCEP(ctx context.Context, cep string) (*CEPData, error) {
+	if detail := ValidateCEP(cep); detail != "" {
+		return nil, &Error{Code: 422, Message: "invalid zipcode", Detail: detail, Err: ErrInvalidCEP}
+	}
+
+	resp, err := c.get(ctx, fmt.Sprintf("%s/%s/json/", c.ViaCEPBaseURL, FormatCEP(cep)))
+	if err != nil {
+		// O ViaCEP responde 400 para alguns CEPs que passam na validação;
+		// é um erro do chamador, não uma falha do provedor
+		if err.Code == http.StatusBadRequest {
+			return nil, &Error{Code: 422, Message: "invalid zipcode", Err: ErrInvalidCEP}
+		}
+		return nil, err
+	}
+
+	var data CEPData
+	if err := json.Unmarshal(resp.body, &data); err != nil {
+		return nil, &Error{Code: 500, Message: "internal server error", Err: err}
+	}
+	if IsNotFound(&data) {
+		return nil, &Error{Code: 404, Message: "can not find zipcode", Err: ErrCEPNotFound}
+	}
+	return &data, nil
+}
+
+// CurrentWeather consulta a temperatura atual da cidade no wttr.in
+func (c *Client) CurrentWeather(ctx context.Context, city, state string) (*WeatherData, error) {
+	location := fmt.Sprintf("%s,%s,Brazil", strings.ReplaceAll(city, " ", "+"), strings.ReplaceAll(state, " ", "+"))
+	return c.currentWeather(ctx, location)
+}
+
+// CurrentWeatherAt consulta a temperatura atual de uma coordenada geográfica no wttr.in
+func (c *Client) CurrentWeatherAt(ctx context.Context, lat, lon float64) (*WeatherData, error) {
+	return c.currentWeather(ctx, fmt.Sprintf("%.4f,%.4f", lat, lon))
+}
+
+// currentWeather consulta o wttr.in no formato JSON (format=j1) para a
+// localização informada (nome ou "lat,lon")
+func (c *Client) currentWeather(ctx context.Context, location string) (*WeatherData, error) {
+	url := fmt.Sprintf("%s/%s?format=j1", c.WeatherBaseURL, url.QueryEscape(location))
+	if c.Lang != "" {
+		url += "&lang=" + c.Lang
+	}
+
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		if err.Code == http.StatusBadRequest {
+			return nil, statusError(http.StatusBadRequest)
+		}
+		return nil, err
+	}
+
+	// Sob limite de requisições o wttr.in às vezes devolve HTML em vez do
+	// JSON; nesse caso a temperatura é obtida do formato texto compacto
+	if !isJSONResponse(resp.contentType, resp.body) {
+		return c.currentWeatherText(ctx, location)
+	}
+	return ParseWttrJSON(resp.body)
+}
+
+// currentWeatherText consulta o wttr.in no formato texto "%t", que traz apenas
+// a temperatura (ex.: "+23°C")
+func (c *Client) currentWeatherText(ctx context.Context, location string) (*WeatherData, error) {
+	resp, err := c.get(ctx, fmt.Sprintf("%s/%s?format=%%25t&m", c.WeatherBaseURL, url.QueryEscape(location)))
+	if err != nil {
+		if err.Code == http.StatusBadRequest {
+			return nil, statusError(http.StatusBadRequest)
+		}
+		return nil, err
+	}
+
+	tempC, ok := parseWttrTemperature(string(resp.body))
+	if !ok {
+		return nil, &Error{Code: 500, Message: "weather data not available", Err: fmt.Errorf("invalid text temperature %q", resp.body)}
+	}
+	data := FromCelsius(tempC)
+	return &data, nil
+}
+
+// isJSONResponse indica se a resposta parece JSON: recusa Content-Type HTML e
+// corpos que começam com "<". Respostas sem Content-Type explícito são aceitas.
+func isJSONResponse(contentType string, body []byte) bool {
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "<") {
+		return false
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mediaType != "text/html"
+}
+
+// parseWttrTemperature lê a temperatura do formato texto do wttr.in, como
+// "+23°C" ou "-1.5°C"
+func parseWttrTemperature(text string) (float64, bool) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "C"), "°")
+	tempC, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	return tempC, err == nil
+}
+
+// wttrDay representa um dia do array weather do wttr.in (format=j1)
+type wttrDay struct {
+	Date     string `json:"date"`
+	MaxTempC string `json:"maxtempC"`
+	MinTempC string `json:"mintempC"`
+	Hourly   []struct {
+		Time  string `json:"time"`
+		TempC string `json:"tempC"`
+	} `json:"hourly"`
+}
+
+// ParseWttrJSON decodifica uma resposta JSON (format=j1) do wttr.in. Os
+// campos complementares são opcionais: valores ausentes ou inválidos apenas
+// ficam zerados.
+func ParseWttrJSON(body []byte) (*WeatherData, error) {
+	var response struct {
+		CurrentCondition []struct {
+			TempC            string `json:"temp_C"`
+			FeelsLikeC       string `json:"FeelsLikeC"`
+			Humidity         string `json:"humidity"`
+			ObservationTime  string `json:"observation_time"`
+			LocalObsDateTime string `json:"localObsDateTime"`
+			WeatherDesc      []struct {
+				Value string `json:"value"`
+			} `json:"weatherDesc"`
+		} `json:"current_condition"`
+		NearestArea []struct {
+			Latitude  string `json:"latitude"`
+			Longitude string `json:"longitude"`
+		} `json:"nearest_area"`
+		Weather []wttrDay `json:"weather"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, &Error{Code: 500, Message: "internal server error", Err: err}
+	}
+	if len(response.CurrentCondition) == 0 {
+		return nil, &Error{Code: 500, Message: "weather data not available"}
+	}
+
+	current := response.CurrentCondition[0]
+	tempC, err := strconv.ParseFloat(current.TempC, 64)
+	if err != nil {
+		return nil, &Error{Code: 500, Message: "internal server error", Err: err}
+	}
+
+	data := FromCelsius(tempC)
+	data.Humidity, _ = strconv.Atoi(current.Humidity)
+	data.FeelsLikeC, _ = strconv.ParseFloat(current.FeelsLikeC, 64)
+	if len(current.WeatherDesc) > 0 {
+		data.Description = strings.TrimSpace(current.WeatherDesc[0].Value)
+	}
+
+	data.Observation = &Observation{
+		Humidity:           current.Humidity,
+		ObservationTimeUTC: current.ObservationTime,
+		LocalObsDateTime:   current.LocalObsDateTime,
+	}
+	if len(response.NearestArea) > 0 {
+		data.Observation.NearestAreaLatitude = response.NearestArea[0].Latitude
+		data.Observation.NearestAreaLongitude = response.NearestArea[0].Longitude
+	}
+	for _, day := range response.Weather {
+		for _, h := range day.Hourly {
+			data.Observation.Hourly = append(data.Observation.Hourly, HourlyTemp{Date: day.Date, Time: h.Time, TempC: h.TempC})
+		}
+	}
+	data.Forecast = buildForecast(response.Weather)
+	return &data, nil
+}
+
+// buildForecast converte os dias do wttr.in, ignorando os que têm data ou
+// temperaturas ausentes ou inválidas
+func buildForecast(days []wttrDay) []ForecastDay {
+	var forecast []ForecastDay
+	for _, day := range days {
+		if strings.TrimSpace(day.Date) == "" {
+			continue
+		}
+		maxTempC, err := strconv.ParseFloat(strings.TrimSpace(day.MaxTempC), 64)
+		if err != nil {
+			continue
+		}
+		minTempC, err := strconv.ParseFloat(strings.TrimSpace(day.MinTempC), 64)
+		if err != nil {
+			continue
+		}
+		forecast = append(forecast, ForecastDay{Date: day.Date, MinTempC: minTempC, MaxTempC: maxTempC})
+	}
+	return forecast
+}
+
+// GetJSON faz um GET pelo HTTPClient do cliente e decodifica a resposta 200
+// em v. Serve aos provedores complementares (BrasilAPI, Open-Meteo etc.), que
+// não têm o tratamento especial de 400 do ViaCEP e do wttr.in: qualquer outro
+// status vira 502 com um *StatusError embrulhado. Os erros são sempre do tipo *Error.
+func (c *Client) GetJSON(ctx context.Context, url string, v any) error {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		if err.Code == http.StatusBadRequest {
+			return statusError(http.StatusBadRequest)
+		}
+		return err
+	}
+	if err := json.Unmarshal(resp.body, v); err != nil {
+		return &Error{Code: 500, Message: "internal server error", Err: err}
+	}
+	return nil
+}
+
+// response guarda o que o cliente usa de uma resposta 200 de um provedor
+type response struct {
+	body        []byte
+	contentType string
+}
+
+// get faz um GET e retorna as respostas 200. Status 400 é devolvido com Code
+// 400 para que o chamador decida o significado; os demais viram 502.
+func (c *Client) get(ctx context.Context, url string) (*response, *Error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, &Error{Code: 500, Message: "internal server error", Err: err}
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, RequestError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest:
+		return nil, &Error{Code: 400, Message: "bad request"}
+	case resp.StatusCode != http.StatusOK:
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, RequestError(err)
+	}
+	return &response{body: body, contentType: resp.Header.Get("Content-Type")}, nil
+}
+
+// statusError é o erro das respostas inesperadas de um provedor; o status
+// recebido fica no *StatusError embrulhado, disponível para logs
+func statusError(status int) *Error {
+	return &Error{Code: 502, Message: "bad gateway", Err: &StatusError{StatusCode: status}}
+}
+
+// RequestError converte a falha de rede de uma chamada a um provedor em erro:
+// timeouts viram 504 e as demais falhas, 500. O erro original fica embrulhado
+// para logs, sem aparecer em Message ou Detail.
+func RequestError(err error) *Error {
+	if isTimeout(err) {
+		return &Error{Code: 504, Message: "upstream timeout", Err: ErrUpstreamTimeout}
+	}
+	return &Error{Code: 500, Message: "internal server error", Err: err}
+}
+
+// isTimeout indica se o erro é um estouro de prazo, do contexto ou da rede
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,58 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Erros sentinela que identificam o motivo de uma falha. Os *Error
+// retornados pela biblioteca os embrulham, permitindo usar errors.Is.
+var (
+	// ErrInvalidCEP indica um CEP com formato inválido ou recusado pelo provedor
+	ErrInvalidCEP = errors.New("invalid zipcode")
+	// ErrCEPNotFound indica um CEP bem formado que não existe
+	ErrCEPNotFound = errors.New("zipcode not found")
+	// ErrUpstreamUnavailable indica que um provedor falhou ou está indisponível
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	// ErrUpstreamTimeout indica que um provedor não respondeu dentro do prazo
+	ErrUpstreamTimeout = errors.New("upstream timeout")
+)
+
+// StatusError é a causa embrulhada nos erros de respostas inesperadas de um
+// provedor. Permite ao chamador tratar um status específico, como o 404 de
+// um CEP inexistente, com errors.As.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream status %d", e.StatusCode)
+}
+
+// Unwrap expõe ErrUpstreamUnavailable para errors.Is
+func (e *StatusError) Unwrap() error {
+	return ErrUpstreamUnavailable
+}
+
+// HTTPStatus retorna o status HTTP equivalente ao erro: o Code de um *Error
+// ou, para os erros sentinela, o status correspondente. Erros desconhecidos
+// viram 500.
+func HTTPStatus(err error) int {
+	var werr *Error
+	if errors.As(err, &werr) && werr.Code != 0 {
+		return werr.Code
+	}
+	switch {
+	case errors.Is(err, ErrInvalidCEP):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrCEPNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return http.StatusBadGateway
+	case errors.Is(err, ErrUpstreamTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,150 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLookupByCEPSentinelErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cep      string
+		viaCEP   http.HandlerFunc
+		sentinel error
+	}{
+		{"CEP inválido", "abc", reply(200, paulistaJSON), ErrInvalidCEP},
+		{"CEP recusado pelo ViaCEP", "01310100", reply(400, ""), ErrInvalidCEP},
+		{"CEP inexistente", "01310100", reply(200, `{"erro": true}`), ErrCEPNotFound},
+		{"ViaCEP fora do ar", "01310100", reply(503, ""), ErrUpstreamUnavailable},
+		{"ViaCEP lento", "01310100", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}, ErrUpstreamTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, tt.viaCEP, reply(200, sunnyJSON))
+			client.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+			_, err := client.LookupByCEP(context.Background(), tt.cep)
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("erro = %v, want errors.Is(%v)", err, tt.sentinel)
+			}
+			for _, other := range []error{ErrInvalidCEP, ErrCEPNotFound, ErrUpstreamUnavailable, ErrUpstreamTimeout} {
+				if other != tt.sentinel && errors.Is(err, other) {
+					t.Errorf("erro %v não deveria corresponder a %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"Error com código", &Error{Code: 422, Err: ErrInvalidCEP}, http.StatusUnprocessableEntity},
+		{"Sentinela de CEP inválido", ErrInvalidCEP, http.StatusUnprocessableEntity},
+		{"Sentinela de CEP inexistente", ErrCEPNotFound, http.StatusNotFound},
+		{"Sentinela de indisponibilidade", ErrUpstreamUnavailable, http.StatusBadGateway},
+		{"Sentinela embrulhada", fmt.Errorf("consulta: %w", ErrUpstreamTimeout), http.StatusGatewayTimeout},
+		{"Erro desconhecido", errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatus(tt.err); got != tt.expected {
+				t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+// timeoutError simula o erro de rede devolvido pelo timeout do http.Client
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRequestError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{"Prazo do contexto", fmt.Errorf("get: %w", context.DeadlineExceeded), 504},
+		{"Timeout de rede", timeoutError{}, 504},
+		{"Cancelamento", context.Canceled, 500},
+		{"Conexão recusada", errors.New("connection refused"), 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RequestError(tt.err); err.Code != tt.expectedCode {
+				t.Errorf("RequestError(%v).Code = %d, want %d", tt.err, err.Code, tt.expectedCode)
+			}
+		})
+	}
+}
+
+func TestGetJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		expectedCode   int
+		expectedStatus int
+		expectedValue  string
+	}{
+		{"Sucesso", reply(200, `{"value": "ok"}`), 0, 0, "ok"},
+		{"Não encontrado", reply(404, ""), 502, 404, ""},
+		{"Requisição recusada", reply(400, ""), 502, 400, ""},
+		{"Provedor fora do ar", reply(503, ""), 502, 503, ""},
+		{"JSON inválido", reply(200, "<html>"), 500, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			t.Cleanup(server.Close)
+
+			var got struct {
+				Value string `json:"value"`
+			}
+			err := NewClient().GetJSON(context.Background(), server.URL, &got)
+			if tt.expectedCode == 0 {
+				if err != nil {
+					t.Fatalf("GetJSON retornou erro: %v", err)
+				}
+				if got.Value != tt.expectedValue {
+					t.Errorf("value = %q, want %q", got.Value, tt.expectedValue)
+				}
+				return
+			}
+
+			if status := HTTPStatus(err); status != tt.expectedCode {
+				t.Errorf("HTTPStatus(%v) = %d, want %d", err, status, tt.expectedCode)
+			}
+			var statusErr *StatusError
+			if tt.expectedStatus == 0 {
+				if errors.As(err, &statusErr) {
+					t.Errorf("erro %v não deveria trazer um *StatusError", err)
+				}
+				return
+			}
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.expectedStatus {
+				t.Fatalf("erro = %v, want *StatusError com status %d", err, tt.expectedStatus)
+			}
+			if !errors.Is(err, ErrUpstreamUnavailable) {
+				t.Errorf("erro %v deveria corresponder a ErrUpstreamUnavailable", err)
+			}
+		})
+	}
+}
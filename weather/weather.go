@@ -0,0 +1,178 @@
+// Package weather consulta o endereço de um CEP no ViaCEP e a temperatura
+// atual da cidade no wttr.in, sem depender do servidor HTTP.
+//
+// Uso básico:
+//
+//	result, err := weather.LookupByCEP(ctx, "01310-100")
+//	if err != nil {
+//		var werr *weather.Error
+//		if errors.As(err, &werr) {
+//			// werr.Code traz o status HTTP equivalente (422, 404, 502...)
+//		}
+//	}
+package weather
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// CEPData representa a estrutura de dados retornada pela API do ViaCEP
+type CEPData struct {
+	CEP         string      `json:"cep"`
+	Logradouro  string      `json:"logradouro"`
+	Complemento string      `json:"complemento"`
+	Bairro      string      `json:"bairro"`
+	Localidade  string      `json:"localidade"`
+	UF          string      `json:"uf"`
+	IBGE        string      `json:"ibge"`
+	GIA         string      `json:"gia"`
+	DDD         string      `json:"ddd"`
+	SIAFI       string      `json:"siafi"`
+	Erro        interface{} `json:"erro,omitempty"`
+}
+
+// WeatherData representa as condições atuais de uma cidade
+type WeatherData struct {
+	TempC float64 `json:"temp_C"`
+	TempF float64 `json:"temp_F"`
+	TempK float64 `json:"temp_K"`
+
+	// Condições informadas pelo provedor; zeradas quando indisponíveis
+	Humidity    int     `json:"humidity,omitempty"`
+	FeelsLikeC  float64 `json:"feels_like_C,omitempty"`
+	Description string  `json:"description,omitempty"`
+
+	// Forecast traz a previsão dos próximos dias, quando informada pelo provedor
+	Forecast []ForecastDay `json:"forecast,omitempty"`
+
+	// Observation guarda os valores brutos da observação atual; é nil quando
+	// a temperatura veio do formato texto do wttr.in
+	Observation *Observation `json:"-"`
+}
+
+// ForecastDay representa a previsão de um dia, com as temperaturas mínima e
+// máxima informadas pelo wttr.in
+type ForecastDay struct {
+	Date     string  `json:"date"`
+	MinTempC float64 `json:"min_temp_C"`
+	MaxTempC float64 `json:"max_temp_C"`
+}
+
+// Observation guarda os valores do wttr.in como recebidos, para quem precisa
+// interpretá-los por conta própria
+type Observation struct {
+	Humidity           string
+	ObservationTimeUTC string
+	LocalObsDateTime   string
+	Hourly             []HourlyTemp
+
+	// Coordenadas da área usada pelo wttr.in para a consulta (nearest_area),
+	// que pode estar longe da cidade pedida
+	NearestAreaLatitude  string
+	NearestAreaLongitude string
+}
+
+// HourlyTemp representa uma leitura horária do wttr.in, no horário local da localização
+type HourlyTemp struct {
+	Date  string
+	Time  string
+	TempC string
+}
+
+// WeatherResult reúne o endereço do CEP e a temperatura da cidade
+type WeatherResult struct {
+	Address CEPData     `json:"address"`
+	Weather WeatherData `json:"weather"`
+}
+
+// Error é o erro retornado pela biblioteca. Code é o status HTTP equivalente,
+// para que os chamadores possam mapeá-lo; Message é estável e Detail explica
+// o problema quando disponível. Err guarda a causa da falha, quando houver:
+// um erro sentinela, para uso com errors.Is, ou o erro de rede ou de
+// decodificação, útil apenas em logs.
+type Error struct {
+	Code    int
+	Message string
+	Detail  string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap expõe o erro sentinela para errors.Is
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// minAssignedCEP é o menor CEP da numeração dos Correios
+const minAssignedCEP = "01000000"
+
+// FormatCEP normaliza o CEP removendo separadores e espaços, inclusive pontos,
+// barras, tabulações e espaços Unicode. Letras são mantidas para que entradas
+// como "123abc456" sejam rejeitadas na validação em vez de aceitas em silêncio.
+func FormatCEP(cep string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, cep)
+}
+
+// ValidateCEP descreve por que o CEP é inválido, ou retorna vazio se ele for válido
+func ValidateCEP(cep string) string {
+	formatted := FormatCEP(cep)
+
+	// Verifica se contém apenas números
+	if matched, _ := regexp.MatchString(`^\d*$`, formatted); !matched {
+		return fmt.Sprintf("cep %q must contain only digits", cep)
+	}
+
+	// Verifica se tem 8 dígitos
+	if len(formatted) != 8 {
+		return fmt.Sprintf("cep %q must have 8 digits, got %d", cep, len(formatted))
+	}
+
+	// A numeração dos Correios começa em 01000-000 (São Paulo); a faixa
+	// 00000-000 a 00999-999 nunca é atribuída
+	if formatted < minAssignedCEP {
+		return fmt.Sprintf("cep %q is outside the assigned range", cep)
+	}
+	return ""
+}
+
+// IsNotFound indica se o ViaCEP sinalizou CEP inexistente. Dependendo da
+// versão do endpoint o campo "erro" vem como booleano (true) ou como texto
+// ("true"); ausente, false, "false", vazio ou zero indicam sucesso.
+func IsNotFound(data *CEPData) bool {
+	switch v := data.Erro.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "", "false", "0":
+			return false
+		}
+		return true
+	case float64:
+		return v != 0
+	default:
+		return true
+	}
+}
+
+// FromCelsius monta os dados de temperatura calculando as conversões a partir de Celsius
+func FromCelsius(tempC float64) WeatherData {
+	return WeatherData{
+		TempC: tempC,
+		TempF: (tempC * 9 / 5) + 32, // Celsius para Fahrenheit
+		TempK: tempC + 273.15,       // Celsius para Kelvin
+	}
+}
@@ -0,0 +1,284 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestClient sobe um servidor que imita o ViaCEP em /ws e o wttr.in em
+// /weather e retorna um cliente apontando para ele
+func newTestClient(t *testing.T, viaCEP, wttr http.HandlerFunc) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/", viaCEP)
+	mux.HandleFunc("/weather/", wttr)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := NewClient()
+	client.ViaCEPBaseURL = server.URL + "/ws"
+	client.WeatherBaseURL = server.URL + "/weather"
+	return client
+}
+
+// reply responde sempre com o status e o corpo informados
+func reply(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+const (
+	paulistaJSON = `{"cep": "01310-100", "logradouro": "Avenida Paulista", "localidade": "São Paulo", "uf": "SP"}`
+	sunnyJSON    = `{"current_condition": [{"temp_C": "25", "humidity": "60", "weatherDesc": [{"value": "Sunny"}]}]}`
+)
+
+func TestLookupByCEP(t *testing.T) {
+	tests := []struct {
+		name            string
+		cep             string
+		viaCEP          http.HandlerFunc
+		wttr            http.HandlerFunc
+		expectedCode    int
+		expectedMessage string
+	}{
+		{"CEP válido", "01310-100", reply(200, paulistaJSON), reply(200, sunnyJSON), 0, ""},
+		{"CEP com formato inválido", "123", reply(200, paulistaJSON), reply(200, sunnyJSON), 422, "invalid zipcode"},
+		{"CEP recusado pelo ViaCEP", "01310100", reply(400, ""), reply(200, sunnyJSON), 422, "invalid zipcode"},
+		{"CEP inexistente", "01310100", reply(200, `{"erro": "true"}`), reply(200, sunnyJSON), 404, "can not find zipcode"},
+		{"ViaCEP fora do ar", "01310100", reply(503, ""), reply(200, sunnyJSON), 502, "bad gateway"},
+		{"wttr.in sem current_condition", "01310100", reply(200, paulistaJSON), reply(200, `{"current_condition": []}`), 500, "weather data not available"},
+		{"wttr.in com JSON malformado", "01310100", reply(200, paulistaJSON), reply(200, `{`), 500, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, tt.viaCEP, tt.wttr)
+			result, err := client.LookupByCEP(context.Background(), tt.cep)
+
+			if tt.expectedCode == 0 {
+				if err != nil {
+					t.Fatalf("LookupByCEP retornou erro: %v", err)
+				}
+				if result.Address.Localidade != "São Paulo" || result.Address.UF != "SP" {
+					t.Errorf("endereço = %+v, want São Paulo/SP", result.Address)
+				}
+				if result.Weather.TempC != 25 || result.Weather.TempF != 77 || result.Weather.TempK != 298.15 {
+					t.Errorf("temperaturas = %+v, want 25C/77F/298.15K", result.Weather)
+				}
+				if result.Weather.Humidity != 60 || result.Weather.Description != "Sunny" {
+					t.Errorf("condições = %+v, want umidade 60 e Sunny", result.Weather)
+				}
+				return
+			}
+
+			var werr *Error
+			if !errors.As(err, &werr) {
+				t.Fatalf("erro = %v (%T), want *Error", err, err)
+			}
+			if werr.Code != tt.expectedCode || werr.Message != tt.expectedMessage {
+				t.Errorf("erro = %d %q, want %d %q", werr.Code, werr.Message, tt.expectedCode, tt.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestLookupByCEPRequests(t *testing.T) {
+	var paths []string
+	record := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			if agent := r.UserAgent(); agent != "meu-app/1.0" {
+				t.Errorf("User-Agent = %q, want meu-app/1.0", agent)
+			}
+			w.Write([]byte(body))
+		}
+	}
+	client := newTestClient(t, record(paulistaJSON), record(sunnyJSON))
+	client.UserAgent = "meu-app/1.0"
+
+	if _, err := client.LookupByCEP(context.Background(), "01310.100"); err != nil {
+		t.Fatalf("LookupByCEP retornou erro: %v", err)
+	}
+
+	want := []string{"/ws/01310100/json/", "/weather/São+Paulo,SP,Brazil"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("requisições = %v, want %v", paths, want)
+	}
+}
+
+func TestValidateCEP(t *testing.T) {
+	tests := []struct {
+		cep   string
+		valid bool
+	}{
+		{"01310-100", true},
+		{"01310.100", true},
+		{" 01310/100\t", true},
+		{"123abc456", false},
+		{"1234567", false},
+		{"00999999", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateCEP(tt.cep) == ""; got != tt.valid {
+			t.Errorf("ValidateCEP(%q) válido = %v, want %v", tt.cep, got, tt.valid)
+		}
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		erro     interface{}
+		expected bool
+	}{
+		{nil, false},
+		{true, true},
+		{false, false},
+		{"true", true},
+		{" True ", true},
+		{"false", false},
+		{"", false},
+		{float64(1), true},
+		{float64(0), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNotFound(&CEPData{Erro: tt.erro}); got != tt.expected {
+			t.Errorf("IsNotFound(%v) = %v, want %v", tt.erro, got, tt.expected)
+		}
+	}
+}
+
+func TestCurrentWeatherAt(t *testing.T) {
+	var query string
+	client := newTestClient(t, reply(200, paulistaJSON), func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		w.Write([]byte(sunnyJSON))
+	})
+	client.Lang = "pt"
+
+	data, err := client.CurrentWeatherAt(context.Background(), -23.55052, -46.633308)
+	if err != nil {
+		t.Fatalf("CurrentWeatherAt retornou erro: %v", err)
+	}
+	if data.TempC != 25 {
+		t.Errorf("temp_C = %v, want 25", data.TempC)
+	}
+	if want := "/weather/-23.5505,-46.6333?format=j1&lang=pt"; query != want {
+		t.Errorf("requisição = %q, want %q", query, want)
+	}
+}
+
+func TestCurrentWeatherTextFallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		expectedTempC float64
+		expectedCode  int
+	}{
+		{"Temperatura no formato texto", "+23°C", 23, 0},
+		{"Texto também inválido", "Unknown location", 0, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, reply(200, paulistaJSON), func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("format") == "%t" {
+					w.Write([]byte(tt.text))
+					return
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<html>rate limited</html>"))
+			})
+
+			data, err := client.CurrentWeather(context.Background(), "São Paulo", "SP")
+			if tt.expectedCode != 0 {
+				if HTTPStatus(err) != tt.expectedCode {
+					t.Fatalf("erro = %v, want código %d", err, tt.expectedCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CurrentWeather retornou erro: %v", err)
+			}
+			if data.TempC != tt.expectedTempC || data.Observation != nil {
+				t.Errorf("dados = %+v, want %v°C sem observação", data, tt.expectedTempC)
+			}
+		})
+	}
+}
+
+func TestParseWttrJSON(t *testing.T) {
+	body := []byte(`{
+		"current_condition": [{"temp_C": "22", "humidity": "73", "observation_time": "12:00 PM", "localObsDateTime": "2025-01-15 09:00 AM"}],
+		"nearest_area": [{"latitude": "-23.533", "longitude": "-46.617"}],
+		"weather": [{"date": "2025-01-15", "maxtempC": "29", "mintempC": "19", "hourly": [{"time": "0", "tempC": "20"}]}]
+	}`)
+
+	data, err := ParseWttrJSON(body)
+	if err != nil {
+		t.Fatalf("ParseWttrJSON retornou erro: %v", err)
+	}
+	if data.TempC != 22 || data.Humidity != 73 {
+		t.Errorf("condições = %+v, want 22°C e umidade 73", data)
+	}
+	expected := &Observation{
+		Humidity:           "73",
+		ObservationTimeUTC: "12:00 PM",
+		LocalObsDateTime:   "2025-01-15 09:00 AM",
+		Hourly:             []HourlyTemp{{Date: "2025-01-15", Time: "0", TempC: "20"}},
+
+		NearestAreaLatitude:  "-23.533",
+		NearestAreaLongitude: "-46.617",
+	}
+	if !reflect.DeepEqual(data.Observation, expected) {
+		t.Errorf("observação = %+v, want %+v", data.Observation, expected)
+	}
+	if want := []ForecastDay{{Date: "2025-01-15", MinTempC: 19, MaxTempC: 29}}; !reflect.DeepEqual(data.Forecast, want) {
+		t.Errorf("previsão = %+v, want %+v", data.Forecast, want)
+	}
+}
+
+func TestBuildForecastSkipsInvalidDays(t *testing.T) {
+	forecast := buildForecast([]wttrDay{
+		{Date: "2025-01-15", MaxTempC: "29", MinTempC: "19"},
+		{Date: "", MaxTempC: "30", MinTempC: "20"},
+		{Date: "2025-01-17", MaxTempC: "", MinTempC: "18"},
+		{Date: "2025-01-18", MaxTempC: "-2", MinTempC: "-9"},
+	})
+
+	expected := []ForecastDay{
+		{Date: "2025-01-15", MinTempC: 19, MaxTempC: 29},
+		{Date: "2025-01-18", MinTempC: -9, MaxTempC: -2},
+	}
+	if !reflect.DeepEqual(forecast, expected) {
+		t.Errorf("previsão = %+v, want %+v", forecast, expected)
+	}
+}
+
+func TestParseWttrTemperature(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected float64
+		ok       bool
+	}{
+		{"+23°C", 23, true},
+		{"-1.5°C\n", -1.5, true},
+		{" 0°C ", 0, true},
+		{"Unknown location", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		tempC, ok := parseWttrTemperature(tt.text)
+		if ok != tt.ok || tempC != tt.expected {
+			t.Errorf("parseWttrTemperature(%q) = (%v, %v), want (%v, %v)", tt.text, tempC, ok, tt.expected, tt.ok)
+		}
+	}
+}
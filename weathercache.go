@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultWeatherCacheTTL é o tempo de vida padrão das entradas; a temperatura
+// muda devagar, mas não deve ficar desatualizada por muito tempo
+const defaultWeatherCacheTTL = 10 * time.Minute
+
+// defaultMaxStale é por quanto tempo após expirar uma entrada ainda pode ser
+// servida com o provedor fora do ar; além disso a temperatura já não é confiável
+const defaultMaxStale = time.Hour
+
+// WeatherCache mantém em memória os dados climáticos já consultados, chaveados
+// por cidade, UF e idioma, por um tempo curto
+type WeatherCache struct {
+	*ttlCache[*WeatherData]
+}
+
+// NewWeatherCache cria um cache com o TTL informado e inicia a remoção
+// periódica das entradas expiradas
+func NewWeatherCache(ttl time.Duration) *WeatherCache {
+	return &WeatherCache{newTTLCache[*WeatherData](ttl, defaultCacheMaxEntries)}
+}
+
+// newWeatherCacheFromEnv cria o cache usando o TTL de WEATHER_CACHE_TTL (ex.:
+// "5m"), o tamanho de WEATHER_CACHE_MAX_ENTRIES e o limite de MAX_STALE para
+// servir dados expirados (0 desativa)
+func newWeatherCacheFromEnv() *WeatherCache {
+	cache := &WeatherCache{newTTLCache[*WeatherData](
+		envDuration("WEATHER_CACHE_TTL", defaultWeatherCacheTTL),
+		envIntWhere("WEATHER_CACHE_MAX_ENTRIES", defaultCacheMaxEntries, positive[int]),
+	)}
+	cache.maxStale = envDurationWhere("MAX_STALE", defaultMaxStale, nonNegative[time.Duration])
+	return cache
+}
+
+// weatherCacheKey monta a chave localidade|uf|idioma, sem acentos nem
+// diferença entre maiúsculas e minúsculas
+func weatherCacheKey(city, state, lang string) string {
+	return normalizeCacheKey(city) + "|" + normalizeCacheKey(state) + "|" + lang
+}
+
+// Get retorna os dados climáticos da cidade, com a descrição no idioma
+// informado, se estiverem no cache, no namespace do contexto, e ainda não
+// tiverem expirado
+func (c *WeatherCache) Get(ctx context.Context, city, state, lang string) (*WeatherData, bool) {
+	data, _, ok := c.get(namespacedKey(ctx, weatherCacheKey(city, state, lang)))
+	return data, ok
+}
+
+// GetStale retorna os dados climáticos da cidade mesmo expirados, desde que
+// tenham expirado há menos de MAX_STALE
+func (c *WeatherCache) GetStale(ctx context.Context, city, state, lang string) (*WeatherData, bool) {
+	data, _, ok := c.getStale(namespacedKey(ctx, weatherCacheKey(city, state, lang)))
+	return data, ok
+}
+
+// Set armazena os dados climáticos da cidade no idioma informado e no
+// namespace do contexto pelo TTL configurado
+func (c *WeatherCache) Set(ctx context.Context, city, state, lang string, data *WeatherData) {
+	c.set(namespacedKey(ctx, weatherCacheKey(city, state, lang)), data)
+}
+
+// staleRefresher atualiza em segundo plano as entradas expiradas servidas do
+// cache, com no máximo workers atualizações simultâneas e uma por chave
+type staleRefresher struct {
+	slots chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+	wg       sync.WaitGroup
+}
+
+// newStaleRefresher cria o pool de atualização; sem workers retorna nil, e as
+// entradas expiradas só são servidas quando o provedor falha
+func newStaleRefresher(workers int) *staleRefresher {
+	if workers <= 0 {
+		return nil
+	}
+	return &staleRefresher{slots: make(chan struct{}, workers), inFlight: make(map[string]bool)}
+}
+
+// newStaleRefresherFromEnv cria o pool com a quantidade de STALE_REFRESH_WORKERS
+// (padrão 0, desativado)
+func newStaleRefresherFromEnv() *staleRefresher {
+	return newStaleRefresher(envIntWhere("STALE_REFRESH_WORKERS", 0, nonNegative[int]))
+}
+
+// trigger inicia a atualização da chave se nenhuma estiver em andamento para
+// ela e houver um worker livre. Com o pool cheio a atualização é descartada:
+// a entrada continua expirada e o próximo acesso tenta de novo.
+func (s *staleRefresher) trigger(key string, refresh func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inFlight[key] {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return
+	}
+	s.inFlight[key] = true
+	s.wg.Add(1)
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.inFlight, key)
+			s.mu.Unlock()
+			<-s.slots
+			s.wg.Done()
+		}()
+		refresh()
+	}()
+}
+
+// wait aguarda o fim das atualizações em andamento
+func (s *staleRefresher) wait() {
+	s.wg.Wait()
+}
+
+// cachingWeatherResolver consulta o cache antes de delegar ao resolver
+// informado, armazenando apenas as consultas bem-sucedidas. Se o provedor
+// falhar, serve os dados expirados há menos de MAX_STALE; depois disso o
+// erro do provedor é devolvido. Com refresh, os dados expirados há menos de
+// MAX_STALE são servidos de imediato e atualizados em segundo plano.
+type cachingWeatherResolver struct {
+	cache   *WeatherCache
+	next    WeatherResolver
+	refresh *staleRefresher
+}
+
+func (r cachingWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	// A descrição depende do idioma, que também faz parte da chave
+	lang := weatherLangFromContext(ctx)
+	if data, ok := r.cache.Get(ctx, city, state, lang); ok {
+		return data, nil
+	}
+
+	if r.refresh != nil {
+		if stale, ok := r.cache.GetStale(ctx, city, state, lang); ok {
+			// A atualização não pode ser cancelada com o fim da requisição,
+			// mas mantém o idioma e os atributos de log do contexto
+			refreshCtx := context.WithoutCancel(ctx)
+			r.refresh.trigger(namespacedKey(ctx, weatherCacheKey(city, state, lang)), func() {
+				if _, err := r.resolve(refreshCtx, city, state, lang); err != nil {
+					logger.WarnContext(refreshCtx, "falha ao atualizar dados expirados em segundo plano",
+						"city", city, "state", state, "error", err.Message)
+				}
+			})
+			return stale, nil
+		}
+	}
+
+	data, err := r.resolve(ctx, city, state, lang)
+	if err != nil {
+		// Erros do cliente (4xx) não indicam provedor fora do ar
+		if err.Code >= 500 {
+			if stale, ok := r.cache.GetStale(ctx, city, state, lang); ok {
+				logger.WarnContext(ctx, "provedor indisponível, servindo dados expirados",
+					"city", city, "state", state, "fetched_at", stale.FetchedAt, "error", err.Message)
+				return stale, nil
+			}
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// resolve consulta o provedor e armazena o resultado no cache se a consulta
+// tiver sucesso
+func (r cachingWeatherResolver) resolve(ctx context.Context, city, state, lang string) (*WeatherData, *CustomError) {
+	data, err := r.next.ResolveWeather(ctx, city, state)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Set(ctx, city, state, lang, data)
+	return data, nil
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestWeatherCache cria um cache com relógio controlado pelo teste
+func newTestWeatherCache(t *testing.T, ttl time.Duration, now *time.Time) *WeatherCache {
+	t.Helper()
+	cache := NewWeatherCache(ttl)
+	cache.now = func() time.Time { return *now }
+	t.Cleanup(cache.Stop)
+	return cache
+}
+
+func TestWeatherCacheGetSet(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestWeatherCache(t, 10*time.Minute, &now)
+
+	if _, ok := cache.Get(context.Background(), "São Paulo", "SP", ""); ok {
+		t.Fatal("cache vazio não deveria retornar dados")
+	}
+
+	data := &WeatherData{TempC: 22}
+	cache.Set(context.Background(), "São Paulo", "SP", "", data)
+
+	if got, ok := cache.Get(context.Background(), "São Paulo", "SP", ""); !ok || got != data {
+		t.Errorf("Get = (%v, %v), want (%v, true)", got, ok, data)
+	}
+	// Variações de caixa, espaços e acentos compartilham a entrada
+	if got, ok := cache.Get(context.Background(), " sao paulo ", "sp", ""); !ok || got != data {
+		t.Errorf("Get sem acentos = (%v, %v), want (%v, true)", got, ok, data)
+	}
+	// Cidades homônimas em outra UF têm entradas separadas
+	if _, ok := cache.Get(context.Background(), "São Paulo", "RJ", ""); ok {
+		t.Error("a UF deveria fazer parte da chave")
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, ok := cache.Get(context.Background(), "São Paulo", "SP", ""); ok {
+		t.Error("entrada expirada não deveria ser retornada")
+	}
+}
+
+func TestWeatherCacheEvictExpired(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestWeatherCache(t, 10*time.Minute, &now)
+
+	cache.Set(context.Background(), "São Paulo", "SP", "", &WeatherData{TempC: 22})
+	now = now.Add(5 * time.Minute)
+	cache.Set(context.Background(), "Rio de Janeiro", "RJ", "", &WeatherData{TempC: 29})
+
+	now = now.Add(6 * time.Minute)
+	cache.evictExpired()
+
+	if _, ok := cache.entries[weatherCacheKey("São Paulo", "SP", "")]; ok {
+		t.Error("entrada expirada deveria ter sido removida")
+	}
+	if _, ok := cache.entries[weatherCacheKey("Rio de Janeiro", "RJ", "")]; !ok {
+		t.Error("entrada válida não deveria ter sido removida")
+	}
+}
+
+func TestCachingWeatherResolver(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	upstream := &fakeWeatherResolver{tempC: 22}
+	resolver := cachingWeatherResolver{cache: newTestWeatherCache(t, 10*time.Minute, &now), next: upstream}
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.ResolveWeather(context.Background(), "São Paulo", "SP"); err != nil {
+			t.Fatalf("ResolveWeather retornou erro: %v", err)
+		}
+	}
+	if upstream.calls != 1 {
+		t.Errorf("provedor chamado %d vezes dentro do TTL, want 1", upstream.calls)
+	}
+
+	// Após o TTL o provedor é consultado novamente
+	now = now.Add(10 * time.Minute)
+	if _, err := resolver.ResolveWeather(context.Background(), "São Paulo", "SP"); err != nil {
+		t.Fatalf("ResolveWeather retornou erro: %v", err)
+	}
+	if upstream.calls != 2 {
+		t.Errorf("provedor chamado %d vezes após expirar, want 2", upstream.calls)
+	}
+
+	// Erros não são armazenados
+	upstream.err = &CustomError{Code: 500, Message: "internal server error"}
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.ResolveWeather(context.Background(), "Rio de Janeiro", "RJ"); err == nil {
+			t.Fatal("ResolveWeather deveria retornar o erro do provedor")
+		}
+	}
+	if upstream.calls != 4 {
+		t.Errorf("provedor chamado %d vezes, want 4", upstream.calls)
+	}
+}
+
+func TestWeatherByCEPHandlerUsesWeatherCache(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	upstream := &fakeWeatherResolver{tempC: 23}
+	resolver := cachingWeatherResolver{cache: newTestWeatherCache(t, 10*time.Minute, &now), next: upstream}
+	handler := NewWeatherHandler(newFakeCEPResolver(), resolver)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/weatherbycep/01310100", nil))
+		if rr.Code != 200 {
+			t.Fatalf("status = %d, want 200", rr.Code)
+		}
+	}
+
+	if upstream.calls != 1 {
+		t.Errorf("wttr.in consultado %d vezes, want 1", upstream.calls)
+	}
+}
+
+func TestCachingWeatherResolverMaxStale(t *testing.T) {
+	upstreamDown := &CustomError{Code: 503, Message: "weather temporarily unavailable"}
+
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		upstreamErr *CustomError
+		expectStale bool
+	}{
+		{"Expirada há pouco com o provedor fora do ar", 30 * time.Minute, upstreamDown, true},
+		{"Expirada no limite de MAX_STALE", 10*time.Minute + time.Hour - time.Second, upstreamDown, true},
+		{"Expirada além de MAX_STALE", 10*time.Minute + time.Hour, upstreamDown, false},
+		{"Erro do cliente não usa dados expirados", 30 * time.Minute, &CustomError{Code: 404, Message: "location not found"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+			cache := newTestWeatherCache(t, 10*time.Minute, &now)
+			cache.maxStale = time.Hour
+			upstream := &fakeWeatherResolver{tempC: 22}
+			resolver := cachingWeatherResolver{cache: cache, next: upstream}
+
+			if _, err := resolver.ResolveWeather(context.Background(), "São Paulo", "SP"); err != nil {
+				t.Fatalf("ResolveWeather retornou erro: %v", err)
+			}
+
+			now = now.Add(tt.elapsed)
+			upstream.err = tt.upstreamErr
+			data, err := resolver.ResolveWeather(context.Background(), "São Paulo", "SP")
+
+			if upstream.calls != 2 {
+				t.Errorf("provedor chamado %d vezes, want 2: dados expirados só servem após a falha", upstream.calls)
+			}
+			if tt.expectStale {
+				if err != nil || data == nil || data.TempC != 22 {
+					t.Errorf("ResolveWeather = (%v, %v), want os dados expirados", data, err)
+				}
+				return
+			}
+			if err != tt.upstreamErr {
+				t.Errorf("erro = %v, want o erro do provedor %v", err, tt.upstreamErr)
+			}
+		})
+	}
+}
+
+// blockingWeatherResolver conta as consultas e só responde quando release é fechado
+type blockingWeatherResolver struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	tempC   float64
+	release chan struct{}
+}
+
+func (b *blockingWeatherResolver) ResolveWeather(ctx context.Context, city, state string) (*WeatherData, *CustomError) {
+	b.mu.Lock()
+	b.calls[city]++
+	b.mu.Unlock()
+
+	<-b.release
+	weatherData := weatherFromCelsius(b.tempC)
+	return &weatherData, nil
+}
+
+func TestCachingWeatherResolverStaleRefresh(t *testing.T) {
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache := newTestWeatherCache(t, 10*time.Minute, &now)
+	cache.maxStale = time.Hour
+	cache.Set(context.Background(), "São Paulo", "SP", defaultWeatherLang, &WeatherData{TempC: 22})
+	cache.Set(context.Background(), "Campinas", "SP", defaultWeatherLang, &WeatherData{TempC: 21})
+	now = now.Add(30 * time.Minute)
+
+	upstream := &blockingWeatherResolver{calls: make(map[string]int), tempC: 25, release: make(chan struct{})}
+	// Um único worker: a atualização de Campinas é descartada enquanto a de São Paulo roda
+	refresh := newStaleRefresher(1)
+	resolver := cachingWeatherResolver{cache: cache, next: upstream, refresh: refresh}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := resolver.ResolveWeather(context.Background(), "São Paulo", "SP")
+			if err != nil || data.TempC != 22 {
+				t.Errorf("ResolveWeather = (%v, %v), want os dados expirados de imediato", data, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if data, err := resolver.ResolveWeather(context.Background(), "Campinas", "SP"); err != nil || data.TempC != 21 {
+		t.Errorf("ResolveWeather(Campinas) = (%v, %v), want os dados expirados de imediato", data, err)
+	}
+
+	close(upstream.release)
+	refresh.wait()
+
+	if calls := upstream.calls["São Paulo"]; calls != 1 {
+		t.Errorf("São Paulo atualizada %d vezes, want 1", calls)
+	}
+	if calls := upstream.calls["Campinas"]; calls != 0 {
+		t.Errorf("Campinas atualizada %d vezes com o pool cheio, want 0", calls)
+	}
+	if data, ok := cache.Get(context.Background(), "São Paulo", "SP", defaultWeatherLang); !ok || data.TempC != 25 {
+		t.Errorf("cache após a atualização = (%v, %v), want 25°C", data, ok)
+	}
+
+	// Com o worker livre, o próximo acesso a Campinas dispara a atualização
+	resolver.ResolveWeather(context.Background(), "Campinas", "SP")
+	refresh.wait()
+	if data, ok := cache.Get(context.Background(), "Campinas", "SP", defaultWeatherLang); !ok || data.TempC != 25 {
+		t.Errorf("cache de Campinas = (%v, %v), want 25°C", data, ok)
+	}
+}
+
+func TestNewStaleRefresherFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", 0},
+		{"0", 0},
+		{"4", 4},
+		{"-1", 0},
+		{"quatro", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("STALE_REFRESH_WORKERS", tt.value)
+			workers := 0
+			if refresh := newStaleRefresherFromEnv(); refresh != nil {
+				workers = cap(refresh.slots)
+			}
+			if workers != tt.expected {
+				t.Errorf("workers = %d, want %d", workers, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewWeatherCacheFromEnvMaxStale(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", defaultMaxStale},
+		{"30m", 30 * time.Minute},
+		{"0", 0},
+		{"-1m", defaultMaxStale},
+		{"uma hora", defaultMaxStale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("MAX_STALE", tt.value)
+			cache := newWeatherCacheFromEnv()
+			t.Cleanup(cache.Stop)
+			if cache.maxStale != tt.expected {
+				t.Errorf("maxStale = %v, want %v", cache.maxStale, tt.expected)
+			}
+		})
+	}
+}